@@ -0,0 +1,134 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AdaptiveLimit is an event subscriber to limit the concurrency of the
+// subscriber as well as Limited, but adapting the limit by the latency and the
+// errors of handling, which is useful for the subscribers calling the
+// downstreams of variable capacity. The limit increases additively on the fast
+// successes, and decreases multiplicatively on the slow handling or the errors
+// (AIMD).
+type AdaptiveLimit struct {
+	subscriber Subscriber
+	min, max   float64
+	latency    time.Duration
+	backoff    float64
+	clock      Clock
+	mu         sync.Mutex
+	limit      float64
+	inflight   int
+	waiters    []chan struct{}
+}
+
+// AdaptiveLimitOption is an option for NewAdaptiveLimit.
+type AdaptiveLimitOption func(*AdaptiveLimit)
+
+// AdaptiveLimitRange sets the range of the limit. The default range is from 1
+// to 100, and the limit starts from the min.
+func AdaptiveLimitRange(min, max int) AdaptiveLimitOption {
+	return func(sub *AdaptiveLimit) { sub.min, sub.max = float64(min), float64(max) }
+}
+
+// AdaptiveLimitLatency sets the target latency of handling. The handling
+// slower than the target decreases the limit. The default is one second.
+func AdaptiveLimitLatency(d time.Duration) AdaptiveLimitOption {
+	return func(sub *AdaptiveLimit) { sub.latency = d }
+}
+
+// AdaptiveLimitBackoff sets the ratio to decrease the limit, which is between
+// 0 and 1. The default ratio is 0.9.
+func AdaptiveLimitBackoff(ratio float64) AdaptiveLimitOption {
+	return func(sub *AdaptiveLimit) { sub.backoff = ratio }
+}
+
+// AdaptiveLimitClock sets the clock to measure the latency of handling instead
+// of the global clock.
+func AdaptiveLimitClock(c Clock) AdaptiveLimitOption {
+	return func(sub *AdaptiveLimit) { sub.clock = c }
+}
+
+// NewAdaptiveLimit creates a new adaptive limited subscriber.
+func NewAdaptiveLimit(sub Subscriber, opts ...AdaptiveLimitOption) *AdaptiveLimit {
+	s := &AdaptiveLimit{subscriber: sub, min: 1, max: 100, latency: time.Second, backoff: 0.9}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.limit = s.min
+	return s
+}
+
+// Limit returns the current concurrency limit.
+func (sub *AdaptiveLimit) Limit() int {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return int(sub.limit)
+}
+
+// Handle implements Subscriber for AdaptiveLimit.
+func (sub *AdaptiveLimit) Handle(ctx context.Context, ev Event) error {
+	if err := sub.acquire(ctx); err != nil {
+		return err
+	}
+	clock := clockOr(sub.clock)
+	start := clock.Now()
+	err := sub.subscriber.Handle(ctx, ev)
+	sub.release(err == nil && clock.Now().Sub(start) <= sub.latency)
+	return err
+}
+
+func (sub *AdaptiveLimit) acquire(ctx context.Context) error {
+	sub.mu.Lock()
+	if sub.inflight < int(sub.limit) {
+		sub.inflight++
+		sub.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	sub.waiters = append(sub.waiters, ch)
+	sub.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		sub.mu.Lock()
+		defer sub.mu.Unlock()
+		for i, w := range sub.waiters {
+			if w == ch {
+				sub.waiters = append(sub.waiters[:i], sub.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		sub.inflight-- // the slot is granted after the context is done
+		sub.grant()
+		return ctx.Err()
+	case <-ch:
+		return nil
+	}
+}
+
+func (sub *AdaptiveLimit) release(ok bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	sub.inflight--
+	if ok {
+		sub.limit += 1 / sub.limit
+	} else {
+		sub.limit *= sub.backoff
+	}
+	if sub.limit < sub.min {
+		sub.limit = sub.min
+	} else if sub.limit > sub.max {
+		sub.limit = sub.max
+	}
+	sub.grant()
+}
+
+func (sub *AdaptiveLimit) grant() {
+	for len(sub.waiters) > 0 && sub.inflight < int(sub.limit) {
+		close(sub.waiters[0])
+		sub.waiters = sub.waiters[1:]
+		sub.inflight++
+	}
+}
@@ -0,0 +1,158 @@
+package event_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestAdaptiveLimit(t *testing.T) {
+	ctx := context.Background()
+	sub := event.NewAdaptiveLimit(event.Func(func(_ context.Context, ev event.Event) error {
+		switch ev.(type) {
+		case eventUpdated:
+			time.Sleep(20 * time.Millisecond)
+		case eventDeleted:
+			return suberr{}.Handle(ctx, ev)
+		}
+		return nil
+	}), event.AdaptiveLimitRange(2, 5), event.AdaptiveLimitLatency(10*time.Millisecond), event.AdaptiveLimitBackoff(0.5))
+	if got, expected := sub.Limit(), 2; got != expected {
+		t.Fatalf("expected limit %d, got %d", expected, got)
+	}
+	for i := 0; i < 10; i++ {
+		_ = sub.Handle(ctx, eventCreated(i))
+	}
+	if got, expected := sub.Limit(), 4; got != expected {
+		t.Errorf("expected limit %d, got %d", expected, got)
+	}
+	_ = sub.Handle(ctx, eventUpdated(1))
+	if got, expected := sub.Limit(), 2; got != expected {
+		t.Errorf("expected limit %d, got %d", expected, got)
+	}
+	for i := 0; i < 100; i++ {
+		_ = sub.Handle(ctx, eventCreated(i))
+	}
+	if got, expected := sub.Limit(), 5; got != expected {
+		t.Errorf("expected limit %d, got %d", expected, got)
+	}
+	for i := 0; i < 2; i++ {
+		_ = sub.Handle(ctx, eventDeleted(i))
+		if got, expected := sub.Limit(), 2; got != expected {
+			t.Errorf("expected limit %d, got %d", expected, got)
+		}
+	}
+}
+
+func TestAdaptiveLimitClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	sub := event.NewAdaptiveLimit(event.Func(func(_ context.Context, ev event.Event) error {
+		if _, ok := ev.(eventUpdated); ok {
+			clock.Advance(time.Minute)
+		}
+		return nil
+	}), event.AdaptiveLimitRange(2, 5), event.AdaptiveLimitLatency(time.Second), event.AdaptiveLimitBackoff(0.5), event.AdaptiveLimitClock(clock))
+	for i := 0; i < 10; i++ {
+		_ = sub.Handle(ctx, eventCreated(i))
+	}
+	if got, expected := sub.Limit(), 4; got != expected {
+		t.Errorf("expected limit %d, got %d", expected, got)
+	}
+	_ = sub.Handle(ctx, eventUpdated(1))
+	if got, expected := sub.Limit(), 2; got != expected {
+		t.Errorf("expected limit %d, got %d", expected, got)
+	}
+}
+
+func TestAdaptiveLimitConcurrency(t *testing.T) {
+	ctx := context.Background()
+	var running, max int32
+	block := make(chan struct{})
+	sub := event.NewAdaptiveLimit(event.Func(func(context.Context, event.Event) error {
+		n := atomic.AddInt32(&running, 1)
+		for m := atomic.LoadInt32(&max); n > m && !atomic.CompareAndSwapInt32(&max, m, n); m = atomic.LoadInt32(&max) {
+		}
+		<-block
+		atomic.AddInt32(&running, -1)
+		return nil
+	}), event.AdaptiveLimitRange(2, 2))
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := sub.Handle(ctx, eventCreated(i)); err != nil {
+				t.Errorf("got error: %v", err)
+			}
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err, expected := sub.Handle(cctx, eventCreated(5)), context.DeadlineExceeded; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	close(block)
+	wg.Wait()
+	if got, expected := atomic.LoadInt32(&max), int32(2); got != expected {
+		t.Errorf("expected max concurrency %d, got %d", expected, got)
+	}
+}
+
+// hookContext calls the hook on the first call of Done.
+type hookContext struct {
+	context.Context
+	once sync.Once
+	hook func()
+}
+
+func (ctx *hookContext) Done() <-chan struct{} {
+	ctx.once.Do(ctx.hook)
+	return ctx.Context.Done()
+}
+
+func TestAdaptiveLimitGranted(t *testing.T) {
+	ctx := context.Background()
+	var handling, release chan struct{}
+	sub := event.NewAdaptiveLimit(event.Func(func(_ context.Context, ev event.Event) error {
+		if _, ok := ev.(eventUpdated); ok {
+			close(handling)
+			<-release
+		}
+		return nil
+	}), event.AdaptiveLimitRange(1, 1))
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	for i := 0; ; i++ {
+		if i == 100 {
+			t.Fatalf("expected the slot granted after the context is done")
+		}
+		handling, release = make(chan struct{}), make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = sub.Handle(ctx, eventUpdated(1))
+		}()
+		<-handling
+		// The slot is granted on handling the first event, while the context
+		// is already done, so that the waiting selects either of them.
+		err := sub.Handle(&hookContext{Context: cctx, hook: func() {
+			close(release)
+			<-done
+		}}, eventCreated(1))
+		if err == context.Canceled {
+			break
+		} else if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := sub.Handle(ctx, eventCreated(2)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
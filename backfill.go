@@ -0,0 +1,140 @@
+package event
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+)
+
+// EventSource is the interface for a source of historical events.
+type EventSource interface {
+	// Next returns the event at the position and the position of the next
+	// event. This method returns io.EOF when there are no more events.
+	Next(context.Context, int64) (Event, int64, error)
+}
+
+// SourceFunc is an event source built from a function, which is useful to
+// plug the drivers of the databases, like reading the rows of an outbox table
+// or a change data capture feed and decoding them into events.
+type SourceFunc func(context.Context, int64) (Event, int64, error)
+
+// Next implements EventSource for SourceFunc.
+func (src SourceFunc) Next(ctx context.Context, pos int64) (Event, int64, error) {
+	return src(ctx, pos)
+}
+
+// BackfillOptions is the options for Backfill.
+type BackfillOptions struct {
+	// The position to start reading the events, which is the last saved
+	// checkpoint to resume the backfilling.
+	From int64
+	// The max number of events to publish per second. Zero means no limit.
+	Rate float64
+	// The function to save the position of the next event. This function is
+	// called with a background context to save the checkpoint even when the
+	// backfilling is canceled. Zero CheckpointEvery means saving the checkpoint
+	// only at the end.
+	Checkpoint      func(context.Context, int64) error
+	CheckpointEvery int
+	// The interval to poll the source for new events after reaching the end,
+	// to tail the source until the context is canceled. Zero means stopping
+	// at the end of the source. The checkpoint is saved on each poll.
+	Follow time.Duration
+	// The number of the events to yield the processor after publishing, and
+	// the duration to sleep on yielding, so that a huge backfilling does not
+	// monopolize the scheduler. The context is checked on yielding. Zero
+	// YieldEvery means no yielding.
+	YieldEvery int
+	YieldPause time.Duration
+	// The clock of the rate, the polling and the pause on yielding. Nil means
+	// the global clock set by SetClock.
+	Clock Clock
+}
+
+// Backfill publishes the historical events of the source to the publisher, which
+// is useful to rebuild the projections or to notify the current subscribers.
+// Backfilling stops on the first error, and the checkpoint is saved at the
+// position of the failed event so that the next backfilling resumes from it.
+func Backfill(ctx context.Context, src EventSource, pub Publisher, opts BackfillOptions) error {
+	clock := clockOr(opts.Clock)
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := clock.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
+	var n int
+	pos := opts.From
+	for {
+		ev, next, err := src.Next(ctx, pos)
+		if err == io.EOF && opts.Follow > 0 {
+			if err := checkpoint(opts, pos, nil); err != nil {
+				return err
+			}
+			timer := clock.NewTimer(opts.Follow)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C():
+			}
+			continue
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return checkpoint(opts, pos, err)
+		}
+		if opts.YieldEvery > 0 && n > 0 && n%opts.YieldEvery == 0 {
+			if err := yield(ctx, clock, opts.YieldPause); err != nil {
+				return checkpoint(opts, pos, err)
+			}
+		}
+		if tick != nil && n > 0 {
+			select {
+			case <-ctx.Done():
+				return checkpoint(opts, pos, ctx.Err())
+			case <-tick:
+			}
+		}
+		if err := pub.Publish(ctx, ev); err != nil {
+			return checkpoint(opts, pos, err)
+		}
+		pos = next
+		if n++; opts.CheckpointEvery > 0 && n%opts.CheckpointEvery == 0 {
+			if err := checkpoint(opts, pos, nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func checkpoint(opts BackfillOptions, pos int64, err error) error {
+	if opts.Checkpoint == nil {
+		return err
+	}
+	if e := opts.Checkpoint(context.Background(), pos); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// StoreCheckpoint returns the function to save the checkpoint of Backfill in
+// the store with the key, for BackfillOptions.Checkpoint.
+func StoreCheckpoint(store Store, key string) func(context.Context, int64) error {
+	return func(ctx context.Context, pos int64) error {
+		return store.Save(ctx, key, strconv.AppendInt(nil, pos, 10))
+	}
+}
+
+// LoadCheckpoint loads the checkpoint saved by StoreCheckpoint, or zero if not
+// found, for BackfillOptions.From.
+func LoadCheckpoint(ctx context.Context, store Store, key string) (int64, error) {
+	bs, ok, err := store.Load(ctx, key)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return strconv.ParseInt(string(bs), 10, 64)
+}
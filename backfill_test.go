@@ -0,0 +1,205 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type eventSource []event.Event
+
+func (src eventSource) Next(_ context.Context, pos int64) (event.Event, int64, error) {
+	if pos >= int64(len(src)) {
+		return nil, pos, io.EOF
+	}
+	return src[pos], pos + 1, nil
+}
+
+func TestBackfill(t *testing.T) {
+	ctx := context.Background()
+	src := eventSource{eventCreated(1), eventCreated(2), eventCreated(3), eventCreated(4), eventCreated(5)}
+	var checkpoints []int64
+	fail := true
+	sub1 := &logged{}
+	pub := event.NewMapping().On(eventTypeCreated, event.Func(func(ctx context.Context, ev event.Event) error {
+		if ev == eventCreated(4) && fail {
+			fail = false
+			return errors.New("publish error")
+		}
+		return sub1.Handle(ctx, ev)
+	}))
+	opts := event.BackfillOptions{
+		Rate: 200,
+		Checkpoint: func(_ context.Context, pos int64) error {
+			checkpoints = append(checkpoints, pos)
+			return nil
+		},
+		CheckpointEvery: 2,
+	}
+	start := time.Now()
+	if err, expected := event.Backfill(ctx, src, pub, opts), "publish error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if elapsed, expected := time.Since(start), 15*time.Millisecond; elapsed < expected {
+		t.Errorf("expected rate limited to take at least %v, got %v", expected, elapsed)
+	}
+	opts.From = checkpoints[len(checkpoints)-1]
+	if err := event.Backfill(ctx, src, pub, opts); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []int64{2, 3, 5, 5}; !reflect.DeepEqual(checkpoints, expected) {
+		t.Errorf("checkpoints: expected %v, got %v", expected, checkpoints)
+	}
+	if expected := []event.Event(src); !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
+func TestBackfillStoreCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	src := eventSource{eventCreated(1), eventCreated(2), eventCreated(3)}
+	store := event.NewMemoryStore()
+	sub1 := &logged{}
+	for i := 0; i < 2; i++ {
+		from, err := event.LoadCheckpoint(ctx, store, "backfill")
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if expected := int64(i * 3); from != expected {
+			t.Errorf("expected checkpoint %d, got %d", expected, from)
+		}
+		if err := event.Backfill(ctx, src, event.Func(sub1.Handle), event.BackfillOptions{
+			From: from, Checkpoint: event.StoreCheckpoint(store, "backfill"),
+		}); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []event.Event(src); !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if _, err := event.LoadCheckpoint(ctx, storeError{load: errors.New("load error")}, "backfill"); err == nil || err.Error() != "load error" {
+		t.Errorf("expected load error, got %v", err)
+	}
+}
+
+func TestBackfillCheckpointError(t *testing.T) {
+	ctx := context.Background()
+	src := eventSource{eventCreated(1), eventCreated(2)}
+	for _, tc := range []struct {
+		opts     event.BackfillOptions
+		expected []event.Event
+	}{
+		{event.BackfillOptions{CheckpointEvery: 1}, src[:1]},
+		{event.BackfillOptions{Follow: time.Minute}, src},
+	} {
+		sub1 := &logged{}
+		tc.opts.Checkpoint = func(context.Context, int64) error {
+			return errors.New("checkpoint error")
+		}
+		err := event.Backfill(ctx, src, event.Func(sub1.Handle), tc.opts)
+		if expected := "checkpoint error"; err == nil || err.Error() != expected {
+			t.Fatalf("expected %v, got %v", expected, err)
+		}
+		if !reflect.DeepEqual(sub1.Events(), tc.expected) {
+			t.Errorf("sub1 handled events: expected %v, got %v", tc.expected, sub1.Events())
+		}
+	}
+}
+
+func TestBackfillCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := eventSource{eventCreated(1), eventCreated(2)}
+	var checkpoint int64
+	pub := event.Func(func(context.Context, event.Event) error {
+		cancel()
+		return nil
+	})
+	err := event.Backfill(ctx, src, pub, event.BackfillOptions{
+		Rate: 10,
+		Checkpoint: func(ctx context.Context, pos int64) error {
+			checkpoint = pos
+			return ctx.Err()
+		},
+	})
+	if expected := context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := int64(1); checkpoint != expected {
+		t.Errorf("expected checkpoint %d, got %d", expected, checkpoint)
+	}
+}
+
+func TestBackfillFollow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var mu sync.Mutex
+	rows := []event.Event{eventCreated(1)}
+	src := event.SourceFunc(func(_ context.Context, pos int64) (event.Event, int64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if pos >= int64(len(rows)) {
+			return nil, pos, io.EOF
+		}
+		return rows[pos], pos + 1, nil
+	})
+	handled := make(chan event.Event)
+	errc := make(chan error)
+	go func() {
+		errc <- event.Backfill(ctx, src, event.Func(func(_ context.Context, ev event.Event) error {
+			handled <- ev
+			return nil
+		}), event.BackfillOptions{Follow: time.Minute, Clock: clock})
+	}()
+	if got, expected := <-handled, eventCreated(1); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	clock.WaitTimers(1)
+	mu.Lock()
+	rows = append(rows, eventCreated(2))
+	mu.Unlock()
+	clock.Advance(time.Minute)
+	if got, expected := <-handled, eventCreated(2); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	cancel()
+	if err, expected := <-errc, context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestBackfillYield(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src := eventSource{eventCreated(1), eventCreated(2), eventCreated(3), eventCreated(4), eventCreated(5)}
+	var checkpoint int64
+	sub1 := &logged{}
+	err := event.Backfill(ctx, src, event.Func(func(ctx context.Context, ev event.Event) error {
+		if ev == eventCreated(3) {
+			cancel()
+		}
+		return sub1.Handle(ctx, ev)
+	}), event.BackfillOptions{
+		YieldEvery: 2,
+		Checkpoint: func(_ context.Context, pos int64) error {
+			checkpoint = pos
+			return nil
+		},
+	})
+	if expected := context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []event.Event(src[:4]); !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := int64(4); checkpoint != expected {
+		t.Errorf("expected checkpoint %d, got %d", expected, checkpoint)
+	}
+}
@@ -0,0 +1,42 @@
+package event
+
+import (
+	"context"
+	"reflect"
+)
+
+// Bridge creates an event subscriber to forward the events to the publisher of
+// another application or library, which defines its own event types. The
+// convert function converts an event into the event of the other side, or
+// returns false when the event has no counterpart, which is reported to the
+// dropped event observer as DropUnconverted. The events published by a bridge
+// are not forwarded by the bridges, so the bridges in both directions do not
+// echo the events back, while the new events published by the subscribers of
+// the other side with the same context are forwarded. Bridge the publishers
+// directly to forward the events across three or more publishers.
+func Bridge(pub Publisher, convert func(Event) (Event, bool)) Func {
+	return func(ctx context.Context, ev Event) error {
+		if bridged, ok := ctx.Value(bridgeKey{}).(Event); ok && sameEvent(bridged, ev) {
+			return nil
+		}
+		converted, ok := convert(ev)
+		if !ok {
+			dropped(ctx, ev, DropUnconverted)
+			return nil
+		}
+		return pub.Publish(context.WithValue(ctx, bridgeKey{}, converted), converted)
+	}
+}
+
+// sameEvent reports whether the events are the same, comparing the events of
+// the incomparable types deeply.
+func sameEvent(x, y Event) bool {
+	if t := reflect.TypeOf(x); t != reflect.TypeOf(y) {
+		return false
+	} else if t.Comparable() {
+		return x == y
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+type bridgeKey struct{}
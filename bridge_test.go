@@ -0,0 +1,86 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestBridge(t *testing.T) {
+	ctx := context.Background()
+	var drops []event.Event
+	event.SetDroppedEventObserver(func(_ context.Context, ev event.Event, reason event.DropReason) {
+		if reason == event.DropUnconverted {
+			drops = append(drops, ev)
+		}
+	})
+	defer event.SetDroppedEventObserver(nil)
+	sub1, sub2, sub3 := &logged{}, &logged{}, &logged{}
+	pub1, pub2 := event.NewMapping(), event.NewMapping()
+	pub1.On(eventTypeUpdated, sub3)
+	pub1.On(eventTypeCreated, sub1).On(eventTypeCreated, event.Bridge(pub2, func(ev event.Event) (event.Event, bool) {
+		if ev.(eventCreated) > 2 {
+			return nil, false
+		}
+		return eventOther(ev.(eventCreated)), true
+	}))
+	pub2.On(eventTypeOther, sub2).On(eventTypeOther, event.Bridge(pub1, func(ev event.Event) (event.Event, bool) {
+		return eventCreated(ev.(eventOther)), true
+	})).On(eventTypeOther, event.Func(func(ctx context.Context, ev event.Event) error {
+		return pub2.Publish(ctx, eventUpdated(ev.(eventOther)*10))
+	})).On(eventTypeUpdated, event.Bridge(pub1, func(ev event.Event) (event.Event, bool) {
+		return ev, true
+	}))
+	for _, ev := range []event.Event{eventCreated(1), eventOther(2), eventCreated(3)} {
+		if err := pub1.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub2.Publish(ctx, eventOther(4)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(3), eventCreated(4)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := []event.Event{eventOther(1), eventOther(4)}; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	if expected := []event.Event{eventUpdated(10), eventUpdated(40)}; !reflect.DeepEqual(sub3.Events(), expected) {
+		t.Errorf("sub3 handled events: expected %v, got %v", expected, sub3.Events())
+	}
+	if expected := []event.Event{eventCreated(3)}; !reflect.DeepEqual(drops, expected) {
+		t.Errorf("dropped events: expected %v, got %v", expected, drops)
+	}
+}
+
+type eventBatch []int
+
+func (eventBatch) Type() event.Type {
+	return eventTypeOther
+}
+
+func TestBridgeIncomparable(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2 := &logged{}, &logged{}
+	pub1, pub2 := event.NewMapping(), event.NewMapping()
+	convert := func(ev event.Event) (event.Event, bool) { return ev, true }
+	pub1.On(eventTypeOther, sub1).On(eventTypeOther, event.Bridge(pub2, convert))
+	pub2.On(eventTypeOther, sub2).On(eventTypeOther, event.Bridge(pub1, convert)).
+		On(eventTypeOther, event.Func(func(ctx context.Context, ev event.Event) error {
+			if ev, ok := ev.(eventBatch); ok && len(ev) == 1 {
+				return pub2.Publish(ctx, eventOther(3))
+			}
+			return nil
+		}))
+	if err := pub1.Publish(ctx, eventBatch{1}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventBatch{1}, eventOther(3)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := []event.Event{eventBatch{1}, eventOther(3)}; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+}
@@ -0,0 +1,110 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ErrorBudget is an event subscriber to track the rolling error rate of the
+// subscriber, and to alert when the error rate exceeds the budget, so that the
+// broken subscribers are noticed early. The panics are counted as errors and
+// propagated.
+type ErrorBudget struct {
+	name       string
+	subscriber Subscriber
+	budget     float64
+	window     time.Duration
+	alert      func(name string, rate float64)
+	minEvents  int
+	meta       Publisher
+	clock      Clock
+	mu         sync.Mutex
+	results    []budgetResult
+	failed     int
+	alerted    bool
+}
+
+type budgetResult struct {
+	time   time.Time
+	failed bool
+}
+
+// NewErrorBudget creates a new subscriber to track the error rate within the
+// window. The alert function is called with the name of the subscriber when
+// the error rate exceeds the budget, and is not called again until the error
+// rate recovers within the budget. The alert function can be nil when the
+// meta events are enough.
+func NewErrorBudget(
+	name string, sub Subscriber, budget float64, window time.Duration,
+	alert func(name string, rate float64),
+) *ErrorBudget {
+	return &ErrorBudget{
+		name: name, subscriber: sub, budget: budget, window: window,
+		alert: alert, minEvents: 1,
+	}
+}
+
+// MinEvents sets the minimum number of events within the window to alert, not
+// to alert on a few errors after a quiet period. This method returns the
+// subscriber to allow method chaining.
+func (sub *ErrorBudget) MinEvents(n int) *ErrorBudget {
+	sub.minEvents = n
+	return sub
+}
+
+// Meta sets the publisher to publish an ErrorBudgetExceeded meta event when the
+// error rate exceeds the budget. This method returns the subscriber to allow
+// method chaining.
+func (sub *ErrorBudget) Meta(pub Publisher) *ErrorBudget {
+	sub.meta = pub
+	return sub
+}
+
+// Clock sets the clock of the rolling window instead of the global clock. This
+// method returns the subscriber to allow method chaining.
+func (sub *ErrorBudget) Clock(c Clock) *ErrorBudget {
+	sub.clock = c
+	return sub
+}
+
+// Handle implements Subscriber for ErrorBudget.
+func (sub *ErrorBudget) Handle(ctx context.Context, ev Event) (err error) {
+	failed := true
+	defer func() { sub.record(ctx, failed) }()
+	err = sub.subscriber.Handle(ctx, ev)
+	failed = err != nil
+	return err
+}
+
+func (sub *ErrorBudget) record(ctx context.Context, failed bool) {
+	sub.mu.Lock()
+	now := clockOr(sub.clock).Now()
+	sub.results = append(sub.results, budgetResult{now, failed})
+	if failed {
+		sub.failed++
+	}
+	since, i := now.Add(-sub.window), 0
+	for ; i < len(sub.results) && sub.results[i].time.Before(since); i++ {
+		if sub.results[i].failed {
+			sub.failed--
+		}
+	}
+	sub.results = sub.results[i:]
+	rate := float64(sub.failed) / float64(len(sub.results))
+	var alert bool
+	if rate <= sub.budget {
+		sub.alerted = false
+	} else if !sub.alerted && len(sub.results) >= sub.minEvents {
+		sub.alerted, alert = true, true
+	}
+	sub.mu.Unlock()
+	if alert {
+		if sub.alert != nil {
+			sub.alert(sub.name, rate)
+		}
+		if sub.meta != nil {
+			_ = sub.meta.Publish(ctx, &ErrorBudgetExceeded{sub.name, rate})
+		}
+	}
+}
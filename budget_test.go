@@ -0,0 +1,51 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestErrorBudget(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var alerts []string
+	sub := event.NewErrorBudget("sub", event.Func(func(_ context.Context, ev event.Event) error {
+		switch ev {
+		case eventCreated(1):
+			return errors.New("handle error")
+		case eventCreated(2):
+			panic("handle panic")
+		}
+		return nil
+	}), 0.5, 100*time.Millisecond, func(name string, rate float64) {
+		alerts = append(alerts, fmt.Sprintf("%s %.2f", name, rate))
+	}).MinEvents(3).Clock(clock)
+	handle := func(ev event.Event) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		return sub.Handle(ctx, ev)
+	}
+	for _, ev := range []event.Event{
+		eventCreated(1), eventCreated(2), eventCreated(0), eventCreated(1),
+		eventCreated(1), eventCreated(0), eventCreated(0), eventCreated(0), eventCreated(1),
+	} {
+		_ = handle(ev)
+	}
+	clock.Advance(200 * time.Millisecond)
+	for _, ev := range []event.Event{eventCreated(0), eventCreated(1), eventCreated(1)} {
+		_ = handle(ev)
+	}
+	if expected := []string{"sub 0.67", "sub 0.56", "sub 0.67"}; !reflect.DeepEqual(alerts, expected) {
+		t.Errorf("alerts: expected %v, got %v", expected, alerts)
+	}
+}
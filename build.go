@@ -0,0 +1,150 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Builder is a builder of a subscriber composed of the common wrappers. The
+// wrappers are applied in a consistent order regardless of the order of the
+// method calls; the panics are recovered on each attempt, each attempt is
+// limited by the timeout, the attempts are retried, the concurrency of the
+// whole handling is limited, and the errors are prefixed by the name.
+type Builder struct {
+	subscriber Subscriber
+	recover    bool
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+	clock      Clock
+	limit      int
+	name       string
+	once       sync.Once
+	built      Subscriber
+}
+
+// Build creates a new builder of the subscriber.
+func Build(sub Subscriber) *Builder {
+	return &Builder{subscriber: sub}
+}
+
+// Recover makes the subscriber recover the panics and return them as errors.
+// This method returns the builder to allow method chaining.
+func (b *Builder) Recover() *Builder {
+	b.recover = true
+	return b
+}
+
+// Timeout sets the timeout of each attempt. This method returns the builder to
+// allow method chaining.
+func (b *Builder) Timeout(timeout time.Duration) *Builder {
+	b.timeout = timeout
+	return b
+}
+
+// Retry sets the max number of retries on the errors. The retrying stops when
+// the context is canceled. This method returns the builder to allow method
+// chaining.
+func (b *Builder) Retry(n int) *Builder {
+	b.retries = n
+	return b
+}
+
+// Backoff sets the interval between the retries. The attempts are retried
+// immediately by default. This method returns the builder to allow method
+// chaining.
+func (b *Builder) Backoff(backoff time.Duration) *Builder {
+	b.backoff = backoff
+	return b
+}
+
+// Clock sets the clock of the backoff instead of the global clock. This method
+// returns the builder to allow method chaining.
+func (b *Builder) Clock(c Clock) *Builder {
+	b.clock = c
+	return b
+}
+
+// Limit sets the max concurrency of the subscriber. This method returns the
+// builder to allow method chaining.
+func (b *Builder) Limit(max int) *Builder {
+	b.limit = max
+	return b
+}
+
+// Named sets the name of the subscriber to prefix the errors. This method
+// returns the builder to allow method chaining.
+func (b *Builder) Named(name string) *Builder {
+	b.name = name
+	return b
+}
+
+// Name returns the name of the subscriber set by Named.
+func (b *Builder) Name() string {
+	return b.name
+}
+
+// Subscriber builds the composed subscriber. Note that the builder is built
+// only once, so configure the builder before building the subscriber.
+func (b *Builder) Subscriber() Subscriber {
+	b.once.Do(func() { b.built = b.build() })
+	return b.built
+}
+
+// Handle implements Subscriber for Builder, by the built subscriber.
+func (b *Builder) Handle(ctx context.Context, ev Event) error {
+	return b.Subscriber().Handle(ctx, ev)
+}
+
+func (b *Builder) build() Subscriber {
+	sub := b.subscriber
+	if b.recover {
+		s := sub
+		sub = Func(func(ctx context.Context, ev Event) error {
+			return handleRecover(ctx, s, ev)
+		})
+	}
+	if timeout := b.timeout; timeout > 0 {
+		s := sub
+		sub = Func(func(ctx context.Context, ev Event) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return s.Handle(ctx, ev)
+		})
+	}
+	if retries := b.retries; retries > 0 {
+		s := sub
+		backoff, clock := b.backoff, b.clock
+		sub = Func(func(ctx context.Context, ev Event) error {
+			err := s.Handle(ctx, ev)
+			for i := 0; err != nil && i < retries && ctx.Err() == nil; i++ {
+				if backoff > 0 {
+					timer := clockOr(clock).NewTimer(backoff)
+					select {
+					case <-ctx.Done():
+						timer.Stop()
+						return err
+					case <-timer.C():
+					}
+				}
+				err = s.Handle(ctx, ev)
+			}
+			return err
+		})
+	}
+	if b.limit > 0 {
+		sub = NewLimited(sub, b.limit)
+	}
+	if name := b.name; name != "" {
+		s := sub
+		sub = Func(func(ctx context.Context, ev Event) error {
+			if err := s.Handle(ctx, ev); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			return nil
+		})
+	}
+	return sub
+}
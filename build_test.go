@@ -0,0 +1,101 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestBuild(t *testing.T) {
+	ctx := context.Background()
+	var attempts, running, maxRunning int32
+	sub := event.Build(event.Func(func(ctx context.Context, ev event.Event) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		switch atomic.AddInt32(&attempts, 1) {
+		case 1:
+			panic("handle panic")
+		case 2:
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})).Named("sub").Retry(2).Limit(1).Timeout(10 * time.Millisecond).Recover()
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := int32(3); attempts != expected {
+		t.Errorf("expected %d attempts, got %d", expected, attempts)
+	}
+	if err := (event.Async{sub, sub, sub}).Handle(ctx, eventCreated(2)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := int32(1); maxRunning != expected {
+		t.Errorf("expected max concurrency %d, got %d", expected, maxRunning)
+	}
+}
+
+func TestBuildError(t *testing.T) {
+	ctx := context.Background()
+	var attempts int
+	sub := event.Build(event.Func(func(context.Context, event.Event) error {
+		attempts++
+		return errors.New("handle error")
+	})).Retry(2).Named("sub").Subscriber()
+	err := sub.Handle(ctx, eventCreated(1))
+	if expected := "sub: handle error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := 3; attempts != expected {
+		t.Errorf("expected %d attempts, got %d", expected, attempts)
+	}
+	if err := event.Build(event.Func(func(context.Context, event.Event) error {
+		panic("handle panic")
+	})).Recover().Handle(ctx, eventCreated(1)); err == nil || err.Error() != "panic: handle panic" {
+		t.Fatalf("expected panic error, got %v", err)
+	}
+}
+
+func TestBuildBackoff(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var attempts int32
+	sub := event.Build(event.Func(func(context.Context, event.Event) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return errors.New("handle error")
+		}
+		return nil
+	})).Retry(2).Backoff(time.Second).Clock(clock)
+	errc := make(chan error)
+	go func() { errc <- sub.Handle(ctx, eventCreated(1)) }()
+	clock.WaitTimers(1)
+	if got, expected := atomic.LoadInt32(&attempts), int32(1); got != expected {
+		t.Errorf("expected %d attempts, got %d", expected, got)
+	}
+	clock.Advance(time.Second)
+	if err := <-errc; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := atomic.LoadInt32(&attempts), int32(2); got != expected {
+		t.Errorf("expected %d attempts, got %d", expected, got)
+	}
+	atomic.StoreInt32(&attempts, 0)
+	cctx, cancel := context.WithCancel(ctx)
+	go func() { errc <- sub.Handle(cctx, eventCreated(2)) }()
+	clock.WaitTimers(1)
+	cancel()
+	if err, expected := <-errc, "handle error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
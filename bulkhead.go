@@ -0,0 +1,39 @@
+package event
+
+import "sync"
+
+// Bulkhead is a registry of the concurrency limits per downstream dependency,
+// like a database or an external API. The subscribers wrapped for the same
+// dependency share the limit, so that a slow dependency does not exhaust all
+// the workers of the subscribers.
+type Bulkhead struct {
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+// NewBulkhead creates a new bulkhead.
+func NewBulkhead() *Bulkhead {
+	return &Bulkhead{sems: make(map[string]chan struct{})}
+}
+
+// Limit sets the max concurrency of the handlers of the dependency. Set the
+// limits before wrapping the subscribers. This method returns the bulkhead to
+// allow method chaining.
+func (b *Bulkhead) Limit(dependency string, max int) *Bulkhead {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sems[dependency] = make(chan struct{}, max)
+	return b
+}
+
+// Wrap creates a new limited subscriber sharing the limit of the dependency.
+// The subscriber is not limited if the dependency has no limit.
+func (b *Bulkhead) Wrap(dependency string, sub Subscriber) Subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sem, ok := b.sems[dependency]
+	if !ok {
+		return sub
+	}
+	return &Limited{sub, sem}
+}
@@ -0,0 +1,53 @@
+package event_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestBulkhead(t *testing.T) {
+	ctx := context.Background()
+	var running, max int32
+	block := make(chan struct{})
+	db := event.Func(func(context.Context, event.Event) error {
+		n := atomic.AddInt32(&running, 1)
+		for m := atomic.LoadInt32(&max); n > m && !atomic.CompareAndSwapInt32(&max, m, n); m = atomic.LoadInt32(&max) {
+		}
+		<-block
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	bulkhead := event.NewBulkhead().Limit("db", 2)
+	pub := event.NewMapping().
+		On(eventTypeCreated, bulkhead.Wrap("db", db)).
+		On(eventTypeUpdated, bulkhead.Wrap("db", db)).
+		On(eventTypeDeleted, bulkhead.Wrap("cache", &logged{}))
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var ev event.Event = eventCreated(i)
+			if i%2 == 1 {
+				ev = eventUpdated(i)
+			}
+			if err := pub.Publish(ctx, ev); err != nil {
+				t.Errorf("got error: %v", err)
+			}
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := pub.Publish(ctx, eventDeleted(1)); err != nil {
+		t.Errorf("got error: %v", err)
+	}
+	close(block)
+	wg.Wait()
+	if got, expected := atomic.LoadInt32(&max), int32(2); got != expected {
+		t.Errorf("expected max concurrency %d, got %d", expected, got)
+	}
+}
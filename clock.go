@@ -0,0 +1,98 @@
+package event
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the interface of the time source of the time-dependent components of
+// this module, like Window, Tick, DeadLetter and the timeouts of Correlator.
+// Inject the clock to the components by their options, like WindowClock and
+// DeadLetter.Clock, in the tests to control the time without real sleeps. The
+// components without the clock injected use the global clock set by SetClock.
+// Note that the deadlines of the contexts rely on the system clock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(time.Duration) Timer
+	AfterFunc(time.Duration, func()) Timer
+	NewTicker(time.Duration) Ticker
+}
+
+// Timer is the interface of a timer created by Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(time.Duration) bool
+}
+
+// Ticker is the interface of a ticker created by Clock.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the clock of the system time, which is the default clock.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+var clock atomic.Value
+
+// SetClock sets the global clock of this module. Set nil to restore
+// SystemClock. Set the clock before starting the components, since the timers
+// already started keep running on the previous clock. The global clock is
+// shared by the parallel tests, so prefer injecting the clock to the components.
+func SetClock(c Clock) {
+	if c == nil {
+		c = SystemClock
+	}
+	clock.Store(&c)
+}
+
+// CurrentClock returns the global clock of this module set by SetClock.
+func CurrentClock() Clock {
+	if c, _ := clock.Load().(*Clock); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// clockOr returns the clock, or the global clock if nil.
+func clockOr(c Clock) Clock {
+	if c != nil {
+		return c
+	}
+	return CurrentClock()
+}
@@ -0,0 +1,65 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestSetClock(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := eventtest.NewClock(start)
+	event.SetClock(clock)
+	defer event.SetClock(nil)
+	if got := event.CurrentClock().Now(); !got.Equal(start) {
+		t.Fatalf("expected %v, got %v", start, got)
+	}
+	ctx := context.Background()
+	w := &windows{}
+	sub := event.NewWindow(w.handle, time.Minute)
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(59 * time.Second)
+	if got := w.get(); len(got) != 0 {
+		t.Fatalf("expected no windows, got %v", got)
+	}
+	clock.Advance(time.Second)
+	if expected := [][]event.Event{{eventCreated(1)}}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("expected %v, got %v", expected, w.get())
+	}
+	if got := clock.Timers(); got != 0 {
+		t.Errorf("expected no timers, got %d", got)
+	}
+}
+
+func TestSetClockTick(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := eventtest.NewClock(start)
+	event.SetClock(clock)
+	defer event.SetClock(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := make(chan time.Time)
+	go func() {
+		_ = event.Tick(ctx, event.Func(func(_ context.Context, ev event.Event) error {
+			ticks <- time.Time(ev.(eventTicked))
+			return nil
+		}), time.Hour, func(t time.Time) event.Event { return eventTicked(t) })
+	}()
+	for i := 1; i <= 3; i++ {
+		clock.WaitTimers(1)
+		clock.Advance(time.Hour)
+		if got, expected := <-ticks, start.Add(time.Duration(i)*time.Hour); !got.Equal(expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	}
+	event.SetClock(nil)
+	if got := event.CurrentClock(); got != event.SystemClock {
+		t.Errorf("expected the system clock, got %v", got)
+	}
+}
@@ -0,0 +1,370 @@
+// Command eventdoc generates the catalog of the event types in Markdown or
+// JSON, to keep the documentation of the events in sync with the code. This
+// command reads the Go files of the packages, and collects the event types
+// from the Type methods of the structs and the calls of MustRegisterType, the
+// payload fields of the structs, and the subscribers registered by On and
+// OnMatch of the mappings.
+//
+//	//go:generate eventdoc -output EVENTS.md . ./handlers
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const name = "eventdoc"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, outw, errw io.Writer) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(errw)
+	fs.Usage = func() {
+		fmt.Fprintf(errw, "Usage: %s [flags] [directory...]\n", name)
+		fs.PrintDefaults()
+	}
+	var format, output string
+	fs.StringVar(&format, "format", "markdown", "output format (markdown or json)")
+	fs.StringVar(&output, "output", "", "output file name (default standard output)")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if format != "markdown" && format != "json" {
+		fs.Usage()
+		return 2
+	}
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	if err := generate(dirs, format, output, outw); err != nil {
+		fmt.Fprintf(errw, "%s: %s\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// Event is the documentation of an event type.
+type Event struct {
+	Name        string   `json:"name"`
+	Constant    string   `json:"constant"`
+	Package     string   `json:"package"`
+	Struct      string   `json:"struct,omitempty"`
+	Doc         string   `json:"doc,omitempty"`
+	Fields      []Field  `json:"fields,omitempty"`
+	Subscribers []string `json:"subscribers,omitempty"`
+}
+
+// Field is the documentation of a payload field of an event.
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	JSON string `json:"json,omitempty"`
+	Doc  string `json:"doc,omitempty"`
+}
+
+func generate(dirs []string, format, output string, outw io.Writer) error {
+	var evs []*Event
+	for _, dir := range dirs {
+		es, err := parse(dir)
+		if err != nil {
+			return err
+		}
+		evs = append(evs, es...)
+	}
+	var buf bytes.Buffer
+	if format == "json" {
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if evs == nil {
+			evs = []*Event{}
+		}
+		_ = enc.Encode(evs) // Event is always serializable
+	} else {
+		_ = tmpl.Execute(&buf, evs) // the fields of the template always exist
+	}
+	if output == "" {
+		_, err := outw.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(output, buf.Bytes(), 0o644)
+}
+
+type collector struct {
+	pkg         string
+	structs     map[string]*ast.TypeSpec
+	docs        map[string]*ast.CommentGroup
+	consts      map[string]token.Pos
+	events      map[string]*Event
+	subscribers map[string][]subscriber
+	order       []string
+}
+
+type subscriber struct {
+	pos  token.Pos
+	expr string
+}
+
+func parse(dir string) ([]*Event, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	c := &collector{
+		structs:     make(map[string]*ast.TypeSpec),
+		docs:        make(map[string]*ast.CommentGroup),
+		consts:      make(map[string]token.Pos),
+		events:      make(map[string]*Event),
+		subscribers: make(map[string][]subscriber),
+	}
+	fset := token.NewFileSet()
+	var fs []*ast.File
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		c.pkg = f.Name.Name
+		fs = append(fs, f)
+	}
+	if c.pkg == "" {
+		return nil, errors.New("no Go files in " + dir)
+	}
+	for _, f := range fs {
+		c.collectDecls(f)
+	}
+	for _, f := range fs {
+		c.collectTypes(f)
+	}
+	for _, f := range fs {
+		c.collectSubscribers(f)
+	}
+	sort.SliceStable(c.order, func(i, j int) bool {
+		return c.consts[c.order[i]] < c.consts[c.order[j]]
+	})
+	evs := make([]*Event, len(c.order))
+	for i, k := range c.order {
+		evs[i] = c.events[k]
+		subs := c.subscribers[k]
+		sort.Slice(subs, func(i, j int) bool { return subs[i].pos < subs[j].pos })
+		for _, sub := range subs {
+			evs[i].Subscribers = append(evs[i].Subscribers, sub.expr)
+		}
+		if ts, ok := c.structs[evs[i].Struct]; ok {
+			evs[i].Doc = docText(c.docs[ts.Name.Name])
+			evs[i].Fields = fields(ts.Type.(*ast.StructType))
+		}
+		if evs[i].Name == "" {
+			if evs[i].Name = evs[i].Struct; evs[i].Name == "" {
+				evs[i].Name = k
+			}
+		}
+	}
+	return evs, nil
+}
+
+func (c *collector) event(constant string) *Event {
+	ev, ok := c.events[constant]
+	if !ok {
+		ev = &Event{Constant: constant, Package: c.pkg}
+		c.events[constant] = ev
+		c.order = append(c.order, constant)
+	}
+	return ev
+}
+
+// collectDecls collects the struct types and the positions of the constants.
+func (c *collector) collectDecls(f *ast.File) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if ok && gen.Tok == token.CONST {
+			for _, spec := range gen.Specs {
+				for _, n := range spec.(*ast.ValueSpec).Names {
+					c.consts[n.Name] = n.Pos()
+				}
+			}
+		}
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if _, ok := ts.Type.(*ast.StructType); ok {
+				c.structs[ts.Name.Name] = ts
+				if c.docs[ts.Name.Name] = ts.Doc; ts.Doc == nil && len(gen.Specs) == 1 {
+					c.docs[ts.Name.Name] = gen.Doc
+				}
+			}
+		}
+	}
+}
+
+// collectTypes collects the event types from the Type methods of the structs
+// and the calls of MustRegisterType.
+func (c *collector) collectTypes(f *ast.File) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Recv == nil || len(n.Recv.List) != 1 || n.Name.Name != "Type" ||
+				n.Body == nil || len(n.Body.List) != 1 {
+				return true
+			}
+			ret, ok := n.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				return true
+			}
+			k, ok := ret.Results[0].(*ast.Ident)
+			if !ok {
+				return true
+			}
+			recv := n.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if id, ok := recv.(*ast.Ident); ok && c.structs[id.Name] != nil {
+				c.event(k.Name).Struct = id.Name
+			}
+		case *ast.CallExpr:
+			if calleeName(n.Fun) != "MustRegisterType" || len(n.Args) != 2 {
+				return true
+			}
+			k := identName(n.Args[0])
+			lit, ok := n.Args[1].(*ast.BasicLit)
+			if k == "" || !ok || lit.Kind != token.STRING {
+				return true
+			}
+			if s, err := strconv.Unquote(lit.Value); err == nil {
+				c.event(k).Name = s
+			}
+		}
+		return true
+	})
+}
+
+// collectSubscribers collects the subscribers registered by On and OnMatch on
+// the collected event types.
+func (c *collector) collectSubscribers(f *ast.File) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var sub ast.Expr
+		switch sel, ok := call.Fun.(*ast.SelectorExpr); {
+		case !ok:
+			return true
+		case sel.Sel.Name == "On" && len(call.Args) == 2:
+			sub = call.Args[1]
+		case sel.Sel.Name == "OnMatch" && len(call.Args) == 3:
+			sub = call.Args[2]
+		default:
+			return true
+		}
+		if k := identName(call.Args[0]); c.events[k] != nil {
+			c.subscribers[k] = append(c.subscribers[k], subscriber{sub.Pos(), types.ExprString(sub)})
+		}
+		return true
+	})
+}
+
+func calleeName(fun ast.Expr) string {
+	if sel, ok := fun.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	return identName(fun)
+}
+
+func identName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	default:
+		return ""
+	}
+}
+
+func fields(st *ast.StructType) []Field {
+	var fs []Field
+	for _, field := range st.Fields.List {
+		typ := types.ExprString(field.Type)
+		var tag string
+		if field.Tag != nil {
+			if s, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tag = strings.Split(reflect.StructTag(s).Get("json"), ",")[0]
+			}
+		}
+		doc := docText(field.Doc)
+		if doc == "" {
+			doc = docText(field.Comment)
+		}
+		for _, n := range field.Names {
+			if n.IsExported() && tag != "-" {
+				fs = append(fs, Field{n.Name, typ, tag, doc})
+			}
+		}
+		if len(field.Names) == 0 {
+			fs = append(fs, Field{typ, typ, tag, doc})
+		}
+	}
+	return fs
+}
+
+func docText(doc *ast.CommentGroup) string {
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
+
+var tmpl = template.Must(template.New(name).Parse(`# Events
+{{range .}}
+## {{.Name}}
+{{- if .Doc}}
+
+{{.Doc}}
+{{- end}}
+
+- Type: ` + "`{{.Package}}.{{.Constant}}`" + `
+{{- if .Struct}}
+- Payload: ` + "`{{.Package}}.{{.Struct}}`" + `
+{{- end}}
+{{- if .Fields}}
+
+| Field | Type | JSON | Description |
+| --- | --- | --- | --- |
+{{- range .Fields}}
+| {{.Name}} | ` + "`{{.Type}}`" + ` | {{.JSON}} | {{.Doc}} |
+{{- end}}
+{{- end}}
+{{- if .Subscribers}}
+
+Subscribers:
+{{range .Subscribers}}
+- ` + "`{{.}}`" + `
+{{- end}}
+{{- end}}
+{{end}}`))
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const src = `package app
+
+import (
+	"context"
+
+	"github.com/itchyny/event-go"
+)
+
+const (
+	TypeUserCreated event.Type = iota + 1
+	TypeUserRetired
+	TypeAudit
+)
+
+func init() {
+	event.MustRegisterType(TypeUserCreated, "user.created")
+	event.MustRegisterType(TypeAudit, "audit")
+}
+
+// UserCreated is published when a user signs up.
+type UserCreated struct {
+	// The ID of the user.
+	ID    int    ` + "`json:\"id\"`" + `
+	Email string ` + "`json:\"email,omitempty\"`" + ` // The email address.
+	token string
+}
+
+func (*UserCreated) Type() event.Type {
+	return TypeUserCreated
+}
+
+type UserRetired struct {
+	UserCreated
+	At, By string
+}
+
+func (UserRetired) Type() event.Type {
+	return TypeUserRetired
+}
+
+func sendWelcome(context.Context, event.Event) error { return nil }
+
+var Mapping = event.NewMapping().
+	On(TypeUserCreated, event.Func(sendWelcome)).
+	On(TypeUserCreated, &Auditor{}).
+	OnMatch(TypeUserRetired, nil, event.Discard)
+
+type Auditor struct{}
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user_test.go"), []byte("package app_test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var outw, errw bytes.Buffer
+	if code := run([]string{dir}, &outw, &errw); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errw.String())
+	}
+	expected := "# Events\n" + `
+## user.created
+
+UserCreated is published when a user signs up.
+
+- Type: ` + "`app.TypeUserCreated`" + `
+- Payload: ` + "`app.UserCreated`" + `
+
+| Field | Type | JSON | Description |
+| --- | --- | --- | --- |
+| ID | ` + "`int`" + ` | id | The ID of the user. |
+| Email | ` + "`string`" + ` | email | The email address. |
+
+Subscribers:
+
+- ` + "`event.Func(sendWelcome)`" + `
+- ` + "`&Auditor{}`" + `
+
+## UserRetired
+
+- Type: ` + "`app.TypeUserRetired`" + `
+- Payload: ` + "`app.UserRetired`" + `
+
+| Field | Type | JSON | Description |
+| --- | --- | --- | --- |
+| UserCreated | ` + "`UserCreated`" + ` |  |  |
+| At | ` + "`string`" + ` |  |  |
+| By | ` + "`string`" + ` |  |  |
+
+Subscribers:
+
+- ` + "`event.Discard`" + `
+
+## audit
+
+- Type: ` + "`app.TypeAudit`" + `
+`
+	if got := outw.String(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "events.json")
+	var errw bytes.Buffer
+	if code := run([]string{"-format", "json", "-output", output, dir}, nil, &errw); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errw.String())
+	}
+	bs, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var evs []*Event
+	if err := json.Unmarshal(bs, &evs); err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(evs))
+	}
+	expected := &Event{
+		Name: "user.created", Constant: "TypeUserCreated", Package: "app", Struct: "UserCreated",
+		Doc: "UserCreated is published when a user signs up.",
+		Fields: []Field{
+			{"ID", "int", "id", "The ID of the user."},
+			{"Email", "string", "email", "The email address."},
+		},
+		Subscribers: []string{"event.Func(sendWelcome)", "&Auditor{}"},
+	}
+	if !reflect.DeepEqual(evs[0], expected) {
+		t.Errorf("expected %+v, got %+v", expected, evs[0])
+	}
+}
+
+func TestRunEmpty(t *testing.T) {
+	var outw, errw bytes.Buffer
+	if code := run(nil, &outw, &errw); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errw.String())
+	}
+	if got, expected := outw.String(), "# Events\n"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	outw.Reset()
+	if code := run([]string{"-format", "json", "."}, &outw, &errw); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errw.String())
+	}
+	if got, expected := outw.String(), "[]\n"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+const srcEdge = `package edge
+
+import "github.com/itchyny/event-go"
+
+const (
+	TypeUnnamed event.Type = iota
+	TypeVariable
+)
+
+var name = "variable"
+
+func init() {
+	event.MustRegisterType(TypeUnnamed, "")
+	event.MustRegisterType(TypeVariable, name)
+	event.MustRegisterType(event.Type(2), "converted")
+	event.MustRegisterType(other.TypeQualified, "qualified")
+	MustRegisterType(TypeUnnamed)
+}
+
+type Panicking struct{}
+
+func (Panicking) Type() event.Type { panic("not implemented") }
+
+type Converted struct{}
+
+func (Converted) Type() event.Type { return event.Type(2) }
+`
+
+func TestRunEdge(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "edge.go"), []byte(srcEdge), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var outw, errw bytes.Buffer
+	if code := run([]string{dir}, &outw, &errw); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errw.String())
+	}
+	expected := "# Events\n" + `
+## qualified
+
+- Type: ` + "`edge.TypeQualified`" + `
+
+## TypeUnnamed
+
+- Type: ` + "`edge.TypeUnnamed`" + `
+`
+	if got := outw.String(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	var errw bytes.Buffer
+	if code := run([]string{"-format", "yaml"}, nil, &errw); code != 2 {
+		t.Errorf("expected exit code 2, got %d", code)
+	}
+	if code := run([]string{"-unknown"}, nil, &errw); code != 2 {
+		t.Errorf("expected exit code 2, got %d", code)
+	}
+	if code := run([]string{"-h"}, nil, &errw); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+	dir := t.TempDir()
+	errw.Reset()
+	if code := run([]string{dir}, nil, &errw); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	errw.Reset()
+	if code := run([]string{"["}, nil, &errw); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "invalid.go"), []byte("package"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	errw.Reset()
+	if code := run([]string{dir}, nil, &errw); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+}
@@ -0,0 +1,168 @@
+// Command eventgen generates the boilerplate of event types. Given the names
+// of the event struct types, this command generates the Type constants, the
+// Type methods, a function to get the names of the types, and typed handler
+// adapters. This command is intended to be invoked by go generate.
+//
+//	//go:generate eventgen -type UserCreated,UserRetired
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const name = "eventgen"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, errw io.Writer) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(errw)
+	fs.Usage = func() {
+		fmt.Fprintf(errw, "Usage: %s -type T1,T2 [flags] [directory]\n", name)
+		fs.PrintDefaults()
+	}
+	var g generator
+	var types string
+	fs.StringVar(&types, "type", "", "comma-separated list of event struct type names (required)")
+	fs.StringVar(&g.output, "output", "events_gen.go", "output file name relative to the directory")
+	fs.StringVar(&g.prefix, "prefix", "EventType", "prefix of the event type constants")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if types == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	g.args, g.types = args, strings.Split(types, ",")
+	g.dir = "."
+	if fs.NArg() == 1 {
+		g.dir = fs.Arg(0)
+	}
+	if err := g.generate(); err != nil {
+		fmt.Fprintf(errw, "%s: %s\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+type generator struct {
+	dir, output, prefix string
+	args, types         []string
+}
+
+func (g *generator) generate() error {
+	pkg, structs, err := g.parse()
+	if err != nil {
+		return err
+	}
+	for _, typ := range g.types {
+		if !structs[typ] {
+			return fmt.Errorf("struct type not found: %s", typ)
+		}
+	}
+	var buf bytes.Buffer
+	_ = tmpl.Execute(&buf, map[string]interface{}{ // the keys of the template always exist
+		"Command": strings.Join(append([]string{name}, g.args...), " "),
+		"Package": pkg,
+		"Prefix":  g.prefix,
+		"Types":   g.types,
+	})
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(g.dir, g.output), src, 0o644)
+}
+
+func (g *generator) parse() (string, map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(g.dir, "*.go"))
+	if err != nil {
+		return "", nil, err
+	}
+	var pkg string
+	structs := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || filepath.Base(file) == g.output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return "", nil, err
+		}
+		pkg = f.Name.Name
+		ast.Inspect(f, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if _, ok := spec.Type.(*ast.StructType); ok {
+					structs[spec.Name.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	if pkg == "" {
+		return "", nil, errors.New("no Go files in " + g.dir)
+	}
+	return pkg, structs, nil
+}
+
+var tmpl = template.Must(template.New(name).Parse(`// Code generated by {{.Command}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/itchyny/event-go"
+)
+
+// Event types.
+const (
+{{- range $i, $typ := .Types}}
+	{{$.Prefix}}{{$typ}}{{if eq $i 0}} event.Type = iota + 1{{end}}
+{{- end}}
+)
+{{range .Types}}
+// Type implements event.Event for {{.}}.
+func (*{{.}}) Type() event.Type {
+	return {{$.Prefix}}{{.}}
+}
+{{end}}
+// {{.Prefix}}Name returns the name of the event type.
+func {{.Prefix}}Name(typ event.Type) string {
+	switch typ {
+{{- range .Types}}
+	case {{$.Prefix}}{{.}}:
+		return "{{.}}"
+{{- end}}
+	default:
+		return "{{.Prefix}}(" + strconv.Itoa(int(typ)) + ")"
+	}
+}
+{{range .Types}}
+// {{.}}Func is a typed event subscriber of {{.}}.
+type {{.}}Func func(context.Context, *{{.}}) error
+
+// Handle implements event.Subscriber for {{.}}Func.
+func (sub {{.}}Func) Handle(ctx context.Context, ev event.Event) error {
+	return sub(ctx, ev.(*{{.}}))
+}
+{{end}}`))
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	src := "package app\n\ntype UserCreated struct{ ID int }\n\ntype UserRetired struct{ ID int }\n"
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user_test.go"), []byte("package app_test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var errw bytes.Buffer
+	if code := run([]string{"-type", "UserCreated,UserRetired", "-prefix", "Type", dir}, &errw); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errw.String())
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "events_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `// Code generated by eventgen -type UserCreated,UserRetired -prefix Type ` + dir + `; DO NOT EDIT.
+
+package app
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/itchyny/event-go"
+)
+
+// Event types.
+const (
+	TypeUserCreated event.Type = iota + 1
+	TypeUserRetired
+)
+
+// Type implements event.Event for UserCreated.
+func (*UserCreated) Type() event.Type {
+	return TypeUserCreated
+}
+
+// Type implements event.Event for UserRetired.
+func (*UserRetired) Type() event.Type {
+	return TypeUserRetired
+}
+
+// TypeName returns the name of the event type.
+func TypeName(typ event.Type) string {
+	switch typ {
+	case TypeUserCreated:
+		return "UserCreated"
+	case TypeUserRetired:
+		return "UserRetired"
+	default:
+		return "Type(" + strconv.Itoa(int(typ)) + ")"
+	}
+}
+
+// UserCreatedFunc is a typed event subscriber of UserCreated.
+type UserCreatedFunc func(context.Context, *UserCreated) error
+
+// Handle implements event.Subscriber for UserCreatedFunc.
+func (sub UserCreatedFunc) Handle(ctx context.Context, ev event.Event) error {
+	return sub(ctx, ev.(*UserCreated))
+}
+
+// UserRetiredFunc is a typed event subscriber of UserRetired.
+type UserRetiredFunc func(context.Context, *UserRetired) error
+
+// Handle implements event.Subscriber for UserRetiredFunc.
+func (sub UserRetiredFunc) Handle(ctx context.Context, ev event.Event) error {
+	return sub(ctx, ev.(*UserRetired))
+}
+`
+	if string(got) != expected {
+		t.Errorf("generated code: expected\n%s\ngot\n%s", expected, got)
+	}
+	// Generating again should ignore the previously generated file.
+	if code := run([]string{"-type", "UserCreated", dir}, &errw); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errw.String())
+	}
+}
+
+func TestRunError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), []byte("package app\n\ntype UserCreated struct{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(t.TempDir(), "broken")
+	if err := os.MkdirAll(broken, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "user.go"), []byte("package app\n\ntype\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		args     []string
+		code     int
+		expected string
+	}{
+		{[]string{"-h"}, 0, "Usage: eventgen"},
+		{[]string{"-unknown"}, 2, "flag provided but not defined"},
+		{[]string{dir}, 2, "Usage: eventgen"},
+		{[]string{"-type", "UserCreated", dir, dir}, 2, "Usage: eventgen"},
+		{[]string{"-type", "UserRetired", dir}, 1, "eventgen: struct type not found: UserRetired"},
+		{[]string{"-type", "UserCreated", t.TempDir()}, 1, "eventgen: no Go files in "},
+		{[]string{"-type", "UserCreated", filepath.Join(dir, "[")}, 1, "eventgen: syntax error in pattern"},
+		{[]string{"-type", "UserCreated", broken}, 1, "eventgen: " + broken},
+		{[]string{"-type", "UserCreated", "-prefix", "-", dir}, 1, "eventgen: "},
+		{[]string{"-type", "UserCreated", "-output", "x/y.go", dir}, 1, "eventgen: open "},
+	}
+	for _, tc := range testCases {
+		var errw bytes.Buffer
+		if code := run(tc.args, &errw); code != tc.code {
+			t.Errorf("%v: expected exit code %d, got %d", tc.args, tc.code, code)
+		}
+		if !strings.Contains(errw.String(), tc.expected) {
+			t.Errorf("%v: expected error output to contain %q, got %q", tc.args, tc.expected, errw.String())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package event
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+type compensated struct {
+	subscriber Subscriber
+	undo       Subscriber
+}
+
+// WithCompensation pairs the subscriber with the undo subscriber, which is
+// called with the event when a later subscriber of Ordered fails after the
+// subscriber succeeded, to build a simple saga within a single publishing.
+// The undo subscribers are called in the reverse order of the handling, and
+// each undo subscriber is called at most once per event. The succeeded
+// subscriber is recorded in the context of Ordered, so the paired subscriber
+// can be wrapped by the middlewares passing the context through.
+func WithCompensation(sub, undo Subscriber) Subscriber {
+	return &compensated{sub, undo}
+}
+
+// Handle implements Subscriber for the paired subscriber.
+func (sub *compensated) Handle(ctx context.Context, ev Event) error {
+	err := sub.subscriber.Handle(ctx, ev)
+	if err == nil {
+		if cs, ok := ctx.Value(compensationsKey{}).(*compensations); ok && sameEvent(cs.event, ev) {
+			cs.mu.Lock()
+			cs.undos = append(cs.undos, sub.undo)
+			cs.mu.Unlock()
+		}
+	}
+	return err
+}
+
+// Ready implements Readier for the paired subscriber.
+func (sub *compensated) Ready(ctx context.Context) error {
+	return WaitReady(ctx, sub.subscriber)
+}
+
+type compensationsKey struct{}
+
+// compensations is the undo subscribers of the paired subscribers succeeded in
+// Ordered on the event, carried by the context. The follow-up events published
+// by the subscribers are not compensated by Ordered.
+type compensations struct {
+	event Event
+	mu    sync.Mutex
+	undos []Subscriber
+}
+
+func withCompensations(ctx context.Context, ev Event) (context.Context, *compensations) {
+	cs := &compensations{event: ev}
+	return context.WithValue(ctx, compensationsKey{}, cs), cs
+}
+
+// handled calls the undo subscribers of the recorded subscribers on failure.
+func (cs *compensations) handled(ctx context.Context, ev Event, err error) error {
+	if err == nil {
+		return nil
+	}
+	cs.mu.Lock()
+	undos := cs.undos
+	cs.undos = nil
+	cs.mu.Unlock()
+	for i := len(undos) - 1; i >= 0; i-- {
+		if e := undos[i].Handle(ctx, ev); e != nil {
+			err = &CompensationError{ev, err, e}
+		}
+	}
+	return err
+}
+
+// CompensationError is the error reported by Ordered when an undo subscriber
+// of WithCompensation fails.
+type CompensationError struct {
+	Event Event
+	Err   error
+	Undo  error
+}
+
+// Error implements error for CompensationError.
+func (err *CompensationError) Error() string {
+	return "compensate event type " + strconv.Itoa(int(err.Event.Type())) +
+		": " + err.Undo.Error() + " (on " + err.Err.Error() + ")"
+}
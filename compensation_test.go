@@ -0,0 +1,95 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestWithCompensation(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	step := func(name string, err error) event.Func {
+		return func(context.Context, event.Event) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	sub := event.Ordered{
+		event.WithCompensation(step("reserve", nil), step("release", nil)),
+		event.WithCompensation(step("charge", nil), step("refund", nil)),
+		event.WithCompensation(step("ship", errors.New("ship error")), step("unship", nil)),
+		step("notify", nil),
+		event.WithCompensation(step("audit", nil), step("unaudit", nil)),
+	}
+	if err, expected := sub.Handle(ctx, eventCreated(1)), "ship error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	expected := []string{"reserve", "charge", "ship", "refund", "release", "notify", "audit"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+}
+
+func TestWithCompensationError(t *testing.T) {
+	ctx := context.Background()
+	sub := event.NewMapping().
+		On(eventTypeCreated, event.WithCompensation(event.Func(func(context.Context, event.Event) error {
+			return nil
+		}), suberr{})).
+		On(eventTypeCreated, suberr{})
+	err := sub.Publish(ctx, eventCreated(1))
+	var e *event.CompensationError
+	if !errors.As(err, &e) || e.Event != eventCreated(1) {
+		t.Fatalf("expected CompensationError, got %v", err)
+	}
+	if expected := "compensate event type 0: handle error (on handle error)"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestWithCompensationWrapped(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	step := func(name string, err error) event.Func {
+		return func(context.Context, event.Event) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	follow := event.NewMapping().
+		On(eventTypeUpdated, event.WithCompensation(step("follow", nil), step("unfollow", nil)))
+	middleware := func(sub event.Subscriber) event.Subscriber {
+		return event.Func(sub.Handle)
+	}
+	pub := event.NewMux(
+		event.MuxMiddleware(middleware), event.MuxProfile(), event.MuxLabels(),
+		event.MuxFailures(event.Discard),
+	).
+		On(eventTypeCreated, event.WithCompensation(step("reserve", nil), step("release", nil))).
+		On(eventTypeCreated, event.WithCompensation(event.Func(func(ctx context.Context, ev event.Event) error {
+			calls = append(calls, "charge")
+			return follow.Publish(ctx, eventUpdated(ev.(eventCreated)))
+		}), step("refund", nil))).
+		On(eventTypeCreated, step("ship", errors.New("ship error")))
+	if err, expected := pub.Publish(ctx, eventCreated(1)), "ship error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	expected := []string{"reserve", "charge", "follow", "ship", "refund", "release"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+}
+
+func TestWithCompensationReady(t *testing.T) {
+	ctx := context.Background()
+	sub := &readySubscriber{ready: make(chan struct{}), err: errors.New("connect error")}
+	close(sub.ready)
+	pub := event.NewMux().On(eventTypeCreated, event.WithCompensation(sub, &logged{}))
+	if err, expected := event.WaitReady(ctx, pub), "connect error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
@@ -0,0 +1,29 @@
+package event
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a new context carrying the publisher.
+func NewContext(ctx context.Context, pub Publisher) context.Context {
+	return context.WithValue(ctx, contextKey{}, pub)
+}
+
+// FromContext returns the publisher stored in the context. This function
+// returns Discard if the context does not carry a publisher, so it is always
+// safe to publish events to the returned publisher.
+func FromContext(ctx context.Context) Publisher {
+	if pub, ok := ctx.Value(contextKey{}).(Publisher); ok && pub != nil {
+		return pub
+	}
+	return Discard
+}
+
+// WithSubscriberContext creates an event subscriber to augment the context
+// before the subscriber handles events, which is useful to attach loggers,
+// tracers or credentials for the subscriber.
+func WithSubscriberContext(sub Subscriber, fn func(context.Context) context.Context) Subscriber {
+	return &wrapper{func(ctx context.Context, ev Event) error {
+		return sub.Handle(fn(ctx), ev)
+	}, sub}
+}
@@ -0,0 +1,74 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if err := event.FromContext(ctx).Publish(ctx, eventCreated(0)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := event.FromContext(event.NewContext(ctx, nil)).Publish(ctx, eventCreated(0)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	sub1 := &logged{}
+	ctx = event.NewContext(ctx, event.NewMapping().On(eventTypeCreated, sub1))
+	evs := []event.Event{eventCreated(1), eventUpdated(2)}
+	for _, ev := range evs {
+		if err := event.FromContext(ctx).Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := evs[:1]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
+func TestWithSubscriberContext(t *testing.T) {
+	ctx := context.Background()
+	type key struct{}
+	var values []interface{}
+	handle := event.Func(func(ctx context.Context, _ event.Event) error {
+		values = append(values, ctx.Value(key{}))
+		return nil
+	})
+	pub := event.NewMapping().
+		On(eventTypeCreated, event.WithSubscriberContext(handle, func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, key{}, "sub1")
+		})).
+		On(eventTypeCreated, handle)
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []interface{}{"sub1", nil}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestWithSubscriberContextName(t *testing.T) {
+	ctx := context.Background()
+	var names []string
+	record := event.Func(func(ctx context.Context, _ event.Event) error {
+		name, _ := pprof.Label(ctx, "subscriber")
+		names = append(names, name)
+		return nil
+	})
+	identity := func(ctx context.Context) context.Context { return ctx }
+	pub := event.NewMux(event.MuxLabels()).
+		On(eventTypeCreated, event.WithSubscriberContext(event.Build(record).Named("record"), identity)).
+		On(eventTypeUpdated, event.WithSubscriberContext(record, identity))
+	for _, ev := range []event.Event{eventCreated(1), eventUpdated(2)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []string{"record", "event-go_test.TestWithSubscriberContextName.func1"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,267 @@
+package event
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Correlator is an event subscriber to wait for a set of related events, like
+// PaymentAuthorized and InventoryReserved with the same order ID, and publish
+// a combined event when all the event types are handled within the timeout.
+type Correlator struct {
+	publisher Publisher
+	key       func(Event) (interface{}, bool)
+	types     []Type
+	combine   func([]Event) Event
+	timeout   time.Duration
+	expire    func([]Event) Event
+	store     CorrelationStore
+	onError   func(error)
+	clock     Clock
+	mu        sync.Mutex
+	timers    map[interface{}]Timer
+}
+
+// CorrelationStore is the interface for storing the pending events of
+// Correlator. The methods are called concurrently so the implementations
+// should be goroutine safe.
+type CorrelationStore interface {
+	// Append the event to the events of the key, and return all of them.
+	Append(context.Context, interface{}, Event) ([]Event, error)
+	// Delete the events of the key, and return the deleted events.
+	Delete(context.Context, interface{}) ([]Event, error)
+}
+
+// NewCorrelator creates a new correlating subscriber. The key function returns
+// the correlation key of an event, or false to ignore the event. Once the
+// events of all the types are handled with the same key, the combine function
+// builds a new event from them, which is published to the publisher.
+func NewCorrelator(
+	pub Publisher,
+	key func(Event) (interface{}, bool),
+	types []Type,
+	combine func([]Event) Event,
+	timeout time.Duration,
+) *Correlator {
+	return &Correlator{
+		publisher: pub, key: key, types: types, combine: combine, timeout: timeout,
+		store:  &memoryCorrelationStore{events: make(map[interface{}][]Event)},
+		timers: make(map[interface{}]Timer),
+	}
+}
+
+// OnTimeout sets the function to build a timeout event from the pending events
+// when the timeout elapsed since the first event of the key. The pending events
+// are discarded by default, and reported to the dropped event observer. This
+// method returns the subscriber to allow method chaining.
+func (sub *Correlator) OnTimeout(expire func([]Event) Event) *Correlator {
+	sub.expire = expire
+	return sub
+}
+
+// Store sets the storage of the pending events. The events are stored in memory
+// by default. Note that the timeouts are tracked in memory regardless of the
+// storage. This method returns the subscriber to allow method chaining.
+func (sub *Correlator) Store(store CorrelationStore) *Correlator {
+	sub.store = store
+	return sub
+}
+
+// ErrorHandler sets the function to report the errors on the timeouts. The
+// errors are ignored by default. This method returns the subscriber to allow
+// method chaining.
+func (sub *Correlator) ErrorHandler(f func(error)) *Correlator {
+	sub.onError = f
+	return sub
+}
+
+// Clock sets the clock of the timeouts instead of the global clock. This method
+// returns the subscriber to allow method chaining.
+func (sub *Correlator) Clock(c Clock) *Correlator {
+	sub.clock = c
+	return sub
+}
+
+// Handle implements Subscriber for Correlator.
+func (sub *Correlator) Handle(ctx context.Context, ev Event) error {
+	if !sub.accepts(ev.Type()) {
+		return nil
+	}
+	key, ok := sub.key(ev)
+	if !ok {
+		return nil
+	}
+	evs, err := sub.store.Append(ctx, key, ev)
+	if err != nil {
+		return err
+	}
+	if !sub.completes(evs) {
+		sub.mu.Lock()
+		if _, ok := sub.timers[key]; !ok {
+			sub.timers[key] = clockOr(sub.clock).AfterFunc(sub.timeout, func() {
+				if err := sub.timedOut(key); err != nil && sub.onError != nil {
+					sub.onError(err)
+				}
+			})
+		}
+		sub.mu.Unlock()
+		return nil
+	}
+	sub.mu.Lock()
+	if timer, ok := sub.timers[key]; ok {
+		timer.Stop()
+		delete(sub.timers, key)
+	}
+	sub.mu.Unlock()
+	if evs, err = sub.store.Delete(ctx, key); err != nil || len(evs) == 0 {
+		return err
+	}
+	return sub.publisher.Publish(ctx, sub.combine(evs))
+}
+
+func (sub *Correlator) accepts(typ Type) bool {
+	for _, t := range sub.types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func (sub *Correlator) completes(evs []Event) bool {
+	for _, typ := range sub.types {
+		var found bool
+		for _, ev := range evs {
+			if ev.Type() == typ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (sub *Correlator) timedOut(key interface{}) error {
+	sub.mu.Lock()
+	delete(sub.timers, key)
+	sub.mu.Unlock()
+	ctx := context.Background()
+	evs, err := sub.store.Delete(ctx, key)
+	if err != nil || len(evs) == 0 {
+		return err
+	}
+	if sub.expire == nil {
+		for _, ev := range evs {
+			dropped(ctx, ev, DropExpired)
+		}
+		return nil
+	}
+	return sub.publisher.Publish(ctx, sub.expire(evs))
+}
+
+type memoryCorrelationStore struct {
+	mu     sync.Mutex
+	events map[interface{}][]Event
+}
+
+func (s *memoryCorrelationStore) Append(_ context.Context, key interface{}, ev Event) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	evs := append(s.events[key], ev)
+	s.events[key] = evs
+	return append([]Event(nil), evs...), nil
+}
+
+func (s *memoryCorrelationStore) Delete(_ context.Context, key interface{}) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	evs := s.events[key]
+	delete(s.events, key)
+	return evs, nil
+}
+
+// NewCorrelationStore creates a storage of the pending events of Correlator
+// backed by the store, so that the pending events are kept with the other
+// states. The events are serialized by the encode and decode functions, and
+// the keys are formatted by fmt.Sprint. The events are appended under the lock
+// of the process, so do not share the correlation keys across the processes.
+func NewCorrelationStore(
+	store Store,
+	encode func(Event) ([]byte, error),
+	decode func([]byte) (Event, error),
+) CorrelationStore {
+	return &correlationStore{store: store, encode: encode, decode: decode}
+}
+
+type correlationStore struct {
+	store  Store
+	encode func(Event) ([]byte, error)
+	decode func([]byte) (Event, error)
+	mu     sync.Mutex
+}
+
+var errCorruptedEvents = errors.New("event: corrupted correlation events")
+
+func (s *correlationStore) Append(ctx context.Context, key interface{}, ev Event) ([]Event, error) {
+	bs, err := s.encode(ev)
+	if err != nil {
+		return nil, err
+	}
+	k := fmt.Sprint(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, _, err := s.store.Load(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+	var buf [binary.MaxVarintLen64]byte
+	value = append(value, buf[:binary.PutUvarint(buf[:], uint64(len(bs)))]...)
+	value = append(value, bs...)
+	evs, err := s.decodeEvents(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Save(ctx, k, value); err != nil {
+		return nil, err
+	}
+	return evs, nil
+}
+
+func (s *correlationStore) Delete(ctx context.Context, key interface{}) ([]Event, error) {
+	k := fmt.Sprint(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok, err := s.store.Load(ctx, k)
+	if err != nil || !ok {
+		return nil, err
+	}
+	evs, err := s.decodeEvents(value)
+	if err != nil {
+		return nil, err
+	}
+	return evs, s.store.Delete(ctx, k)
+}
+
+func (s *correlationStore) decodeEvents(value []byte) ([]Event, error) {
+	var evs []Event
+	for len(value) > 0 {
+		n, m := binary.Uvarint(value)
+		if m <= 0 || n > uint64(len(value)-m) {
+			return nil, errCorruptedEvents
+		}
+		ev, err := s.decode(value[m : m+int(n)])
+		if err != nil {
+			return nil, err
+		}
+		evs = append(evs, ev)
+		value = value[m+int(n):]
+	}
+	return evs, nil
+}
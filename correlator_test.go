@@ -0,0 +1,221 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type eventCorrelated struct {
+	Events  []event.Event
+	Expired bool
+}
+
+func (eventCorrelated) Type() event.Type {
+	return eventTypeOther
+}
+
+func newCorrelator(pub event.Publisher, timeout time.Duration) *event.Correlator {
+	return event.NewCorrelator(
+		pub,
+		func(ev event.Event) (interface{}, bool) {
+			switch ev := ev.(type) {
+			case eventCreated:
+				return int(ev), true
+			case eventUpdated:
+				return int(ev), true
+			default:
+				return nil, false
+			}
+		},
+		[]event.Type{eventTypeCreated, eventTypeUpdated},
+		func(evs []event.Event) event.Event {
+			return eventCorrelated{Events: evs}
+		},
+		timeout,
+	).OnTimeout(func(evs []event.Event) event.Event {
+		return eventCorrelated{Events: evs, Expired: true}
+	})
+}
+
+func TestCorrelator(t *testing.T) {
+	ctx := context.Background()
+	published := make(chan event.Event, 10)
+	sub := newCorrelator(event.Func(func(_ context.Context, ev event.Event) error {
+		published <- ev
+		return nil
+	}), 20*time.Millisecond)
+	evs := []event.Event{
+		eventCreated(1), eventCreated(2), eventDeleted(1), eventUpdated(1), eventUpdated(3),
+	}
+	for _, ev := range evs {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if got, expected := <-published, (eventCorrelated{Events: []event.Event{evs[0], evs[3]}}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("published event: expected %v, got %v", expected, got)
+	}
+	got := []event.Event{<-published, <-published}
+	if got[0].(eventCorrelated).Events[0] == evs[4] {
+		got[0], got[1] = got[1], got[0]
+	}
+	expected := []event.Event{
+		eventCorrelated{Events: []event.Event{evs[1]}, Expired: true},
+		eventCorrelated{Events: []event.Event{evs[4]}, Expired: true},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("published events: expected %v, got %v", expected, got)
+	}
+}
+
+func TestCorrelatorClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	pub := &logged{}
+	sub := newCorrelator(event.Func(pub.Handle), time.Minute).Clock(clock)
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(59 * time.Second)
+	if got := pub.Events(); len(got) != 0 {
+		t.Fatalf("expected no events, got %v", got)
+	}
+	clock.Advance(time.Second)
+	if expected := []event.Event{
+		eventCorrelated{Events: []event.Event{eventCreated(1)}, Expired: true},
+	}; !reflect.DeepEqual(pub.Events(), expected) {
+		t.Errorf("published events: expected %v, got %v", expected, pub.Events())
+	}
+}
+
+type correlationStoreError struct {
+	events map[interface{}][]event.Event
+}
+
+func (s *correlationStoreError) Append(_ context.Context, key interface{}, ev event.Event) ([]event.Event, error) {
+	if s.events == nil {
+		return nil, errors.New("append error")
+	}
+	s.events[key] = append(s.events[key], ev)
+	return s.events[key], nil
+}
+
+func (*correlationStoreError) Delete(context.Context, interface{}) ([]event.Event, error) {
+	return nil, errors.New("delete error")
+}
+
+func TestCorrelatorStoreError(t *testing.T) {
+	ctx := context.Background()
+	pub := &logged{}
+	sub := newCorrelator(event.Func(pub.Handle), time.Minute).Store(&correlationStoreError{})
+	if err, expected := sub.Handle(ctx, eventCreated(1)), "append error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := sub.Handle(ctx, eventDeleted(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var errs []error
+	sub = newCorrelator(event.Func(pub.Handle), time.Minute).
+		Store(&correlationStoreError{events: make(map[interface{}][]event.Event)}).
+		Clock(clock).
+		ErrorHandler(func(err error) { errs = append(errs, err) })
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2)} {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err, expected := sub.Handle(ctx, eventUpdated(1)), "delete error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	clock.Advance(time.Minute)
+	if expected := "delete error"; len(errs) != 1 || errs[0].Error() != expected {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+	if len(pub.Events()) != 0 {
+		t.Errorf("published events: expected no events, got %v", pub.Events())
+	}
+	sub = event.NewCorrelator(event.Func(pub.Handle), func(event.Event) (interface{}, bool) {
+		return nil, false
+	}, []event.Type{eventTypeCreated}, nil, time.Minute)
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestCorrelationStore(t *testing.T) {
+	ctx := context.Background()
+	encode := func(ev event.Event) ([]byte, error) {
+		switch ev := ev.(type) {
+		case eventCreated:
+			return []byte("c" + strconv.Itoa(int(ev))), nil
+		case eventUpdated:
+			return []byte("u" + strconv.Itoa(int(ev))), nil
+		default:
+			return nil, errors.New("encode error")
+		}
+	}
+	decode := func(bs []byte) (event.Event, error) {
+		n, err := strconv.Atoi(string(bs[1:]))
+		if err != nil {
+			return nil, err
+		}
+		if bs[0] == 'c' {
+			return eventCreated(n), nil
+		}
+		return eventUpdated(n), nil
+	}
+	pub, store := &logged{}, event.NewMemoryStore()
+	sub := newCorrelator(event.Func(pub.Handle), time.Minute).
+		Store(event.NewCorrelationStore(store, encode, decode))
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2), eventUpdated(1)} {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	expected := []event.Event{
+		eventCorrelated{Events: []event.Event{eventCreated(1), eventUpdated(1)}},
+	}
+	if !reflect.DeepEqual(pub.Events(), expected) {
+		t.Errorf("published events: expected %v, got %v", expected, pub.Events())
+	}
+	if _, ok, err := store.Load(ctx, "1"); err != nil || ok {
+		t.Errorf("expected the events of 1 deleted, got %v, %v", ok, err)
+	}
+	if _, ok, err := store.Load(ctx, "2"); err != nil || !ok {
+		t.Errorf("expected the events of 2 stored, got %v, %v", ok, err)
+	}
+	s := event.NewCorrelationStore(store, encode, decode)
+	if _, err := s.Append(ctx, 3, eventDeleted(3)); err == nil || err.Error() != "encode error" {
+		t.Errorf("expected encode error, got %v", err)
+	}
+	for key, value := range map[string]string{"4": "\x05c4", "5": "\x01c"} {
+		if err := store.Save(ctx, key, []byte(value)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if _, err := s.Append(ctx, key, eventCreated(4)); err == nil {
+			t.Errorf("expected an error on %q", value)
+		}
+		if _, err := s.Delete(ctx, key); err == nil {
+			t.Errorf("expected an error on %q", value)
+		}
+	}
+	if evs, err := s.Delete(ctx, 6); err != nil || evs != nil {
+		t.Errorf("expected no events, got %v, %v", evs, err)
+	}
+	s = event.NewCorrelationStore(storeError{load: errors.New("load error")}, encode, decode)
+	if _, err := s.Append(ctx, 1, eventCreated(1)); err == nil || err.Error() != "load error" {
+		t.Errorf("expected load error, got %v", err)
+	}
+	s = event.NewCorrelationStore(storeError{event.NewMemoryStore(), nil, errors.New("save error")}, encode, decode)
+	if _, err := s.Append(ctx, 1, eventCreated(1)); err == nil || err.Error() != "save error" {
+		t.Errorf("expected save error, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DeadLetter is an event subscriber to retry handling events by the subscriber,
+// and to pass the events failed in all the attempts to the sink, like a dead
+// letter queue, instead of returning the errors.
+type DeadLetter struct {
+	name       string
+	subscriber Subscriber
+	sink       func(context.Context, *DeadLetterRecord) error
+	attempts   int
+	backoff    time.Duration
+	quarantine *quarantine
+	meta       Publisher
+	clock      Clock
+}
+
+type quarantine struct {
+	key    func(Event) (interface{}, bool)
+	max    int
+	sink   func(context.Context, *DeadLetterRecord) error
+	alert  func(*DeadLetterRecord)
+	mu     sync.Mutex
+	counts map[interface{}]int
+}
+
+// DeadLetterRecord is the record of a dead-lettered event.
+type DeadLetterRecord struct {
+	Event       Event
+	Subscriber  string
+	Attempts    []DeadLetterAttempt
+	Disposition Disposition
+}
+
+// DeadLetterAttempt is an attempt to handle a dead-lettered event.
+type DeadLetterAttempt struct {
+	Time time.Time
+	Err  error
+}
+
+// Err returns the error of the last attempt.
+func (r *DeadLetterRecord) Err() error {
+	if len(r.Attempts) == 0 {
+		return nil
+	}
+	return r.Attempts[len(r.Attempts)-1].Err
+}
+
+// Disposition is the final disposition of a dead-lettered event.
+type Disposition int
+
+const (
+	// DispositionDeadLettered means the event is passed to the sink.
+	DispositionDeadLettered Disposition = iota + 1
+	// DispositionQuarantined means the event is passed to the quarantine sink.
+	DispositionQuarantined
+)
+
+// String implements fmt.Stringer for Disposition.
+func (d Disposition) String() string {
+	switch d {
+	case DispositionDeadLettered:
+		return "dead-lettered"
+	case DispositionQuarantined:
+		return "quarantined"
+	default:
+		return "unknown"
+	}
+}
+
+// NewDeadLetter creates a new dead letter subscriber. The name identifies the
+// subscriber in the records.
+func NewDeadLetter(name string, sub Subscriber, sink func(context.Context, *DeadLetterRecord) error) *DeadLetter {
+	return &DeadLetter{name: name, subscriber: sub, sink: sink, attempts: 1}
+}
+
+// Retry sets the max number of attempts and the interval between the attempts.
+// The event is handled only once by default. This method returns the
+// subscriber to allow method chaining.
+func (sub *DeadLetter) Retry(attempts int, backoff time.Duration) *DeadLetter {
+	sub.attempts, sub.backoff = attempts, backoff
+	return sub
+}
+
+// Quarantine makes the subscriber detect the poison events, which are
+// dead-lettered repeatedly, for example across redrives. The key function
+// returns the identity of an event like an ID or a hash, or false not to
+// track the event. Once the events with the same key are dead-lettered max
+// times, the events of the key are passed to the quarantine sink without
+// handling, and the alert function is called. Note that the counts are kept in
+// memory. This method returns the subscriber to allow method chaining.
+func (sub *DeadLetter) Quarantine(
+	key func(Event) (interface{}, bool),
+	max int,
+	sink func(context.Context, *DeadLetterRecord) error,
+	alert func(*DeadLetterRecord),
+) *DeadLetter {
+	sub.quarantine = &quarantine{
+		key: key, max: max, sink: sink, alert: alert,
+		counts: make(map[interface{}]int),
+	}
+	return sub
+}
+
+// Meta sets the publisher to publish an EventDeadLettered meta event on each
+// dead-lettered or quarantined event. This method returns the subscriber to
+// allow method chaining.
+func (sub *DeadLetter) Meta(pub Publisher) *DeadLetter {
+	sub.meta = pub
+	return sub
+}
+
+// Clock sets the clock of the backoff and the times of the attempts instead of
+// the global clock. This method returns the subscriber to allow method
+// chaining.
+func (sub *DeadLetter) Clock(c Clock) *DeadLetter {
+	sub.clock = c
+	return sub
+}
+
+// Handle implements Subscriber for DeadLetter. The retrying stops when the
+// context is canceled. This method returns an error only when the sink fails.
+func (sub *DeadLetter) Handle(ctx context.Context, ev Event) error {
+	r := &DeadLetterRecord{Event: ev, Subscriber: sub.name}
+	if q := sub.quarantine; q != nil {
+		if key, ok := q.key(ev); ok {
+			q.mu.Lock()
+			quarantined := q.counts[key] >= q.max
+			q.mu.Unlock()
+			if quarantined {
+				return sub.put(ctx, r, q.put)
+			}
+		}
+	}
+	for i := 0; i < sub.attempts || i == 0; i++ {
+		if i > 0 {
+			timer := clockOr(sub.clock).NewTimer(sub.backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return sub.deadLetter(ctx, r)
+			case <-timer.C():
+			}
+		}
+		err := sub.subscriber.Handle(ctx, ev)
+		if err == nil {
+			return nil
+		}
+		r.Attempts = append(r.Attempts, DeadLetterAttempt{clockOr(sub.clock).Now(), err})
+	}
+	return sub.deadLetter(ctx, r)
+}
+
+func (sub *DeadLetter) deadLetter(ctx context.Context, r *DeadLetterRecord) error {
+	if q := sub.quarantine; q != nil {
+		if key, ok := q.key(r.Event); ok {
+			q.mu.Lock()
+			q.counts[key]++
+			quarantined := q.counts[key] >= q.max
+			q.mu.Unlock()
+			if quarantined {
+				return sub.put(ctx, r, q.put)
+			}
+		}
+	}
+	r.Disposition = DispositionDeadLettered
+	return sub.put(ctx, r, sub.sink)
+}
+
+func (sub *DeadLetter) put(ctx context.Context, r *DeadLetterRecord, sink func(context.Context, *DeadLetterRecord) error) error {
+	if sub.meta != nil {
+		defer func() { _ = sub.meta.Publish(ctx, &EventDeadLettered{r}) }()
+	}
+	return sink(ctx, r)
+}
+
+func (q *quarantine) put(ctx context.Context, r *DeadLetterRecord) error {
+	r.Disposition = DispositionQuarantined
+	if q.alert != nil {
+		q.alert(r)
+	}
+	return q.sink(ctx, r)
+}
@@ -0,0 +1,152 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	var records []*event.DeadLetterRecord
+	var attempts int
+	sub := event.NewDeadLetter("sub", event.Func(func(_ context.Context, ev event.Event) error {
+		attempts++
+		if ev == eventCreated(1) && attempts < 3 {
+			return errors.New("temporary error")
+		}
+		if ev == eventCreated(2) {
+			return errors.New("permanent error")
+		}
+		return nil
+	}), func(_ context.Context, r *event.DeadLetterRecord) error {
+		records = append(records, r)
+		return nil
+	}).Retry(3, time.Millisecond)
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2)} {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := 6; attempts != expected {
+		t.Errorf("expected %d attempts, got %d", expected, attempts)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %v", records)
+	}
+	r := records[0]
+	if r.Event != eventCreated(2) || r.Subscriber != "sub" || len(r.Attempts) != 3 ||
+		r.Disposition != event.DispositionDeadLettered || r.Disposition.String() != "dead-lettered" {
+		t.Errorf("unexpected record: %#v", r)
+	}
+	if expected := "permanent error"; r.Err() == nil || r.Err().Error() != expected {
+		t.Errorf("expected %v, got %v", expected, r.Err())
+	}
+	for i := 1; i < len(r.Attempts); i++ {
+		if !r.Attempts[i-1].Time.Before(r.Attempts[i].Time) {
+			t.Errorf("expected attempts in order, got %v", r.Attempts)
+		}
+	}
+}
+
+func TestDeadLetterClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := eventtest.NewClock(now)
+	records := make(chan *event.DeadLetterRecord, 1)
+	sub := event.NewDeadLetter("sub", suberr{}, func(_ context.Context, r *event.DeadLetterRecord) error {
+		records <- r
+		return nil
+	}).Retry(3, time.Minute).Clock(clock)
+	go func() {
+		if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+			t.Errorf("got error: %v", err)
+		}
+	}()
+	for i := 0; i < 2; i++ {
+		clock.WaitTimers(1)
+		clock.Advance(time.Minute)
+	}
+	r := <-records
+	if len(r.Attempts) != 3 {
+		t.Fatalf("unexpected record: %#v", r)
+	}
+	for i, a := range r.Attempts {
+		if expected := now.Add(time.Duration(i) * time.Minute); !a.Time.Equal(expected) {
+			t.Errorf("attempt %d: expected %v, got %v", i, expected, a.Time)
+		}
+	}
+}
+
+func TestDeadLetterError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts []int
+	sub := event.NewDeadLetter("sub", event.Func(func(context.Context, event.Event) error {
+		cancel()
+		return errors.New("handle error")
+	}), func(_ context.Context, r *event.DeadLetterRecord) error {
+		attempts = append(attempts, len(r.Attempts))
+		return errors.New("sink error")
+	}).Retry(3, time.Minute)
+	if err, expected := sub.Handle(ctx, eventCreated(1)), "sink error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []int{1}; !reflect.DeepEqual(attempts, expected) {
+		t.Errorf("expected %v, got %v", expected, attempts)
+	}
+}
+
+func TestDeadLetterQuarantine(t *testing.T) {
+	ctx := context.Background()
+	var attempts int
+	var records, quarantined []*event.DeadLetterRecord
+	var alerts []event.Event
+	sub := event.NewDeadLetter("sub", event.Func(func(context.Context, event.Event) error {
+		attempts++
+		return errors.New("handle error")
+	}), func(_ context.Context, r *event.DeadLetterRecord) error {
+		records = append(records, r)
+		return nil
+	}).Quarantine(func(ev event.Event) (interface{}, bool) {
+		return int(ev.(eventCreated)), ev != eventCreated(0)
+	}, 2, func(_ context.Context, r *event.DeadLetterRecord) error {
+		quarantined = append(quarantined, r)
+		return nil
+	}, func(r *event.DeadLetterRecord) {
+		alerts = append(alerts, r.Event)
+	})
+	for _, ev := range []event.Event{
+		eventCreated(1), eventCreated(2), eventCreated(1), eventCreated(1),
+		eventCreated(0), eventCreated(0), eventCreated(0),
+	} {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := 6; attempts != expected {
+		t.Errorf("expected %d attempts, got %d", expected, attempts)
+	}
+	if len(records) != 5 {
+		t.Fatalf("expected 5 records, got %v", records)
+	}
+	if len(quarantined) != 2 {
+		t.Fatalf("expected 2 quarantined records, got %v", quarantined)
+	}
+	for i, r := range quarantined {
+		if r.Event != eventCreated(1) || r.Disposition != event.DispositionQuarantined || len(r.Attempts) != 1-i ||
+			r.Disposition.String() != "quarantined" || (r.Err() == nil) != (i == 1) {
+			t.Errorf("unexpected quarantined record: %#v", r)
+		}
+	}
+	if got, expected := event.Disposition(0).String(), "unknown"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(1)}; !reflect.DeepEqual(alerts, expected) {
+		t.Errorf("alerts: expected %v, got %v", expected, alerts)
+	}
+}
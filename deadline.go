@@ -0,0 +1,51 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// SplitDeadline creates an event subscriber to handle in order of the
+// subscribers as well as Ordered, but splitting the remaining deadline of the
+// context across the subscribers, so that a slow subscriber does not consume
+// the entire deadline and starve the rest. The deadline is split equally by
+// default, or by the weights of the subscribers. The deadline is split equally
+// when the weights sum to zero or less. The time left by a subscriber is split
+// across the rest. The context without the deadline is not split.
+func (sub Ordered) SplitDeadline(weights ...float64) Func {
+	return func(ctx context.Context, ev Event) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return sub.Handle(ctx, ev)
+		}
+		weight := func(i int) float64 {
+			if i < len(weights) {
+				return weights[i]
+			}
+			return 1
+		}
+		var total float64
+		for i := range sub {
+			total += weight(i)
+		}
+		if total <= 0 {
+			weight = func(int) float64 { return 1 }
+			total = float64(len(sub))
+		}
+		var err error
+		ctx, cs := withCompensations(ctx, ev)
+		for i, s := range sub {
+			d := deadline.Sub(CurrentClock().Now())
+			if total > 0 {
+				d = time.Duration(float64(d) * weight(i) / total)
+			}
+			total -= weight(i)
+			cctx, cancel := context.WithTimeout(ctx, d)
+			if e := cs.handled(ctx, ev, s.Handle(cctx, ev)); e != nil {
+				err = e
+			}
+			cancel()
+		}
+		return err
+	}
+}
@@ -0,0 +1,98 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestOrderedSplitDeadline(t *testing.T) {
+	var budgets []time.Duration
+	record := func(block bool) event.Subscriber {
+		return event.Func(func(ctx context.Context, _ event.Event) error {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatalf("expected a deadline")
+			}
+			budgets = append(budgets, time.Until(deadline))
+			if block {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	sub := event.Ordered{record(true), record(false), record(false)}.SplitDeadline(2, 1)
+	if err, expected := sub.Handle(ctx, eventCreated(1)), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	for i, expected := range []time.Duration{100 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond} {
+		if got := budgets[i]; got > expected || got < expected-20*time.Millisecond {
+			t.Errorf("budget of subscriber %d: expected about %v, got %v", i, expected, got)
+		}
+	}
+	sub = event.Ordered{event.Func(func(ctx context.Context, _ event.Event) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Errorf("expected no deadline")
+		}
+		return nil
+	})}.SplitDeadline()
+	if err := sub.Handle(context.Background(), eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestOrderedSplitDeadlineZeroWeights(t *testing.T) {
+	var budgets []time.Duration
+	record := event.Func(func(ctx context.Context, _ event.Event) error {
+		deadline, _ := ctx.Deadline()
+		budgets = append(budgets, time.Until(deadline))
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	for _, weights := range [][]float64{{0, 0}, {-1, 0}} {
+		budgets = nil
+		sub := event.Ordered{record, record}.SplitDeadline(weights...)
+		if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		for i, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond} {
+			if got := budgets[i]; got > expected || got < expected-20*time.Millisecond {
+				t.Errorf("budget of subscriber %d: expected about %v, got %v", i, expected, got)
+			}
+		}
+	}
+	budgets = nil
+	sub := event.Ordered{record, record}.SplitDeadline(1, 0)
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := budgets[1], 150*time.Millisecond; got < expected {
+		t.Errorf("budget of the last subscriber: expected the rest, got %v", got)
+	}
+}
+
+func TestMuxSplitDeadline(t *testing.T) {
+	var budgets []time.Duration
+	record := event.Func(func(ctx context.Context, _ event.Event) error {
+		if deadline, ok := ctx.Deadline(); ok {
+			budgets = append(budgets, time.Until(deadline))
+		}
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	pub := event.NewMux(event.MuxSplitDeadline()).
+		On(eventTypeCreated, record).On(eventTypeCreated, record)
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(budgets) != 2 || budgets[0] > 50*time.Millisecond || budgets[1] < 50*time.Millisecond {
+		t.Errorf("unexpected budgets: %v", budgets)
+	}
+}
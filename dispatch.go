@@ -0,0 +1,124 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// DispatchAsync dispatches all the buffered events concurrently by the workers.
+// The events are dispatched in no particular order, unless the key function is
+// set by OrderBy to dispatch the events with the same key in order. The events
+// published by the subscribers are dispatched after the current events.
+func (pub *Buffer) DispatchAsync(ctx context.Context, workers int) error {
+	if workers < 1 {
+		workers = 1
+	}
+	pub.coalesce()
+	var (
+		n   int
+		mu  sync.Mutex
+		err error
+	)
+	for {
+		evs, e := pub.take(ctx, &n, 0)
+		if e != nil {
+			err = e
+		}
+		if len(evs) == 0 {
+			return err
+		}
+		chs := make([]chan bufferedEvent, 1)
+		if pub.orderKey != nil {
+			chs = make([]chan bufferedEvent, workers)
+		}
+		for i := range chs {
+			chs[i] = make(chan bufferedEvent)
+		}
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for i := 0; i < workers; i++ {
+			ch := chs[i%len(chs)]
+			spawn(ctx, func() {
+				defer wg.Done()
+				for ev := range ch {
+					if e := pub.dispatch(ctx, ev); e != nil {
+						mu.Lock()
+						err = e
+						mu.Unlock()
+					}
+				}
+			})
+		}
+		for _, ev := range evs {
+			var i int
+			if pub.orderKey != nil {
+				h := fnv.New32a()
+				fmt.Fprint(h, pub.orderKey(ev.event))
+				i = int(h.Sum32() % uint32(len(chs)))
+			}
+			chs[i] <- ev
+		}
+		for _, ch := range chs {
+			close(ch)
+		}
+		wg.Wait()
+	}
+}
+
+// DispatchWhere dispatches the buffered events matching the predicate, and
+// keeps the rest of the events in the buffer. This is useful to dispatch some
+// kinds of the events early, while holding the others until the transaction
+// succeeded. When the number of the dispatched events exceeds the limit set by
+// MaxDispatch, only the events matching the predicate are discarded.
+func (pub *Buffer) DispatchWhere(ctx context.Context, pred func(Event) bool) error {
+	pub.coalesce()
+	var (
+		n   int
+		err error
+	)
+	for {
+		ev, ok, e := pub.takeWhere(ctx, &n, pred)
+		if e != nil {
+			err = e
+		}
+		if !ok {
+			return err
+		}
+		if e := pub.dispatch(ctx, ev); e != nil {
+			err = e
+		}
+	}
+}
+
+func (pub *Buffer) takeWhere(ctx context.Context, n *int, pred func(Event) bool) (bufferedEvent, bool, error) {
+	pub.mu.Lock()
+	for i, ev := range pub.events {
+		if !pred(ev.event) {
+			continue
+		}
+		if pub.maxDispatch > 0 && *n >= pub.maxDispatch {
+			evs, discarded := pub.events[:i:i], []Event{}
+			for _, ev := range pub.events[i:] {
+				if pred(ev.event) {
+					discarded = append(discarded, ev.event)
+				} else {
+					evs = append(evs, ev)
+				}
+			}
+			pub.events = evs
+			pub.mu.Unlock()
+			for _, ev := range discarded {
+				dropped(ctx, ev, DropLimitExceeded)
+			}
+			return bufferedEvent{}, false, &DispatchLimitError{pub.maxDispatch, len(discarded)}
+		}
+		*n++
+		pub.events = append(pub.events[:i], pub.events[i+1:]...)
+		pub.mu.Unlock()
+		return ev, true, nil
+	}
+	pub.mu.Unlock()
+	return bufferedEvent{}, false, nil
+}
@@ -0,0 +1,163 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestBufferDispatchAsync(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu      sync.Mutex
+		handled []int
+		running int32
+		max     int32
+	)
+	var pub *event.Buffer
+	pub = event.NewBuffer(event.Func(func(ctx context.Context, ev event.Event) error {
+		if n := atomic.AddInt32(&running, 1); n > atomic.LoadInt32(&max) {
+			atomic.StoreInt32(&max, n)
+		}
+		defer atomic.AddInt32(&running, -1)
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		handled = append(handled, int(ev.(eventCreated)))
+		mu.Unlock()
+		if ev == eventCreated(3) {
+			return pub.Publish(ctx, eventCreated(10))
+		}
+		if ev == eventCreated(4) {
+			return errors.New("handle error")
+		}
+		return nil
+	}))
+	for i := 0; i < 8; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err, expected := pub.DispatchAsync(ctx, 4), "handle error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if max < 2 {
+		t.Errorf("expected concurrent dispatching, got max concurrency %d", max)
+	}
+	if handled[len(handled)-1] != 10 {
+		t.Errorf("expected the published event dispatched last, got %v", handled)
+	}
+	sort.Ints(handled)
+	if expected := []int{0, 1, 2, 3, 4, 5, 6, 7, 10}; !reflect.DeepEqual(handled, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, handled)
+	}
+}
+
+func TestBufferDispatchAsyncOrderBy(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	handled := make(map[int][]int)
+	pub := event.NewBuffer(event.Func(func(_ context.Context, ev event.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		i := int(ev.(eventCreated))
+		handled[i%3] = append(handled[i%3], i)
+		return nil
+	})).OrderBy(func(ev event.Event) interface{} {
+		return int(ev.(eventCreated)) % 3
+	}).MaxDispatch(20)
+	for i := 0; i < 30; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	err := pub.DispatchAsync(ctx, 4)
+	if expected := "dispatch limit exceeded: 20 events dispatched, 10 events discarded"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	for k, is := range handled {
+		if len(is) < 6 || !sort.IntsAreSorted(is) {
+			t.Errorf("expected events of key %d handled in order, got %v", k, is)
+		}
+	}
+}
+
+func TestBufferDispatchWhere(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	var pub *event.Buffer
+	pub = event.NewBuffer(event.NewMapping().
+		On(eventTypeCreated, sub1).
+		On(eventTypeUpdated, sub1).
+		On(eventTypeUpdated, event.Func(func(ctx context.Context, ev event.Event) error {
+			return pub.Publish(ctx, eventUpdated(ev.(eventUpdated)+10))
+		})),
+	).MaxDispatch(3)
+	evs := []event.Event{eventCreated(1), eventUpdated(2), eventCreated(3), eventUpdated(4)}
+	for _, ev := range evs {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	err := pub.DispatchWhere(ctx, func(ev event.Event) bool {
+		return ev.Type() == eventTypeUpdated
+	})
+	if expected := "dispatch limit exceeded: 3 events dispatched, 2 events discarded"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []event.Event{eventUpdated(2), eventUpdated(4), eventUpdated(12)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	*sub1 = nil
+	if err := pub.Dispatch(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(3)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
+func TestBufferDispatchWhereError(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	pub := event.NewBuffer(event.NewMapping().
+		On(eventTypeCreated, sub1).
+		On(eventTypeUpdated, event.Func(func(ctx context.Context, ev event.Event) error {
+			if ev == eventUpdated(1) {
+				return errors.New("handle error")
+			}
+			return sub1.Handle(ctx, ev)
+		})),
+	).MaxDispatch(1)
+	for _, ev := range []event.Event{eventUpdated(1), eventUpdated(2), eventCreated(3)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	err := pub.DispatchWhere(ctx, func(ev event.Event) bool {
+		return ev.Type() == eventTypeUpdated
+	})
+	if expected := "dispatch limit exceeded: 1 events dispatched, 1 events discarded"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := pub.DispatchWhere(ctx, func(ev event.Event) bool {
+		return ev.Type() == eventTypeDeleted
+	}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(sub1.Events()) != 0 {
+		t.Errorf("sub1 handled events: expected no events, got %v", sub1.Events())
+	}
+	if err := pub.DispatchAsync(ctx, 0); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(3)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
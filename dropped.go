@@ -0,0 +1,79 @@
+package event
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// DropReason is the reason why an event is dropped.
+type DropReason int
+
+const (
+	// DropDiscarded means the event is handled by Discard or a nil Func.
+	DropDiscarded DropReason = iota + 1
+	// DropUnhandled means the event has no registered subscribers in Mapping or
+	// Mux.
+	DropUnhandled
+	// DropUnrouted means the event matches no routes of Router.
+	DropUnrouted
+	// DropLimitExceeded means the event is discarded by Buffer on exceeding
+	// the limit of dispatching.
+	DropLimitExceeded
+	// DropExpired means the event is discarded by Correlator on the timeout,
+	// or the event is expired by Expirer.
+	DropExpired
+	// DropUnconverted means the event is not converted by Bridge.
+	DropUnconverted
+	// DropUnreplicated means the event is not replicated by Replicator to a
+	// region on the full queue or the errors.
+	DropUnreplicated
+	// DropUnmatched means the event does not match the matcher of OnMatch.
+	DropUnmatched
+	// DropFlagDisabled means the feature flag of WhenFlag is disabled.
+	DropFlagDisabled
+)
+
+// String implements fmt.Stringer for DropReason.
+func (r DropReason) String() string {
+	switch r {
+	case DropDiscarded:
+		return "discarded"
+	case DropUnhandled:
+		return "unhandled"
+	case DropUnrouted:
+		return "unrouted"
+	case DropLimitExceeded:
+		return "limit exceeded"
+	case DropExpired:
+		return "expired"
+	case DropUnconverted:
+		return "unconverted"
+	case DropUnreplicated:
+		return "unreplicated"
+	case DropUnmatched:
+		return "unmatched"
+	case DropFlagDisabled:
+		return "flag disabled"
+	default:
+		return "unknown"
+	}
+}
+
+// DroppedEventObserver is a function to observe the dropped events.
+type DroppedEventObserver func(context.Context, Event, DropReason)
+
+var droppedEventObserver atomic.Value
+
+// SetDroppedEventObserver sets the global observer of the events dropped by
+// this package, which is useful to count and log the drops instead of losing
+// the events invisibly. The observer is called synchronously, so it should
+// return quickly. Set nil to remove the observer.
+func SetDroppedEventObserver(f DroppedEventObserver) {
+	droppedEventObserver.Store(f)
+}
+
+func dropped(ctx context.Context, ev Event, reason DropReason) {
+	if f, _ := droppedEventObserver.Load().(DroppedEventObserver); f != nil {
+		f(ctx, ev, reason)
+	}
+}
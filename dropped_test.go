@@ -0,0 +1,72 @@
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestDroppedEventObserver(t *testing.T) {
+	ctx := context.Background()
+	drops := make(chan string, 10)
+	event.SetDroppedEventObserver(func(_ context.Context, ev event.Event, reason event.DropReason) {
+		drops <- fmt.Sprint(ev, " ", reason)
+	})
+	defer event.SetDroppedEventObserver(nil)
+	var pub *event.Buffer
+	pub = event.NewBuffer(event.NewMapping().
+		On(eventTypeCreated, event.Discard).
+		On(eventTypeUpdated, event.Func(func(ctx context.Context, ev event.Event) error {
+			return pub.Publish(ctx, ev.(eventUpdated)+1)
+		}))).MaxDispatch(3)
+	for _, ev := range []event.Event{eventCreated(1), eventOther(2), eventUpdated(3)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub.Dispatch(ctx); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := event.NewRouter().Publish(ctx, eventCreated(5)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	sub := event.NewCorrelator(event.Discard, func(ev event.Event) (interface{}, bool) {
+		return 0, true
+	}, []event.Type{eventTypeCreated, eventTypeUpdated}, nil, 10*time.Millisecond)
+	if err := sub.Handle(ctx, eventCreated(6)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	var got []string
+	for i := 0; i < 5; i++ {
+		got = append(got, <-drops)
+	}
+	expected := []string{
+		"1 discarded", "2 unhandled", "4 limit exceeded", "5 unrouted", "6 expired",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("dropped events: expected %v, got %v", expected, got)
+	}
+}
+
+func TestDropReasonString(t *testing.T) {
+	for reason, expected := range map[event.DropReason]string{
+		event.DropDiscarded:     "discarded",
+		event.DropUnhandled:     "unhandled",
+		event.DropUnrouted:      "unrouted",
+		event.DropLimitExceeded: "limit exceeded",
+		event.DropExpired:       "expired",
+		event.DropUnconverted:   "unconverted",
+		event.DropUnreplicated:  "unreplicated",
+		event.DropUnmatched:     "unmatched",
+		event.DropFlagDisabled:  "flag disabled",
+		event.DropReason(0):     "unknown",
+	} {
+		if got := reason.String(); got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Enricher is an event subscriber to augment events before handling them by
+// the subscriber, like looking up the user details for an event with a user
+// ID. The lookups of the same key are shared between concurrent events, and
+// the looked up values are cached when the TTL is configured.
+type Enricher struct {
+	subscriber Subscriber
+	key        func(Event) (interface{}, bool)
+	lookup     func(context.Context, interface{}) (interface{}, error)
+	enrich     func(Event, interface{}) Event
+	timeout    time.Duration
+	ttl        time.Duration
+	clock      Clock
+	mu         sync.Mutex
+	entries    map[interface{}]*enricherEntry
+	swept      time.Time
+}
+
+type enricherEntry struct {
+	done    chan struct{}
+	value   interface{}
+	err     error
+	expires time.Time
+	retry   bool
+}
+
+// NewEnricher creates a new enriching subscriber. The key function returns the
+// lookup key of an event, or false to handle the event as it is. The lookup
+// function looks up the value of the key, and the enrich function augments
+// the event with the looked up value.
+func NewEnricher(
+	sub Subscriber,
+	key func(Event) (interface{}, bool),
+	lookup func(context.Context, interface{}) (interface{}, error),
+	enrich func(Event, interface{}) Event,
+) *Enricher {
+	return &Enricher{
+		subscriber: sub, key: key, lookup: lookup, enrich: enrich,
+		entries: make(map[interface{}]*enricherEntry),
+	}
+}
+
+// Timeout sets the timeout of each lookup. This method returns the subscriber
+// to allow method chaining.
+func (sub *Enricher) Timeout(timeout time.Duration) *Enricher {
+	sub.timeout = timeout
+	return sub
+}
+
+// TTL sets the duration to cache the looked up values. The values are not
+// cached by default, and the lookup errors are never cached. The expired values
+// are swept on the lookups. This method returns the subscriber to allow method
+// chaining.
+func (sub *Enricher) TTL(ttl time.Duration) *Enricher {
+	sub.ttl = ttl
+	return sub
+}
+
+// Clock sets the clock to expire the cached values instead of the global clock.
+// This method returns the subscriber to allow method chaining.
+func (sub *Enricher) Clock(c Clock) *Enricher {
+	sub.clock = c
+	return sub
+}
+
+// Handle implements Subscriber for Enricher.
+func (sub *Enricher) Handle(ctx context.Context, ev Event) error {
+	key, ok := sub.key(ev)
+	if !ok {
+		return sub.subscriber.Handle(ctx, ev)
+	}
+	value, err := sub.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return sub.subscriber.Handle(ctx, sub.enrich(ev, value))
+}
+
+func (sub *Enricher) get(ctx context.Context, key interface{}) (interface{}, error) {
+	sub.mu.Lock()
+	entry, ok := sub.entries[key]
+	if ok {
+		select {
+		case <-entry.done:
+			if clockOr(sub.clock).Now().Before(entry.expires) {
+				sub.mu.Unlock()
+				return entry.value, nil
+			}
+			ok = false
+		default:
+		}
+	}
+	if !ok {
+		entry = &enricherEntry{done: make(chan struct{})}
+		sub.entries[key] = entry
+		sub.mu.Unlock()
+		sub.load(ctx, key, entry)
+		return entry.value, entry.err
+	}
+	sub.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-entry.done:
+		if entry.retry {
+			return sub.get(ctx, key)
+		}
+		return entry.value, entry.err
+	}
+}
+
+// load looks up the value of the entry. When the context of the caller is done
+// or the lookup panics, the waiters of the entry retry the lookup instead of
+// sharing the failure.
+func (sub *Enricher) load(ctx context.Context, key interface{}, entry *enricherEntry) {
+	entry.retry = true
+	defer func() {
+		now := clockOr(sub.clock).Now()
+		sub.mu.Lock()
+		defer sub.mu.Unlock()
+		if entry.retry || entry.err != nil || sub.ttl <= 0 {
+			delete(sub.entries, key)
+		}
+		sub.sweep(now)
+		close(entry.done)
+	}()
+	lookupCtx := ctx
+	if sub.timeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, sub.timeout)
+		defer cancel()
+	}
+	entry.value, entry.err = sub.lookup(lookupCtx, key)
+	entry.expires = clockOr(sub.clock).Now().Add(sub.ttl)
+	entry.retry = entry.err != nil && ctx.Err() != nil
+}
+
+// sweep deletes the expired entries at most once in the TTL.
+func (sub *Enricher) sweep(now time.Time) {
+	if sub.ttl <= 0 || now.Before(sub.swept.Add(sub.ttl)) {
+		return
+	}
+	sub.swept = now
+	for key, entry := range sub.entries {
+		select {
+		case <-entry.done:
+			if !now.Before(entry.expires) {
+				delete(sub.entries, key)
+			}
+		default:
+		}
+	}
+}
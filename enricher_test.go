@@ -0,0 +1,180 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type eventEnriched struct {
+	event.Event
+	Value interface{}
+}
+
+func newEnricher(sub event.Subscriber, lookup func(context.Context, interface{}) (interface{}, error)) *event.Enricher {
+	return event.NewEnricher(
+		sub,
+		func(ev event.Event) (interface{}, bool) {
+			if ev, ok := ev.(eventCreated); ok {
+				return int(ev) % 10, true
+			}
+			return nil, false
+		},
+		lookup,
+		func(ev event.Event, value interface{}) event.Event {
+			return eventEnriched{ev, value}
+		},
+	)
+}
+
+func TestEnricher(t *testing.T) {
+	ctx := context.Background()
+	var lookups int32
+	sub1 := &logged{}
+	sub := newEnricher(event.NewLimited(sub1, 1), func(_ context.Context, key interface{}) (interface{}, error) {
+		atomic.AddInt32(&lookups, 1)
+		time.Sleep(10 * time.Millisecond)
+		return key.(int) * 100, nil
+	}).TTL(time.Minute)
+	pub := event.NewMapping().
+		On(eventTypeCreated, event.Async{sub, sub, sub}).
+		On(eventTypeUpdated, sub)
+	evs := []event.Event{eventCreated(1), eventCreated(11), eventCreated(2), eventUpdated(3)}
+	for _, ev := range evs {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := int32(2); lookups != expected {
+		t.Errorf("expected %d lookups, got %d", expected, lookups)
+	}
+	expected := []event.Event{
+		eventEnriched{evs[0], 100}, eventEnriched{evs[0], 100}, eventEnriched{evs[0], 100},
+		eventEnriched{evs[1], 100}, eventEnriched{evs[1], 100}, eventEnriched{evs[1], 100},
+		eventEnriched{evs[2], 200}, eventEnriched{evs[2], 200}, eventEnriched{evs[2], 200},
+		evs[3],
+	}
+	if !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
+func TestEnricherNoCache(t *testing.T) {
+	ctx := context.Background()
+	var lookups int32
+	sub := newEnricher(&logged{}, func(_ context.Context, key interface{}) (interface{}, error) {
+		atomic.AddInt32(&lookups, 1)
+		return key, nil
+	})
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(1)} {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := int32(2); lookups != expected {
+		t.Errorf("expected %d lookups, got %d", expected, lookups)
+	}
+}
+
+func TestEnricherExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var lookups int32
+	sub := newEnricher(&logged{}, func(_ context.Context, key interface{}) (interface{}, error) {
+		atomic.AddInt32(&lookups, 1)
+		return key, nil
+	}).TTL(5 * time.Millisecond).Clock(clock)
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(1)} {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		clock.Advance(10 * time.Millisecond)
+	}
+	if expected := int32(2); lookups != expected {
+		t.Errorf("expected %d lookups, got %d", expected, lookups)
+	}
+}
+
+func TestEnricherError(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	sub := newEnricher(sub1, func(ctx context.Context, key interface{}) (interface{}, error) {
+		if key.(int) == 1 {
+			return nil, errors.New("lookup error")
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}).TTL(time.Minute).Timeout(10 * time.Millisecond)
+	if err, expected := sub.Handle(ctx, eventCreated(1)), "lookup error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err, expected := sub.Handle(ctx, eventCreated(2)), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err, expected := (event.Async{sub, sub}).Handle(ctx, eventCreated(2)), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if len(sub1.Events()) != 0 {
+		t.Errorf("sub1 handled events: expected no events, got %v", sub1.Events())
+	}
+}
+
+func TestEnricherCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	sub := newEnricher(&logged{}, func(context.Context, interface{}) (interface{}, error) {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		return 0, nil
+	})
+	errc := make(chan error)
+	go func() { errc <- sub.Handle(context.Background(), eventCreated(1)) }()
+	<-started
+	cancel()
+	if err, expected := sub.Handle(ctx, eventCreated(1)), context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestEnricherLookupCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	var lookups int32
+	sub1 := &logged{}
+	sub := newEnricher(sub1, func(ctx context.Context, key interface{}) (interface{}, error) {
+		if atomic.AddInt32(&lookups, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return key.(int) * 100, nil
+	}).TTL(time.Minute)
+	errc := make(chan error)
+	go func() { errc <- sub.Handle(ctx, eventCreated(1)) }()
+	<-started
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := sub.Handle(context.Background(), eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := <-errc, context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []event.Event{eventEnriched{eventCreated(1), 100}}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := int32(2); lookups != expected {
+		t.Errorf("expected %d lookups, got %d", expected, lookups)
+	}
+}
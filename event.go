@@ -2,7 +2,11 @@ package event
 
 import (
 	"context"
+	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Type is the event type. The underlying type is int to define nonduplicate
@@ -29,28 +33,38 @@ type Publisher interface {
 	Publish(context.Context, Event) error
 }
 
-// Discard is an event subscriber which ignores the event.
+// Discard is an event subscriber which ignores the event. This is also a
+// publisher, which is useful as a default publisher.
 var Discard Func
 
-// Func is an event subscriber built from a function.
+// Func is an event subscriber built from a function. This is also a publisher
+// which handles the published event by the function.
 type Func func(context.Context, Event) error
 
 // Handle implements Subscriber for Func.
 func (sub Func) Handle(ctx context.Context, ev Event) error {
 	if sub == nil {
+		dropped(ctx, ev, DropDiscarded)
 		return nil
 	}
 	return sub(ctx, ev)
 }
 
+// Publish implements Publisher for Func.
+func (sub Func) Publish(ctx context.Context, ev Event) error {
+	return sub.Handle(ctx, ev)
+}
+
 // Ordered is an event subscriber to handle in specified order of subscribers.
+// The subscribers are paired with the undo subscribers by WithCompensation.
 type Ordered []Subscriber
 
 // Handle implements Subscriber for Ordered.
 func (sub Ordered) Handle(ctx context.Context, ev Event) error {
 	var err error
+	ctx, cs := withCompensations(ctx, ev)
 	for _, sub := range sub {
-		if e := sub.Handle(ctx, ev); e != nil {
+		if e := cs.handled(ctx, ev, sub.Handle(ctx, ev)); e != nil {
 			err = e
 		}
 	}
@@ -69,17 +83,56 @@ func (sub Async) Handle(ctx context.Context, ev Event) error {
 	)
 	wg.Add(len(sub))
 	for _, sub := range sub {
-		go func(sub Subscriber) {
+		sub := sub
+		spawn(ctx, func() {
 			defer wg.Done()
 			if e := sub.Handle(ctx, ev); e != nil {
 				once.Do(func() { err = e })
 			}
-		}(sub)
+		})
 	}
 	wg.Wait()
 	return err
 }
 
+// AsyncN creates an event subscriber to handle asynchronously between the
+// subscribers as well as Async, but with at most n goroutines for each event.
+// Use Limited to limit the concurrency across the events.
+func AsyncN(n int, subs ...Subscriber) Func {
+	return func(ctx context.Context, ev Event) error {
+		var (
+			wg   sync.WaitGroup
+			once sync.Once
+			err  error
+			next int32 = -1
+		)
+		m := n
+		if m < 1 {
+			m = 1
+		}
+		if m > len(subs) {
+			m = len(subs)
+		}
+		wg.Add(m)
+		for i := 0; i < m; i++ {
+			spawn(ctx, func() {
+				defer wg.Done()
+				for {
+					j := int(atomic.AddInt32(&next, 1))
+					if j >= len(subs) {
+						return
+					}
+					if e := subs[j].Handle(ctx, ev); e != nil {
+						once.Do(func() { err = e })
+					}
+				}
+			})
+		}
+		wg.Wait()
+		return err
+	}
+}
+
 // Limited is an event subscriber to limit the max concurrency of subscriber.
 type Limited struct {
 	subscriber Subscriber
@@ -114,15 +167,7 @@ func NewMapping() Mapping {
 // publisher to allow method chaining. Note that this method is not goroutine
 // safe so register all the subscribers before starting event publishing.
 func (pub Mapping) On(typ Type, sub Subscriber) Mapping {
-	if s, ok := pub[typ]; ok {
-		if o, ok := s.(Ordered); ok {
-			pub[typ] = append(o, sub)
-		} else {
-			pub[typ] = Ordered{s, sub}
-		}
-	} else {
-		pub[typ] = sub
-	}
+	pub[typ] = appendSubscriber(pub[typ], sub)
 	return pub
 }
 
@@ -136,16 +181,277 @@ func (pub Mapping) Publish(ctx context.Context, ev Event) error {
 	if sub, ok := pub[ev.Type()]; ok {
 		return sub.Handle(ctx, ev)
 	}
+	dropped(ctx, ev, DropUnhandled)
+	return nil
+}
+
+// Strict is an event publisher to report an error on publishing an event
+// which has no registered subscribers. Convert a mapping by Strict(mapping).
+type Strict Mapping
+
+// Handle implements Subscriber for Strict.
+func (pub Strict) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Strict.
+func (pub Strict) Publish(ctx context.Context, ev Event) error {
+	if sub, ok := pub[ev.Type()]; ok {
+		return sub.Handle(ctx, ev)
+	}
+	return &UnhandledError{ev}
+}
+
+// UnhandledError is the error returned by Strict on publishing an event which
+// has no registered subscribers.
+type UnhandledError struct {
+	Event Event
+}
+
+// Error implements error for UnhandledError.
+func (err *UnhandledError) Error() string {
+	return "unhandled event type: " + strconv.Itoa(int(err.Event.Type()))
+}
+
+// Mux is an event publisher for mapping event types and subscribers, with the
+// options to wrap the subscribers and to observe the events. Use Mapping for
+// the plain mapping without the options.
+type Mux struct {
+	subscribers Mapping
+	namespaces  map[Namespace]Subscriber
+	fallback    Subscriber
+	middlewares []func(Subscriber) Subscriber
+	before      func(context.Context, Event)
+	after       func(context.Context, Event, error)
+	stats       *muxStats
+	profile     bool
+	labels      bool
+	readiers    []Subscriber
+	split       bool
+	failures    Publisher
+	clock       Clock
+	frozen      bool
+}
+
+// MuxOption is an option for NewMux.
+type MuxOption func(*Mux)
+
+// MuxStrict registers the default subscriber to report an UnhandledError on
+// the events which have no registered subscribers, as well as Strict.
+func MuxStrict() MuxOption {
+	return func(pub *Mux) { pub.Default(Func(unhandled)) }
+}
+
+func unhandled(_ context.Context, ev Event) error {
+	return &UnhandledError{ev}
+}
+
+// MuxDefault registers the subscriber to listen on the events which have no
+// registered subscribers, as well as Default.
+func MuxDefault(sub Subscriber) MuxOption {
+	return func(pub *Mux) { pub.Default(sub) }
+}
+
+// MuxMiddleware sets the middleware to wrap each subscriber on registering. The
+// middlewares are applied in order, so the first middleware is the outermost.
+// Specify this option before MuxDefault to wrap the default subscribers as
+// well.
+func MuxMiddleware(middlewares ...func(Subscriber) Subscriber) MuxOption {
+	return func(pub *Mux) { pub.middlewares = append(pub.middlewares, middlewares...) }
+}
+
+// MuxHooks sets the functions called before and after publishing each event.
+// Either of the functions can be nil.
+func MuxHooks(before func(context.Context, Event), after func(context.Context, Event, error)) MuxOption {
+	return func(pub *Mux) { pub.before, pub.after = before, after }
+}
+
+// MuxStats makes the mux collect the statistics of the events per type, which
+// are available by Stats.
+func MuxStats() MuxOption {
+	return func(pub *Mux) { pub.stats = &muxStats{types: make(map[Type]*typeStats)} }
+}
+
+// MuxProfile makes the mux account the count and the wall time of each
+// registered subscriber, which are available by Stats as well as MuxStats. The
+// subscriber is named by the Name() string method, the function name of Func,
+// or the type name.
+func MuxProfile() MuxOption {
+	return func(pub *Mux) { pub.profile = true }
+}
+
+// MuxLabels makes the mux set the pprof labels of the event type and the
+// subscriber during handling, so that the CPU and goroutine profiles attribute
+// the cost to the subscribers. The event type is labeled by the name registered
+// by MustRegisterType, or the number. The subscriber is named as well as
+// MuxProfile.
+func MuxLabels() MuxOption {
+	return func(pub *Mux) { pub.labels = true }
+}
+
+// MuxSplitDeadline makes the mux split the remaining deadline of the context
+// equally across the subscribers of the event, by Ordered.SplitDeadline.
+func MuxSplitDeadline() MuxOption {
+	return func(pub *Mux) { pub.split = true }
+}
+
+// MuxFailures makes the mux wrap each registered subscriber by Monitor, to
+// publish a SubscriberFailed meta event to the publisher when the subscriber
+// fails. The subscribers are monitored outside the middlewares, so the
+// failures are reported after the retries of the middlewares. The subscriber
+// is named as well as MuxProfile.
+func MuxFailures(meta Publisher) MuxOption {
+	return func(pub *Mux) { pub.failures = meta }
+}
+
+// MuxClock sets the clock to measure the latencies of the statistics and the
+// profiles, and the times of the snapshots, instead of the global clock.
+func MuxClock(c Clock) MuxOption {
+	return func(pub *Mux) { pub.clock = c }
+}
+
+// NewMux creates a new event mux publisher.
+func NewMux(opts ...MuxOption) *Mux {
+	pub := &Mux{subscribers: NewMapping()}
+	for _, opt := range opts {
+		opt(pub)
+	}
+	if pub.profile && pub.stats == nil {
+		MuxStats()(pub)
+	}
+	return pub
+}
+
+// On registers the subscriber to listen on the event. This method returns the
+// publisher to allow method chaining. Note that this method is not goroutine
+// safe so register all the subscribers before starting event publishing.
+func (pub *Mux) On(typ Type, sub Subscriber) *Mux {
+	if pub.subscribers == nil {
+		pub.subscribers = NewMapping()
+	}
+	pub.subscribers[typ] = appendSubscriber(pub.subscribers[typ], pub.wrap(sub))
+	return pub
+}
+
+// Default registers the subscriber to listen on the events which have no
+// registered subscribers. Use MuxStrict to report the unhandled events.
+// This method is not goroutine safe as well as On.
+func (pub *Mux) Default(sub Subscriber) *Mux {
+	pub.fallback = appendSubscriber(pub.fallback, pub.wrap(sub))
+	return pub
+}
+
+func (pub *Mux) wrap(sub Subscriber) Subscriber {
+	if pub.frozen {
+		panic("event: subscriber registered on a frozen mux")
+	}
+	if _, ok := sub.(Readier); ok {
+		pub.readiers = append(pub.readiers, sub)
+	}
+	var name string
+	if pub.failures != nil {
+		name = subscriberName(sub)
+	}
+	if pub.labels {
+		sub = labeled(sub)
+	}
+	if pub.profile {
+		sub = pub.stats.profile(sub, pub.clock)
+	}
+	for i := len(pub.middlewares) - 1; i >= 0; i-- {
+		sub = pub.middlewares[i](sub)
+	}
+	if pub.failures != nil {
+		sub = Monitor(name, sub, pub.failures)
+	}
+	return sub
+}
+
+func appendSubscriber(s, sub Subscriber) Subscriber {
+	if s == nil {
+		return sub
+	}
+	if o, ok := s.(Ordered); ok {
+		return append(o, sub)
+	}
+	return Ordered{s, sub}
+}
+
+// Handle implements Subscriber for Mux.
+func (pub *Mux) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// observe publishes the event with the hooks and the statistics. Publish
+// verifies the producer before this in the builds with the eventdebug tag.
+func (pub *Mux) observe(ctx context.Context, ev Event) error {
+	if pub.before != nil {
+		pub.before(ctx, ev)
+	}
+	if pub.after == nil && pub.stats == nil {
+		return pub.publish(ctx, ev)
+	}
+	clock := clockOr(pub.clock)
+	start := clock.Now()
+	err := pub.publish(ctx, ev)
+	if pub.stats != nil {
+		pub.stats.record(ev.Type(), clock.Now().Sub(start), err)
+	}
+	if pub.after != nil {
+		pub.after(ctx, ev, err)
+	}
+	return err
+}
+
+func (pub *Mux) publish(ctx context.Context, ev Event) error {
+	sub, ok := pub.subscribers[ev.Type()]
+	if nsub, found := pub.namespaces[ev.Type().Namespace()]; found {
+		if ok {
+			sub = Ordered{sub, nsub}
+		} else {
+			sub, ok = nsub, true
+		}
+	}
+	if ok {
+		if o, ok := sub.(Ordered); ok && pub.split {
+			return o.SplitDeadline().Handle(ctx, ev)
+		}
+		return sub.Handle(ctx, ev)
+	}
+	if pub.fallback != nil {
+		return pub.fallback.Handle(ctx, ev)
+	}
+	dropped(ctx, ev, DropUnhandled)
 	return nil
 }
 
 // Buffer is an event publisher for delaying event dispatching. This is useful
 // for buffering all the events during a transaction and dispatching them only
 // after the transaction succeeded. This publisher is not goroutine safe, so
-// create a new buffered publisher each request.
+// create a new buffered publisher each request. The subscribers can publish
+// events to the buffer during dispatching, even with DispatchAsync.
 type Buffer struct {
-	publisher Publisher
-	events    []Event
+	publisher   Publisher
+	mu          sync.Mutex
+	events      []bufferedEvent
+	maxDispatch int
+	orderKey    func(Event) interface{}
+	coalescer   Coalescer
+	inline      map[Type]Subscriber
+	yieldEvery  int
+	yieldPause  time.Duration
+	timeout     time.Duration
+	clock       Clock
+}
+
+// Coalescer is a function to rewrite the buffered events before dispatching,
+// like merging the events into one event, or canceling out the events, to
+// reduce the work of the subscribers after chatty transactions.
+type Coalescer func([]Event) []Event
+
+type bufferedEvent struct {
+	event Event
+	trace *Trace
 }
 
 // NewBuffer creates a new event buffered publisher.
@@ -153,28 +459,216 @@ func NewBuffer(pub Publisher) *Buffer {
 	return &Buffer{publisher: pub}
 }
 
+// MaxDispatch sets the max number of events to dispatch at once, including the
+// events published by the subscribers during dispatching, to protect against
+// the infinite event generation. This method returns the publisher to allow
+// method chaining.
+func (pub *Buffer) MaxDispatch(n int) *Buffer {
+	pub.maxDispatch = n
+	return pub
+}
+
+// OrderBy sets the function to return the key of the event, to dispatch the
+// events with the same key in order by DispatchAsync. This method returns the
+// publisher to allow method chaining.
+func (pub *Buffer) OrderBy(key func(Event) interface{}) *Buffer {
+	pub.orderKey = key
+	return pub
+}
+
+// Coalesce sets the coalescer to rewrite the buffered events on dispatching.
+// The events are coalesced at the start of each dispatching. The traces of the
+// events are kept only when all the buffered events share the same trace.
+// This method returns the publisher to allow method chaining.
+func (pub *Buffer) Coalesce(c Coalescer) *Buffer {
+	pub.coalescer = c
+	return pub
+}
+
+// Inline registers the subscriber to handle the events of the type on
+// publishing, in addition to dispatching later. This is useful for the
+// subscribers which must see the effects before the transaction ends, like
+// updating an in-memory cache for the subsequent queries. The error of the
+// subscriber is returned from Publish, and the event is not buffered. This
+// method returns the publisher to allow method chaining.
+func (pub *Buffer) Inline(typ Type, sub Subscriber) *Buffer {
+	if pub.inline == nil {
+		pub.inline = make(map[Type]Subscriber)
+	}
+	pub.inline[typ] = appendSubscriber(pub.inline[typ], sub)
+	return pub
+}
+
+// Yield makes Dispatch yield the processor every n events, sleeping for the
+// pause if positive, so that a huge dispatching does not monopolize the
+// scheduler. The context is checked on yielding, and the rest of the events
+// are kept buffered when the context is done. This method returns the
+// publisher to allow method chaining.
+func (pub *Buffer) Yield(n int, pause time.Duration) *Buffer {
+	pub.yieldEvery, pub.yieldPause = n, pause
+	return pub
+}
+
+// Clock sets the clock of the pause on yielding instead of the global clock.
+// This method returns the publisher to allow method chaining.
+func (pub *Buffer) Clock(c Clock) *Buffer {
+	pub.clock = c
+	return pub
+}
+
+// EventTimeout sets the timeout of dispatching each event. The events are
+// dispatched with a fresh context of the timeout, which keeps the values of
+// the context of dispatching but not the deadline and the cancellation, so
+// that a slow event does not make the rest of the events fail on the deadline
+// of the dispatching. This method returns the publisher to allow method
+// chaining.
+func (pub *Buffer) EventTimeout(d time.Duration) *Buffer {
+	pub.timeout = d
+	return pub
+}
+
 // Handle implements Subscriber for Buffer.
 func (pub *Buffer) Handle(ctx context.Context, ev Event) error {
 	return pub.Publish(ctx, ev)
 }
 
 // Publish implements Publisher for Buffer.
-func (pub *Buffer) Publish(_ context.Context, ev Event) error {
-	pub.events = append(pub.events, ev)
+func (pub *Buffer) Publish(ctx context.Context, ev Event) error {
+	if sub, ok := pub.inline[ev.Type()]; ok {
+		if err := sub.Handle(ctx, ev); err != nil {
+			return err
+		}
+	}
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	pub.events = append(pub.events, bufferedEvent{ev, TraceFromContext(ctx)})
 	return nil
 }
 
-// Dispatch all the buffered events.
+// Dispatch all the buffered events. When the number of the dispatched events
+// exceeds the limit set by MaxDispatch, the rest of the events are discarded
+// and DispatchLimitError is returned.
 func (pub *Buffer) Dispatch(ctx context.Context) error {
+	pub.coalesce()
 	var (
-		ev  Event
+		n   int
 		err error
 	)
-	for len(pub.events) != 0 {
-		ev, pub.events = pub.events[0], pub.events[1:]
-		if e := pub.publisher.Publish(ctx, ev); e != nil {
+	for {
+		if pub.yieldEvery > 0 && n > 0 && n%pub.yieldEvery == 0 {
+			if e := yield(ctx, clockOr(pub.clock), pub.yieldPause); e != nil {
+				return e
+			}
+		}
+		evs, e := pub.take(ctx, &n, 1)
+		if e != nil {
+			err = e
+		}
+		if len(evs) == 0 {
+			return err
+		}
+		if e := pub.dispatch(ctx, evs[0]); e != nil {
 			err = e
 		}
 	}
-	return err
+}
+
+func (pub *Buffer) coalesce() {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	if pub.coalescer == nil || len(pub.events) == 0 {
+		return
+	}
+	evs := make([]Event, len(pub.events))
+	trace := pub.events[0].trace
+	for i, ev := range pub.events {
+		evs[i] = ev.event
+		if ev.trace != trace {
+			trace = nil
+		}
+	}
+	evs = pub.coalescer(evs)
+	pub.events = make([]bufferedEvent, len(evs))
+	for i, ev := range evs {
+		pub.events[i] = bufferedEvent{ev, trace}
+	}
+}
+
+// take at most max buffered events, counting the number of the taken events
+// to report DispatchLimitError.
+func (pub *Buffer) take(ctx context.Context, n *int, max int) ([]bufferedEvent, error) {
+	pub.mu.Lock()
+	if max <= 0 || max > len(pub.events) {
+		max = len(pub.events)
+	}
+	if pub.maxDispatch == 0 || *n+max <= pub.maxDispatch {
+		evs := pub.events[:max]
+		*n, pub.events = *n+max, pub.events[max:]
+		pub.mu.Unlock()
+		return evs, nil
+	}
+	max = pub.maxDispatch - *n
+	evs, discarded := pub.events[:max], pub.events[max:]
+	*n, pub.events = *n+max, nil
+	pub.mu.Unlock()
+	for _, ev := range discarded {
+		dropped(ctx, ev.event, DropLimitExceeded)
+	}
+	return evs, &DispatchLimitError{pub.maxDispatch, len(discarded)}
+}
+
+// dispatch the event to the publisher, with the timeout set by EventTimeout.
+func (pub *Buffer) dispatch(ctx context.Context, ev bufferedEvent) error {
+	if pub.timeout <= 0 {
+		return ev.publish(ctx, pub.publisher)
+	}
+	ctx, cancel := context.WithTimeout(detachedContext{context.Background(), ctx}, pub.timeout)
+	defer cancel()
+	return ev.publish(ctx, pub.publisher)
+}
+
+// detachedContext is the context with the values of the parent context, but
+// without the deadline and the cancellation.
+type detachedContext struct {
+	context.Context
+	parent context.Context
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} { return ctx.parent.Value(key) }
+
+func (ev bufferedEvent) publish(ctx context.Context, pub Publisher) error {
+	if ev.trace != nil {
+		ctx = context.WithValue(ctx, traceKey{}, ev.trace)
+	}
+	return pub.Publish(ctx, ev.event)
+}
+
+// DispatchLimitError is the error returned by Buffer.Dispatch when the number
+// of the dispatched events exceeds the limit.
+type DispatchLimitError struct {
+	Limit     int
+	Discarded int
+}
+
+// Error implements error for DispatchLimitError.
+func (err *DispatchLimitError) Error() string {
+	return "dispatch limit exceeded: " + strconv.Itoa(err.Limit) +
+		" events dispatched, " + strconv.Itoa(err.Discarded) + " events discarded"
+}
+
+// yield the processor, or sleep for the pause on the clock if positive, and
+// returns the error of the context.
+func yield(ctx context.Context, clock Clock, pause time.Duration) error {
+	if pause <= 0 {
+		runtime.Gosched()
+		return ctx.Err()
+	}
+	timer := clock.NewTimer(pause)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
 }
@@ -3,12 +3,14 @@ package event_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
 )
 
 const (
@@ -156,6 +158,81 @@ func TestMappingError(t *testing.T) {
 	}
 }
 
+func TestMuxDefault(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2, sub3 := &logged{}, &logged{}, &logged{}
+	pub := event.NewMux().
+		On(eventTypeCreated, sub1).
+		Default(sub2).
+		Default(sub3)
+	evs := []event.Event{
+		eventCreated(1), eventUpdated(2), eventDeleted(3), eventOther(4),
+	}
+	for _, ev := range evs {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := evs[:1]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := evs[1:]; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	if expected := evs[1:]; !reflect.DeepEqual(sub3.Events(), expected) {
+		t.Errorf("sub3 handled events: expected %v, got %v", expected, sub3.Events())
+	}
+}
+
+func TestMuxZero(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	var pub event.Mux
+	pub.On(eventTypeCreated, sub1)
+	evs := []event.Event{eventCreated(1), eventUpdated(2)}
+	for _, ev := range evs {
+		if err := pub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := evs[:1]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
+func TestStrict(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	pub := event.Strict(event.NewMapping().
+		On(eventTypeCreated, sub1).
+		On(eventTypeUpdated, &suberr{}))
+	evs := []event.Event{eventCreated(1), eventUpdated(2), eventOther(3)}
+	for _, ev := range evs {
+		err := pub.Handle(ctx, ev)
+		switch ev.Type() {
+		case eventTypeCreated:
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+		case eventTypeUpdated:
+			if expected := "handle error"; err == nil || err.Error() != expected {
+				t.Fatalf("expected %v, got %v", expected, err)
+			}
+		default:
+			var e *event.UnhandledError
+			if !errors.As(err, &e) || e.Event != ev {
+				t.Fatalf("expected an unhandled error, got %v", err)
+			}
+			if expected := "unhandled event type: 3"; err.Error() != expected {
+				t.Fatalf("expected %v, got %v", expected, err)
+			}
+		}
+	}
+	if expected := evs[:1]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
 func TestDiscard(t *testing.T) {
 	ctx := context.Background()
 	pub := event.NewMapping().
@@ -274,6 +351,49 @@ func TestAsyncError(t *testing.T) {
 	}
 }
 
+func TestAsyncN(t *testing.T) {
+	ctx := context.Background()
+	var handled, running, max int32
+	sub := event.Func(func(context.Context, event.Event) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	pub := event.NewMapping().
+		On(eventTypeCreated, event.AsyncN(3, sub, sub, sub, sub, sub, sub, sub, sub, sub, sub)).
+		On(eventTypeUpdated, event.AsyncN(5, sub, &suberr{}, sub)).
+		On(eventTypeDeleted, event.AsyncN(0, sub, sub))
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := int32(10); handled != expected {
+		t.Errorf("expected %d handled events, got %d", expected, handled)
+	}
+	if expected := int32(3); max != expected {
+		t.Errorf("expected max concurrency %d, got %d", expected, max)
+	}
+	if err, expected := pub.Publish(ctx, eventUpdated(2)), "handle error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := int32(12); handled != expected {
+		t.Errorf("expected %d handled events, got %d", expected, handled)
+	}
+	if err := pub.Publish(ctx, eventDeleted(3)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := int32(14); handled != expected {
+		t.Errorf("expected %d handled events, got %d", expected, handled)
+	}
+}
+
 func TestLimited(t *testing.T) {
 	ctx := context.Background()
 	const max = 3
@@ -354,3 +474,276 @@ func TestBuffer(t *testing.T) {
 		t.Fatalf("expected %v, got %v", expected, err)
 	}
 }
+
+func TestBufferMaxDispatch(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	var pub *event.Buffer
+	pub = event.NewBuffer(
+		event.NewMapping().
+			On(eventTypeCreated, sub1).
+			On(eventTypeCreated, event.Func(func(ctx context.Context, ev event.Event) error {
+				return pub.Publish(ctx, ev.(eventCreated)+1)
+			})),
+	).MaxDispatch(3)
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(10)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	err := pub.Dispatch(ctx)
+	if expected := "dispatch limit exceeded: 3 events dispatched, 2 events discarded"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(10), eventCreated(2)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if err := pub.Dispatch(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestMuxOptions(t *testing.T) {
+	ctx := context.Background()
+	var logs []string
+	sub1, sub2 := &logged{}, &logged{}
+	middleware := func(name string) func(event.Subscriber) event.Subscriber {
+		return func(sub event.Subscriber) event.Subscriber {
+			return event.Func(func(ctx context.Context, ev event.Event) error {
+				logs = append(logs, fmt.Sprint(name, " ", ev))
+				return sub.Handle(ctx, ev)
+			})
+		}
+	}
+	pub := event.NewMux(
+		event.MuxMiddleware(middleware("m1"), middleware("m2")),
+		event.MuxDefault(sub2),
+		event.MuxStrict(),
+		event.MuxHooks(
+			func(_ context.Context, ev event.Event) {
+				logs = append(logs, fmt.Sprint("before ", ev))
+			},
+			func(_ context.Context, ev event.Event, err error) {
+				logs = append(logs, fmt.Sprint("after ", ev, " ", err))
+			},
+		),
+	).On(eventTypeCreated, sub1)
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := pub.Publish(ctx, eventUpdated(2)), "unhandled event type: 1"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := []event.Event{eventUpdated(2)}; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	expected := []string{
+		"before 1", "m1 1", "m2 1", "after 1 <nil>",
+		"before 2", "m1 2", "m2 2", "m1 2", "m2 2", "after 2 unhandled event type: 1",
+	}
+	if !reflect.DeepEqual(logs, expected) {
+		t.Errorf("logs: expected %v, got %v", expected, logs)
+	}
+}
+
+func TestBufferCoalesce(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	pub := event.NewBuffer(event.Func(sub1.Handle)).Coalesce(func(evs []event.Event) []event.Event {
+		var created eventCreated
+		var coalesced []event.Event
+		for _, ev := range evs {
+			switch ev := ev.(type) {
+			case eventCreated:
+				created += ev
+			case eventDeleted:
+				if len(coalesced) > 0 && coalesced[len(coalesced)-1] == eventUpdated(ev) {
+					coalesced = coalesced[:len(coalesced)-1]
+					continue
+				}
+				coalesced = append(coalesced, ev)
+			default:
+				coalesced = append(coalesced, ev)
+			}
+		}
+		return append([]event.Event{created}, coalesced...)
+	})
+	for _, ev := range []event.Event{
+		eventCreated(1), eventUpdated(2), eventCreated(3), eventDeleted(2), eventDeleted(4),
+	} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub.Dispatch(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(4), eventDeleted(4)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if err := pub.Dispatch(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := 2; len(sub1.Events()) != expected {
+		t.Errorf("sub1 handled events: expected %d events, got %v", expected, sub1.Events())
+	}
+}
+
+func TestBufferCoalesceTrace(t *testing.T) {
+	ctx := context.Background()
+	var traces []*event.Trace
+	buf := event.NewBuffer(event.Func(func(ctx context.Context, _ event.Event) error {
+		traces = append(traces, event.TraceFromContext(ctx))
+		return nil
+	})).Coalesce(func(evs []event.Event) []event.Event {
+		return evs[:1]
+	})
+	pub := event.NewTracer(event.Func(func(ctx context.Context, ev event.Event) error {
+		for i := 0; i < int(ev.(eventCreated)); i++ {
+			if err := buf.Publish(ctx, ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	for _, evs := range [][]event.Event{{eventCreated(2)}, {eventCreated(1), eventCreated(1)}} {
+		for _, ev := range evs {
+			if err := pub.Publish(ctx, ev); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+		}
+		if err := buf.Dispatch(ctx); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if len(traces) != 2 || traces[0] == nil || traces[1] != nil {
+		t.Errorf("unexpected traces: %v", traces)
+	}
+}
+
+func TestBufferInline(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2 := &logged{}, &logged{}
+	pub := event.NewBuffer(event.Func(sub1.Handle)).
+		Inline(eventTypeCreated, sub2).
+		Inline(eventTypeDeleted, suberr{})
+	for _, ev := range []event.Event{eventCreated(1), eventUpdated(2), eventCreated(3)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub.Publish(ctx, eventDeleted(4)); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(3)}; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	if len(sub1.Events()) != 0 {
+		t.Errorf("sub1 handled events: expected no events, got %v", sub1.Events())
+	}
+	if err := pub.Dispatch(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1), eventUpdated(2), eventCreated(3)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
+func TestBufferYield(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub1 := &logged{}
+	pub := event.NewBuffer(event.Func(func(ctx context.Context, ev event.Event) error {
+		if ev == eventCreated(5) {
+			cancel()
+		}
+		return sub1.Handle(ctx, ev)
+	})).Yield(3, time.Millisecond)
+	for i := 0; i < 10; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	start := time.Now()
+	if err, expected := pub.Dispatch(ctx), context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if elapsed, expected := time.Since(start), time.Millisecond; elapsed < expected {
+		t.Errorf("expected yielding to take at least %v, got %v", expected, elapsed)
+	}
+	if got, expected := len(sub1.Events()), 6; got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+	sub1 = &logged{}
+	if err := pub.Dispatch(context.Background()); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := []event.Event{eventCreated(6), eventCreated(7), eventCreated(8), eventCreated(9)}
+	if !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
+func TestBufferYieldClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	handled := make(chan event.Event, 3)
+	pub := event.NewBuffer(event.Func(func(_ context.Context, ev event.Event) error {
+		handled <- ev
+		return nil
+	})).Yield(2, time.Minute).Clock(clock)
+	for i := 0; i < 3; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	errc := make(chan error)
+	go func() { errc <- pub.Dispatch(ctx) }()
+	clock.WaitTimers(1)
+	if got, expected := len(handled), 2; got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+	clock.Advance(time.Minute)
+	if err := <-errc; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := len(handled), 3; got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+}
+
+func TestBufferEventTimeout(t *testing.T) {
+	type key struct{}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "value"), time.Millisecond)
+	defer cancel()
+	sub1 := &logged{}
+	pub := event.NewBuffer(event.Func(func(ctx context.Context, ev event.Event) error {
+		if v := ctx.Value(key{}); v != "value" {
+			t.Errorf("expected the value of the context, got %v", v)
+		}
+		if ev == eventCreated(2) {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return sub1.Handle(ctx, ev)
+	})).EventTimeout(10 * time.Millisecond)
+	for i := 1; i <= 3; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	<-ctx.Done()
+	if err, expected := pub.Dispatch(ctx), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	expected := []event.Event{eventCreated(1), eventCreated(3)}
+	if !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
@@ -0,0 +1,210 @@
+// Package eventarchive provides a subscriber to archive the events into the
+// time-partitioned objects, for the long-term retention of the events in an
+// object storage and the offline analytics.
+package eventarchive
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+// Store is the interface of an object storage, like Amazon S3 or Google Cloud
+// Storage. The keys are slash-separated paths.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// Dir is a store to write the objects as the files under the directory.
+type Dir string
+
+// Put implements Store for Dir. The file is written atomically.
+func (dir Dir) Put(_ context.Context, key string, data []byte) error {
+	path := filepath.Join(string(dir), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	return err
+}
+
+// Archiver is an event subscriber to batch the encoded events into the objects
+// partitioned by the time of handling. An object is put when the batch reaches
+// the max number of events, the flush interval elapses since the first event of
+// the batch, or the partition changes. The keys of the objects consist of the
+// partition, the time of flushing, the instance and the sequence number of the
+// archiver, so that the objects flushed at the same time are all kept.
+type Archiver struct {
+	store     Store
+	format    Format
+	layout    string
+	maxEvents int
+	interval  time.Duration
+	onError   func(error)
+	sequenced bool
+	instance  string
+	clock     event.Clock
+	mu        sync.Mutex
+	seq       uint64
+	partition string
+	writer    Writer
+	data      bytes.Buffer
+	count     int
+	timer     event.Timer
+}
+
+// Option is an option for New.
+type Option func(*Archiver)
+
+// Partition sets the layout of time.Format to build the partition of the keys.
+// The default layout is "2006/01/02/15", which partitions the objects hourly.
+// The times are formatted in UTC.
+func Partition(layout string) Option {
+	return func(a *Archiver) { a.layout = layout }
+}
+
+// MaxEvents sets the max number of the events in an object. The default is
+// 1000.
+func MaxEvents(n int) Option {
+	return func(a *Archiver) { a.maxEvents = n }
+}
+
+// FlushInterval sets the max duration to keep the events in memory. The default
+// interval is one minute.
+func FlushInterval(d time.Duration) Option {
+	return func(a *Archiver) { a.interval = d }
+}
+
+// ErrorHandler sets the function to report the errors on flushing the events
+// after the interval. The errors are ignored by default.
+func ErrorHandler(f func(error)) Option {
+	return func(a *Archiver) { a.onError = f }
+}
+
+// Instance sets the identifier of the archiver in the keys, so that the
+// archivers of the processes sharing the store do not overwrite the objects of
+// each other. The default identifier is random.
+func Instance(id string) Option {
+	return func(a *Archiver) { a.instance = id }
+}
+
+// WithClock sets the clock of the partitions, the keys and the flush interval
+// instead of the global clock set by event.SetClock.
+func WithClock(c event.Clock) Option {
+	return func(a *Archiver) { a.clock = c }
+}
+
+// New creates a new archiver to put the objects of the format to the store.
+func New(store Store, format Format, opts ...Option) *Archiver {
+	a := &Archiver{
+		store: store, format: format, layout: "2006/01/02/15",
+		maxEvents: 1000, interval: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.instance == "" {
+		var b [4]byte
+		_, _ = rand.Read(b[:])
+		a.instance = hex.EncodeToString(b[:])
+	}
+	return a
+}
+
+// Handle implements event.Subscriber for Archiver.
+func (sub *Archiver) Handle(ctx context.Context, ev event.Event) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	partition := clockOr(sub.clock).Now().UTC().Format(sub.layout)
+	if sub.count > 0 && (partition != sub.partition || sub.writer == nil) {
+		if err := sub.flush(ctx); err != nil {
+			return err
+		}
+	}
+	if sub.writer == nil {
+		w, err := sub.format.NewWriter(&sub.data)
+		if err != nil {
+			return err
+		}
+		sub.partition, sub.writer = partition, w
+	}
+	if seq, ok := event.SequenceFromContext(ctx, ev); ok && sub.sequenced {
+		ev = &Sequenced{seq, ev}
+	}
+	if err := sub.writer.Write(ev); err != nil {
+		return err
+	}
+	if sub.count++; sub.count >= sub.maxEvents {
+		return sub.flush(ctx)
+	}
+	if sub.timer == nil {
+		sub.timer = clockOr(sub.clock).AfterFunc(sub.interval, func() {
+			if err := sub.Flush(context.Background()); err != nil && sub.onError != nil {
+				sub.onError(err)
+			}
+		})
+	}
+	return nil
+}
+
+// Flush puts the object of the batched events immediately, which is useful on
+// shutdown. The events are kept in the batch when putting fails.
+func (sub *Archiver) Flush(ctx context.Context) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.flush(ctx)
+}
+
+func (sub *Archiver) flush(ctx context.Context) error {
+	if sub.timer != nil {
+		sub.timer.Stop()
+		sub.timer = nil
+	}
+	if sub.count == 0 {
+		return nil
+	}
+	if sub.writer != nil {
+		err := sub.writer.Close()
+		if sub.writer = nil; err != nil {
+			sub.data.Reset()
+			sub.count = 0
+			return err
+		}
+	}
+	sub.seq++
+	key := sub.partition + "/" + strconv.FormatInt(clockOr(sub.clock).Now().UnixNano(), 10) +
+		"-" + sub.instance + "-" + strconv.FormatUint(sub.seq, 10) + sub.format.Extension
+	if err := sub.store.Put(ctx, key, sub.data.Bytes()); err != nil {
+		return err
+	}
+	sub.data.Reset()
+	sub.count = 0
+	return nil
+}
+
+// clockOr returns the clock, or the global clock if nil.
+func clockOr(c event.Clock) event.Clock {
+	if c != nil {
+		return c
+	}
+	return event.CurrentClock()
+}
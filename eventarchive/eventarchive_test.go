@@ -0,0 +1,336 @@
+package eventarchive_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventarchive"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+const eventTypeCreated event.Type = iota
+
+type eventCreated struct {
+	ID int `json:"id"`
+}
+
+func (eventCreated) Type() event.Type {
+	return eventTypeCreated
+}
+
+var ndjson = eventarchive.NDJSON(
+	func(ev event.Event) ([]byte, error) {
+		return json.Marshal(ev)
+	},
+	func(bs []byte) (event.Event, error) {
+		var ev eventCreated
+		err := json.Unmarshal(bs, &ev)
+		return ev, err
+	},
+)
+
+type objects struct {
+	mu   sync.Mutex
+	keys []string
+	data []string
+	err  error
+}
+
+func (s *objects) Put(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.keys = append(s.keys, key)
+	s.data = append(s.data, string(data))
+	return nil
+}
+
+func TestArchiver(t *testing.T) {
+	ctx := context.Background()
+	store := &objects{}
+	sub := eventarchive.New(store, ndjson, eventarchive.MaxEvents(2), eventarchive.Partition("2006-01-02"))
+	for i := 1; i <= 3; i++ {
+		if err := sub.Handle(ctx, eventCreated{i}); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	store.err = errors.New("put error")
+	if err, expected := sub.Flush(ctx), "put error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	store.err = nil
+	if err := sub.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []string{"{\"id\":1}\n{\"id\":2}\n", "{\"id\":3}\n"}; !reflect.DeepEqual(store.data, expected) {
+		t.Errorf("expected %q, got %q", expected, store.data)
+	}
+	for _, key := range store.keys {
+		if prefix := time.Now().UTC().Format("2006-01-02") + "/"; !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, ".ndjson") {
+			t.Errorf("unexpected key: %s", key)
+		}
+	}
+}
+
+func TestArchiverInterval(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := eventtest.NewClock(now)
+	sub := eventarchive.New(eventarchive.Dir(dir), ndjson,
+		eventarchive.FlushInterval(time.Minute),
+		eventarchive.ErrorHandler(func(err error) { t.Errorf("got error: %v", err) }),
+		eventarchive.WithClock(clock),
+		eventarchive.Instance("host1"),
+	)
+	if err := sub.Handle(ctx, eventCreated{1}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*", "*", "*", "*.ndjson"))
+	if expected := []string{
+		filepath.Join(dir, "2024", "01", "01", "00", strconv.FormatInt(now.Add(time.Minute).UnixNano(), 10)+"-host1-1.ndjson"),
+	}; !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+	bs, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := string(bs), "{\"id\":1}\n"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestArchiverKeys(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	for i := 0; i < 2; i++ {
+		sub := eventarchive.New(eventarchive.Dir(dir), ndjson, eventarchive.WithClock(clock))
+		for j := 1; j <= 3; j++ {
+			if err := sub.Handle(ctx, eventCreated{i*3 + j}); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if err := sub.Flush(ctx); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*", "*", "*", "*.ndjson"))
+	if len(files) != 6 {
+		t.Fatalf("expected 6 objects, got %v", files)
+	}
+	var evs []event.Event
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		es, err := eventarchive.ReadAll(f, ndjson)
+		f.Close()
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		evs = append(evs, es...)
+	}
+	if len(evs) != 6 {
+		t.Errorf("expected 6 events, got %v", evs)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	evs, err := eventarchive.ReadAll(strings.NewReader("{\"id\":1}\n\n{\"id\":2}\n"), ndjson)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated{1}, eventCreated{2}}; !reflect.DeepEqual(evs, expected) {
+		t.Errorf("expected %v, got %v", expected, evs)
+	}
+	evs, err = eventarchive.ReadAll(strings.NewReader("{\"id\":1}\n{\"id\":\n"), ndjson)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if expected := []event.Event{eventCreated{1}}; !reflect.DeepEqual(evs, expected) {
+		t.Errorf("expected %v, got %v", expected, evs)
+	}
+	if _, err := eventarchive.ReadAll(iotest.ErrReader(errors.New("read error")), ndjson); err == nil || err.Error() != "read error" {
+		t.Errorf("expected read error, got %v", err)
+	}
+	_, err = eventarchive.ReadAll(strings.NewReader(""), eventarchive.Format{
+		NewReader: func(io.Reader) (eventarchive.Reader, error) {
+			return nil, errors.New("reader error")
+		},
+	})
+	if err == nil || err.Error() != "reader error" {
+		t.Errorf("expected reader error, got %v", err)
+	}
+}
+
+func TestArchiverSequence(t *testing.T) {
+	ctx := context.Background()
+	format := eventarchive.NDJSON(
+		func(ev event.Event) ([]byte, error) {
+			return json.Marshal(ev)
+		},
+		func(bs []byte) (event.Event, error) {
+			var ev struct {
+				Seq   uint64
+				Event eventCreated
+			}
+			err := json.Unmarshal(bs, &ev)
+			return &eventarchive.Sequenced{Seq: ev.Seq, Event: ev.Event}, err
+		},
+	)
+	store1, store2 := &objects{}, &objects{}
+	archiver1 := eventarchive.New(store1, format, eventarchive.WithSequence())
+	pub := event.NewSequencer(event.Func(archiver1.Handle), 100)
+	for i := 1; i <= 3; i++ {
+		if err := pub.Publish(ctx, eventCreated{i}); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := archiver1.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := `{"Seq":100,"Event":{"id":1}}` + "\n" +
+		`{"Seq":101,"Event":{"id":2}}` + "\n" +
+		`{"Seq":102,"Event":{"id":3}}` + "\n"
+	if len(store1.data) != 1 || store1.data[0] != expected {
+		t.Fatalf("expected %q, got %q", expected, store1.data)
+	}
+	evs, err := eventarchive.ReadAll(strings.NewReader(store1.data[0]), format)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	archiver2 := eventarchive.New(store2, format, eventarchive.WithSequence())
+	pub = event.NewSequencer(event.Func(archiver2.Handle), 0)
+	if err := eventarchive.Replay(ctx, evs, pub); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := archiver2.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !reflect.DeepEqual(store2.data, store1.data) {
+		t.Errorf("expected %q, got %q", store1.data, store2.data)
+	}
+	if got, expected := pub.Next(), uint64(103); got != expected {
+		t.Errorf("expected %d, got %d", expected, got)
+	}
+	if got, expected := evs[0].Type(), eventTypeCreated; got != expected {
+		t.Errorf("expected %d, got %d", expected, got)
+	}
+	err = eventarchive.Replay(ctx, evs, event.Func(func(context.Context, event.Event) error {
+		return errors.New("publish error")
+	}))
+	if err == nil || err.Error() != "publish error" {
+		t.Errorf("expected publish error, got %v", err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(event.Event) error {
+	return nil
+}
+
+func (failingWriter) Close() error {
+	return errors.New("close error")
+}
+
+func TestArchiverError(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := eventtest.NewClock(now)
+	errs := make(chan error, 1)
+	store := &objects{}
+	sub := eventarchive.New(store, eventarchive.NDJSON(
+		func(ev event.Event) ([]byte, error) {
+			if ev == (eventCreated{0}) {
+				return nil, errors.New("encode error")
+			}
+			return json.Marshal(ev)
+		}, nil),
+		eventarchive.ErrorHandler(func(err error) { errs <- err }),
+		eventarchive.WithClock(clock),
+	)
+	if err := sub.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := sub.Handle(ctx, eventCreated{0}), "encode error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := sub.Handle(ctx, eventCreated{1}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	store.err = errors.New("put error")
+	clock.Advance(time.Minute)
+	if err, expected := <-errs, "put error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	clock.Advance(time.Hour)
+	if err, expected := sub.Handle(ctx, eventCreated{2}), "put error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	store.err = nil
+	if err := sub.Handle(ctx, eventCreated{2}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []string{"{\"id\":1}\n"}; !reflect.DeepEqual(store.data, expected) {
+		t.Errorf("expected %q, got %q", expected, store.data)
+	}
+	sub = eventarchive.New(store, eventarchive.Format{
+		NewWriter: func(io.Writer) (eventarchive.Writer, error) {
+			return nil, errors.New("writer error")
+		},
+	})
+	if err, expected := sub.Handle(ctx, eventCreated{1}), "writer error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	sub = eventarchive.New(store, eventarchive.Format{
+		NewWriter: func(io.Writer) (eventarchive.Writer, error) {
+			return failingWriter{}, nil
+		},
+	})
+	if err := sub.Handle(ctx, eventCreated{1}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := sub.Flush(ctx), "close error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := sub.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := 1; len(store.data) != expected {
+		t.Errorf("expected %d objects, got %q", expected, store.data)
+	}
+}
+
+func TestDirError(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := eventarchive.Dir(file).Put(ctx, "key", nil); err == nil {
+		t.Errorf("expected an error")
+	}
+	if err := eventarchive.Dir(dir).Put(ctx, strings.Repeat("x", 250), nil); err == nil {
+		t.Errorf("expected an error")
+	}
+}
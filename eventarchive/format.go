@@ -0,0 +1,104 @@
+package eventarchive
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/itchyny/event-go"
+)
+
+// Format is the format of the archived objects. Implement the writer and the
+// reader with the third-party libraries to support the columnar formats like
+// Parquet.
+type Format struct {
+	// The extension of the object keys, like ".ndjson".
+	Extension string
+	NewWriter func(io.Writer) (Writer, error)
+	NewReader func(io.Reader) (Reader, error)
+}
+
+// Writer is the interface to write the events into an object. Close is called
+// after writing all the events of the object.
+type Writer interface {
+	Write(event.Event) error
+	Close() error
+}
+
+// Reader is the interface to read the events from an object. Read returns
+// io.EOF when there are no more events.
+type Reader interface {
+	Read() (event.Event, error)
+}
+
+// NDJSON returns the format of newline delimited JSON. The encode function
+// serializes an event into a line, like json.Marshal, and the decode function
+// deserializes a line into an event. The nil decode function makes the format
+// write only.
+func NDJSON(encode func(event.Event) ([]byte, error), decode func([]byte) (event.Event, error)) Format {
+	return Format{
+		Extension: ".ndjson",
+		NewWriter: func(w io.Writer) (Writer, error) {
+			return &ndjsonWriter{w, encode}, nil
+		},
+		NewReader: func(r io.Reader) (Reader, error) {
+			s := bufio.NewScanner(r)
+			s.Buffer(nil, 64*1024*1024)
+			return &ndjsonReader{s, decode}, nil
+		},
+	}
+}
+
+type ndjsonWriter struct {
+	w      io.Writer
+	encode func(event.Event) ([]byte, error)
+}
+
+func (w *ndjsonWriter) Write(ev event.Event) error {
+	line, err := w.encode(ev)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(append(bytes.TrimRight(line, "\n"), '\n'))
+	return err
+}
+
+func (*ndjsonWriter) Close() error {
+	return nil
+}
+
+type ndjsonReader struct {
+	s      *bufio.Scanner
+	decode func([]byte) (event.Event, error)
+}
+
+func (r *ndjsonReader) Read() (event.Event, error) {
+	for r.s.Scan() {
+		if line := r.s.Bytes(); len(bytes.TrimSpace(line)) > 0 {
+			return r.decode(line)
+		}
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ReadAll reads all the events of the object in the format.
+func ReadAll(r io.Reader, format Format) ([]event.Event, error) {
+	rd, err := format.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var evs []event.Event
+	for {
+		ev, err := rd.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return evs, err
+		}
+		evs = append(evs, ev)
+	}
+}
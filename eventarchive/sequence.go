@@ -0,0 +1,42 @@
+package eventarchive
+
+import (
+	"context"
+
+	"github.com/itchyny/event-go"
+)
+
+// Sequenced is the envelope of an event with the sequence number assigned by
+// event.Sequencer, which is written to the objects with the WithSequence option.
+// The encode function of the format records the number along with the event.
+type Sequenced struct {
+	Seq   uint64
+	Event event.Event
+}
+
+// Type implements event.Event for Sequenced.
+func (ev *Sequenced) Type() event.Type {
+	return ev.Event.Type()
+}
+
+// WithSequence makes the archiver wrap the events into Sequenced by the sequence
+// numbers carried by the contexts, so that Replay restores the numbers.
+func WithSequence() Option {
+	return func(a *Archiver) { a.sequenced = true }
+}
+
+// Replay publishes the archived events in order, restoring the sequence
+// numbers of Sequenced by event.WithSequence, so that the replays are
+// reproducible. Replaying stops on the first error.
+func Replay(ctx context.Context, evs []event.Event, pub event.Publisher) error {
+	for _, ev := range evs {
+		ctx := ctx
+		if s, ok := ev.(*Sequenced); ok {
+			ctx, ev = event.WithSequence(ctx, s.Seq, s.Event), s.Event
+		}
+		if err := pub.Publish(ctx, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package eventcron_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventcron"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+const eventTypeTick event.Type = iota
+
+type tick time.Time
+
+func (ev tick) Type() event.Type {
+	return eventTypeTick
+}
+
+func TestParse(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC)
+	testCases := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, time.January, 31, 13, 0, 0, 0, time.UTC)},
+		{"5,10 8 * * *", time.Date(2024, time.February, 1, 8, 5, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 5", time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2024, time.January, 31, 10, 25, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			s, err := eventcron.Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if got := s.Next(from); !got.Equal(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := eventcron.Parse(expr); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	schedule, err := eventcron.Parse("0 * * * *")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	now := time.Now()
+	last := now.Add(-3 * time.Hour)
+	var missed []time.Time
+	for next := schedule.Next(last); !next.After(now); next = schedule.Next(next) {
+		missed = append(missed, next)
+	}
+	testCases := []struct {
+		name     string
+		catchUp  eventcron.CatchUp
+		expected []time.Time
+	}{
+		{"all", eventcron.CatchUpAll, missed},
+		{"once", eventcron.CatchUpOnce, missed[len(missed)-1:]},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			path := filepath.Join(t.TempDir(), "cron.json")
+			store, err := eventcron.NewFileStore(path)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if err := store.Save(ctx, "hourly", last); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			handled := make(chan time.Time)
+			s := eventcron.New(event.Func(func(_ context.Context, ev event.Event) error {
+				handled <- time.Time(ev.(tick))
+				return nil
+			}), eventcron.WithStore(store))
+			if err := s.Add("hourly", "0 * * * *", func(t time.Time) event.Event {
+				return tick(t)
+			}, eventcron.WithCatchUp(tc.catchUp), eventcron.Jitter(time.Minute)); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if err, expected := s.Add("hourly", "* * * * *", nil), `duplicate job name: "hourly"`; err == nil || err.Error() != expected {
+				t.Fatalf("expected %v, got %v", expected, err)
+			}
+			errc := make(chan error)
+			go func() { errc <- s.Run(ctx) }()
+			for _, expected := range tc.expected {
+				if got := <-handled; !got.Equal(expected) {
+					t.Errorf("expected %v, got %v", expected, got)
+				}
+			}
+			cancel()
+			if err, expected := <-errc, context.Canceled; err != expected {
+				t.Fatalf("expected %v, got %v", expected, err)
+			}
+			store, err = eventcron.NewFileStore(path)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			got, err := store.Load(ctx, "hourly")
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			if expected := missed[len(missed)-1]; !got.Equal(expected) {
+				t.Errorf("expected last run %v, got %v", expected, got)
+			}
+		})
+	}
+}
+
+func TestSchedulerClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Date(2024, time.January, 1, 0, 30, 0, 0, time.UTC)
+	clock := eventtest.NewClock(now)
+	handled := make(chan time.Time)
+	s := eventcron.New(event.Func(func(_ context.Context, ev event.Event) error {
+		handled <- time.Time(ev.(tick))
+		return nil
+	}), eventcron.WithClock(clock))
+	if err := s.Add("hourly", "0 * * * *", func(t time.Time) event.Event {
+		return tick(t)
+	}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	errc := make(chan error)
+	go func() { errc <- s.Run(ctx) }()
+	for i := 1; i <= 2; i++ {
+		clock.WaitTimers(1)
+		clock.Advance(time.Hour)
+		if got, expected := <-handled, now.Add(time.Duration(i)*time.Hour).Truncate(time.Hour); !got.Equal(expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	}
+	cancel()
+	if err, expected := <-errc, context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestKeyValueStore(t *testing.T) {
+	ctx := context.Background()
+	kv := event.NewMemoryStore()
+	store := eventcron.NewKeyValueStore(kv)
+	if got, err := store.Load(ctx, "hourly"); err != nil || !got.IsZero() {
+		t.Fatalf("expected the zero time, got %v, %v", got, err)
+	}
+	last := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	if err := store.Save(ctx, "hourly", last); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, err := store.Load(ctx, "hourly"); err != nil || !got.Equal(last) {
+		t.Fatalf("expected %v, got %v, %v", last, got, err)
+	}
+	if err := store.Save(ctx, "hourly", time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := kv.Save(ctx, "hourly", []byte("invalid")); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := store.Load(ctx, "hourly"); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Load(context.Context, string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (failingStore) Save(context.Context, string, time.Time) error {
+	return errors.New("save error")
+}
+
+func TestSchedulerError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Date(2024, time.January, 1, 0, 30, 0, 0, time.UTC)
+	clock := eventtest.NewClock(now)
+	errs := make(chan error)
+	s := eventcron.New(event.Func(func(context.Context, event.Event) error {
+		return errors.New("publish error")
+	}), eventcron.WithStore(failingStore{}), eventcron.WithClock(clock),
+		eventcron.ErrorHandler(func(err error) { errs <- err }))
+	if err := s.Add("hourly", "0 * * *", nil); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := s.Add("hourly", "0 * * * *", func(t time.Time) event.Event {
+		return tick(t)
+	}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	errc := make(chan error)
+	go func() { errc <- s.Run(ctx) }()
+	clock.WaitTimers(1)
+	clock.Advance(time.Hour)
+	for _, expected := range []string{"publish error", "save error"} {
+		if err := <-errs; err == nil || err.Error() != expected {
+			t.Errorf("expected %v, got %v", expected, err)
+		}
+	}
+	cancel()
+	if err, expected := <-errc, context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	s = eventcron.New(event.Discard)
+	if err := s.Add("never", "0 0 30 2 *", nil); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := s.Run(ctx), context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	kv := event.NewMemoryStore()
+	if err := kv.Save(ctx, "hourly", []byte("invalid")); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	s = eventcron.New(event.Discard, eventcron.WithStore(eventcron.NewKeyValueStore(kv)))
+	if err := s.Add("hourly", "0 * * * *", nil); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestSchedulerSkip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Date(2024, time.January, 1, 0, 30, 0, 0, time.UTC)
+	clock := eventtest.NewClock(now)
+	store := eventcron.NewKeyValueStore(event.NewMemoryStore())
+	if err := store.Save(ctx, "hourly", now.Add(-3*time.Hour)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	handled := make(chan time.Time)
+	s := eventcron.New(event.Func(func(_ context.Context, ev event.Event) error {
+		handled <- time.Time(ev.(tick))
+		return nil
+	}), eventcron.WithStore(store), eventcron.WithClock(clock))
+	if err := s.Add("hourly", "0 * * * *", func(t time.Time) event.Event {
+		return tick(t)
+	}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	errc := make(chan error)
+	go func() { errc <- s.Run(ctx) }()
+	clock.WaitTimers(1)
+	clock.Advance(time.Hour)
+	if got, expected := <-handled, now.Add(30*time.Minute); !got.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	cancel()
+	if err, expected := <-errc, context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestFileStoreError(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if _, err := eventcron.NewFileStore(dir); err == nil {
+		t.Fatalf("expected an error")
+	}
+	path := filepath.Join(dir, "cron.json")
+	if err := os.WriteFile(path, []byte("invalid"), 0o644); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := eventcron.NewFileStore(path); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	store, err := eventcron.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	last := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	if err := store.Save(ctx, "hourly", last); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := store.Save(ctx, "hourly", time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := store.Save(ctx, "daily", last); err == nil {
+		t.Fatalf("expected an error")
+	}
+	for _, name := range []string{"hourly", "daily"} {
+		got, err := store.Load(ctx, name)
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if expected := map[string]time.Time{"hourly": last}[name]; !got.Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+}
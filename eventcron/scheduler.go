@@ -0,0 +1,193 @@
+// Package eventcron provides a scheduler to publish events on cron schedules,
+// with the jitter, the catch-up of the missed runs and the persistent store of
+// the last run times to survive restarts.
+package eventcron
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+// Scheduler is a scheduler to publish the events of the jobs on their cron
+// schedules.
+type Scheduler struct {
+	publisher event.Publisher
+	store     Store
+	onError   func(error)
+	clock     event.Clock
+	jobs      []*job
+}
+
+type job struct {
+	name     string
+	schedule *Schedule
+	factory  func(time.Time) event.Event
+	jitter   time.Duration
+	catchUp  CatchUp
+	next     time.Time
+	fire     time.Time
+}
+
+// Option is an option for New.
+type Option func(*Scheduler)
+
+// WithStore sets the store of the last run times. The times are stored in
+// memory by default, so the missed runs are not caught up after restarts.
+func WithStore(store Store) Option {
+	return func(s *Scheduler) { s.store = store }
+}
+
+// ErrorHandler sets the function to report the errors on publishing the events
+// and saving the last run times. The errors are ignored by default.
+func ErrorHandler(f func(error)) Option {
+	return func(s *Scheduler) { s.onError = f }
+}
+
+// WithClock sets the clock of the schedules instead of the global clock set by
+// event.SetClock.
+func WithClock(c event.Clock) Option {
+	return func(s *Scheduler) { s.clock = c }
+}
+
+// CatchUp is the policy of the runs missed while the scheduler is stopped or
+// the publishing is slow.
+type CatchUp int
+
+// The catch-up policies. CatchUpNone skips the missed runs, CatchUpOnce runs
+// only the latest missed run, and CatchUpAll runs all the missed runs.
+const (
+	CatchUpNone CatchUp = iota
+	CatchUpOnce
+	CatchUpAll
+)
+
+// JobOption is an option for Add.
+type JobOption func(*job)
+
+// Jitter sets the max random delay of the runs, to spread the load of the jobs
+// scheduled at the same time. The scheduled time is passed to the factory
+// regardless of the delay.
+func Jitter(d time.Duration) JobOption {
+	return func(j *job) { j.jitter = d }
+}
+
+// WithCatchUp sets the catch-up policy of the missed runs. The missed runs are
+// skipped by default. The missed runs are published immediately without the
+// jitter.
+func WithCatchUp(c CatchUp) JobOption {
+	return func(j *job) { j.catchUp = c }
+}
+
+// New creates a new scheduler to publish the events to the publisher.
+func New(pub event.Publisher, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		publisher: pub,
+		store:     &memoryStore{times: make(map[string]time.Time)},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add adds a job to publish the events built by the factory on the cron
+// schedule. The factory is called with the scheduled time. The name identifies
+// the job in the store, so it should be unique and stable across restarts. Add
+// the jobs before calling Run.
+func (s *Scheduler) Add(name, expr string, factory func(time.Time) event.Event, opts ...JobOption) error {
+	for _, j := range s.jobs {
+		if j.name == name {
+			return fmt.Errorf("duplicate job name: %q", name)
+		}
+	}
+	schedule, err := Parse(expr)
+	if err != nil {
+		return err
+	}
+	j := &job{name: name, schedule: schedule, factory: factory}
+	for _, opt := range opts {
+		opt(j)
+	}
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Run publishes the events of the jobs until the context is canceled. The last
+// run times are loaded from the store on start, and the missed runs are caught
+// up by the policies of the jobs. This method returns the error of loading the
+// last run times or the error of the context.
+func (s *Scheduler) Run(ctx context.Context) error {
+	clock := s.clock
+	if clock == nil {
+		clock = event.CurrentClock()
+	}
+	now := clock.Now()
+	for _, j := range s.jobs {
+		last, err := s.store.Load(ctx, j.name)
+		if err != nil {
+			return err
+		}
+		if last.IsZero() {
+			last = now
+		}
+		j.advance(last, now)
+	}
+	for {
+		var next *job
+		for _, j := range s.jobs {
+			if !j.fire.IsZero() && (next == nil || j.fire.Before(next.fire)) {
+				next = j
+			}
+		}
+		if next == nil {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		timer := clock.NewTimer(next.fire.Sub(clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+		if err := s.publisher.Publish(ctx, next.factory(next.next)); err != nil {
+			s.error(err)
+		}
+		if err := s.store.Save(ctx, next.name, next.next); err != nil {
+			s.error(err)
+		}
+		next.advance(next.next, clock.Now())
+	}
+}
+
+func (s *Scheduler) error(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
+func (j *job) advance(last, now time.Time) {
+	next := j.schedule.Next(last)
+	if !next.IsZero() && !next.After(now) {
+		switch j.catchUp {
+		case CatchUpOnce:
+			for n := j.schedule.Next(next); !n.IsZero() && !n.After(now); n = j.schedule.Next(n) {
+				next = n
+			}
+			fallthrough
+		case CatchUpAll:
+			j.next, j.fire = next, now
+			return
+		default:
+			next = j.schedule.Next(now)
+		}
+	}
+	j.next, j.fire = next, next
+	if !next.IsZero() && j.jitter > 0 {
+		j.fire = next.Add(time.Duration(rand.Int63n(int64(j.jitter))))
+	}
+}
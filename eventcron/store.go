@@ -0,0 +1,141 @@
+package eventcron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+// Store is the interface for storing the last run times of the jobs, so that
+// the missed runs can be caught up after restarts.
+type Store interface {
+	// Load the last run time of the job, or the zero time if not found.
+	Load(context.Context, string) (time.Time, error)
+	// Save the last run time of the job.
+	Save(context.Context, string, time.Time) error
+}
+
+type memoryStore struct {
+	mu    sync.Mutex
+	times map[string]time.Time
+}
+
+func (s *memoryStore) Load(_ context.Context, name string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.times[name], nil
+}
+
+func (s *memoryStore) Save(_ context.Context, name string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.times[name] = t
+	return nil
+}
+
+// FileStore is a store to keep the last run times in a JSON file. The file is
+// replaced atomically on each save.
+type FileStore struct {
+	path  string
+	mu    sync.Mutex
+	times map[string]time.Time
+}
+
+// NewFileStore creates a new file store, loading the last run times from the
+// file if it exists.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, times: make(map[string]time.Time)}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(bs, &s.times); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Load implements Store for FileStore.
+func (s *FileStore) Load(_ context.Context, name string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.times[name], nil
+}
+
+// Save implements Store for FileStore.
+func (s *FileStore) Save(_ context.Context, name string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.times[name]
+	s.times[name] = t
+	if err := s.write(); err != nil {
+		if ok {
+			s.times[name] = prev
+		} else {
+			delete(s.times, name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) write() error {
+	bs, err := json.Marshal(s.times)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(bs)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path)
+	}
+	return err
+}
+
+// KeyValueStore is a store to keep the last run times in event.Store, like the
+// stores of the eventstore package. The jobs are keyed by the names.
+type KeyValueStore struct {
+	store event.Store
+}
+
+// NewKeyValueStore creates a new store backed by event.Store.
+func NewKeyValueStore(store event.Store) *KeyValueStore {
+	return &KeyValueStore{store: store}
+}
+
+// Load implements Store for KeyValueStore.
+func (s *KeyValueStore) Load(ctx context.Context, name string) (time.Time, error) {
+	var t time.Time
+	bs, ok, err := s.store.Load(ctx, name)
+	if err != nil || !ok {
+		return t, err
+	}
+	err = t.UnmarshalText(bs)
+	return t, err
+}
+
+// Save implements Store for KeyValueStore.
+func (s *KeyValueStore) Save(ctx context.Context, name string, t time.Time) error {
+	bs, err := t.MarshalText()
+	if err != nil {
+		return err
+	}
+	return s.store.Save(ctx, name, bs)
+}
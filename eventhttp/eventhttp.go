@@ -0,0 +1,65 @@
+// Package eventhttp provides a net/http middleware to buffer the events
+// published while handling a request.
+package eventhttp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/itchyny/event-go"
+)
+
+// Middleware returns a middleware which injects a new event.Buffer of the
+// publisher into each request context, so that handlers can publish events
+// via event.FromContext. The buffered events are dispatched after the handler
+// responds with a successful status code, and discarded otherwise. Since the
+// response is already written, dispatching errors are not reported to the
+// client, so the subscribers should handle their errors by themselves.
+func Middleware(pub event.Publisher) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf := event.NewBuffer(pub)
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r.WithContext(event.NewContext(r.Context(), buf)))
+			if rw.status < http.StatusBadRequest {
+				_ = buf.Dispatch(r.Context())
+			}
+		})
+	}
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush implements http.Flusher for streaming responses, which writes the
+// header with the status code of the response.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for the protocols like WebSocket.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("eventhttp: response writer does not implement http.Hijacker")
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,91 @@
+package eventhttp_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventhttp"
+)
+
+const eventTypeCreated event.Type = iota
+
+type eventCreated int
+
+func (eventCreated) Type() event.Type {
+	return eventTypeCreated
+}
+
+func TestMiddleware(t *testing.T) {
+	var handled []event.Event
+	handler := eventhttp.Middleware(
+		event.NewMapping().
+			On(eventTypeCreated, event.Func(func(_ context.Context, ev event.Event) error {
+				handled = append(handled, ev)
+				return nil
+			})),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, n := r.Context(), len(handled)
+		if err := event.FromContext(ctx).Publish(ctx, eventCreated(len(r.URL.Path))); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if len(handled) != n {
+			t.Errorf("expected events not dispatched yet, got %v", handled)
+		}
+		switch r.URL.Path {
+		case "/error":
+			http.Error(w, "error", http.StatusInternalServerError)
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	for _, path := range []string{"/", "/error", "/created"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(8)}; !reflect.DeepEqual(handled, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, handled)
+	}
+	rw := httptest.NewRecorder()
+	eventhttp.Middleware(event.NewMapping())(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if w, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || w.Unwrap() != rw {
+			t.Errorf("expected the response writer to unwrap to %v", rw)
+		}
+	})).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+type hijacker struct {
+	http.ResponseWriter
+	conn net.Conn
+}
+
+func (w hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, nil, nil
+}
+
+func TestMiddlewareFlushHijack(t *testing.T) {
+	rw := httptest.NewRecorder()
+	eventhttp.Middleware(event.NewMapping())(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.(http.Flusher).Flush()
+		if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			t.Errorf("expected an error on hijacking the recorder")
+		}
+	})).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !rw.Flushed {
+		t.Errorf("expected the response to be flushed")
+	}
+	conn, _ := net.Pipe()
+	defer conn.Close()
+	eventhttp.Middleware(event.NewMapping())(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if c, _, err := w.(http.Hijacker).Hijack(); err != nil || c != conn {
+			t.Errorf("expected the hijacked connection, got %v, %v", c, err)
+		}
+	})).ServeHTTP(hijacker{httptest.NewRecorder(), conn}, httptest.NewRequest(http.MethodGet, "/", nil))
+}
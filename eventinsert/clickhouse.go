@@ -0,0 +1,73 @@
+package eventinsert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// ClickHouse is an inserter to insert the rows with the HTTP interface of
+// ClickHouse, in the JSONEachRow format.
+type ClickHouse struct {
+	// The URL of the HTTP interface, like "http://localhost:8123/".
+	URL    string
+	Header http.Header
+	// The client to send the requests. The nil client means
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// Insert implements Inserter for ClickHouse.
+func (c *ClickHouse) Insert(ctx context.Context, table string, rows []Row) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, &body)
+	if err != nil {
+		return err
+	}
+	q := req.URL.Query()
+	q.Set("query", "INSERT INTO "+table+" FORMAT JSONEachRow")
+	req.URL.RawQuery = q.Encode()
+	for k, vs := range c.Header {
+		req.Header[k] = vs
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if res.StatusCode >= http.StatusBadRequest {
+		return &StatusError{StatusCode: res.StatusCode, Message: string(bytes.TrimSpace(msg))}
+	}
+	return nil
+}
+
+// StatusError is the error on the unsuccessful response status code.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements error for StatusError.
+func (err *StatusError) Error() string {
+	return "unexpected status code " + strconv.Itoa(err.StatusCode) + ": " + err.Message
+}
+
+// Temporary reports whether the error is temporary, like the quota errors and
+// the unavailability of the server.
+func (err *StatusError) Temporary() bool {
+	return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= http.StatusInternalServerError
+}
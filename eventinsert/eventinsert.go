@@ -0,0 +1,243 @@
+// Package eventinsert provides a subscriber to stream the events into the
+// tables of the analytical databases, like ClickHouse and BigQuery.
+package eventinsert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/itchyny/event-go"
+)
+
+// Row is a row of a table, keyed by the column names.
+type Row map[string]interface{}
+
+// Inserter is the interface to insert the rows into a table.
+type Inserter interface {
+	Insert(ctx context.Context, table string, rows []Row) error
+}
+
+// Sink is an event subscriber to batch the events into the rows of the tables
+// per event type, and insert them with the inserter. The rows are inserted when
+// the batch of a table reaches the max number of rows, or the flush interval
+// elapses since the first row of the batch. The insertions failed with the
+// temporary errors, like the quota errors, are retried.
+type Sink struct {
+	inserter  Inserter
+	tables    map[event.Type]string
+	maxRows   int
+	interval  time.Duration
+	attempts  int
+	backoff   time.Duration
+	retryable func(error) bool
+	onError   func(error)
+	clock     event.Clock
+	mu        sync.Mutex
+	rows      map[string][]Row
+	timer     event.Timer
+}
+
+// Option is an option for New.
+type Option func(*Sink)
+
+// Table maps the events of the type into the rows of the table. The events of
+// the types without the tables are ignored.
+func Table(typ event.Type, table string) Option {
+	return func(s *Sink) { s.tables[typ] = table }
+}
+
+// MaxRows sets the max number of the rows of an insertion. The default is 500.
+func MaxRows(n int) Option {
+	return func(s *Sink) { s.maxRows = n }
+}
+
+// FlushInterval sets the max duration to keep the rows in memory. The default
+// interval is one second.
+func FlushInterval(d time.Duration) Option {
+	return func(s *Sink) { s.interval = d }
+}
+
+// Retry sets the max number of attempts of an insertion and the initial
+// interval between the attempts, which doubles on each retry. The retryable
+// function reports whether the error is temporary. The nil function retries
+// the errors implementing Temporary() bool, like StatusError of the quota
+// errors. The insertions are attempted 3 times by default.
+func Retry(attempts int, backoff time.Duration, retryable func(error) bool) Option {
+	return func(s *Sink) { s.attempts, s.backoff, s.retryable = attempts, backoff, retryable }
+}
+
+// ErrorHandler sets the function to report the errors on inserting the rows
+// after the interval. The errors are ignored by default.
+func ErrorHandler(f func(error)) Option {
+	return func(s *Sink) { s.onError = f }
+}
+
+// WithClock sets the clock of the flush interval and the retries instead of the
+// global clock set by event.SetClock.
+func WithClock(c event.Clock) Option {
+	return func(s *Sink) { s.clock = c }
+}
+
+// New creates a new sink to insert the rows with the inserter.
+func New(inserter Inserter, opts ...Option) *Sink {
+	s := &Sink{
+		inserter: inserter, tables: make(map[event.Type]string),
+		maxRows: 500, interval: time.Second,
+		attempts: 3, backoff: 100 * time.Millisecond,
+		rows: make(map[string][]Row),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.retryable == nil {
+		s.retryable = temporary
+	}
+	return s
+}
+
+func temporary(err error) bool {
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// Handle implements event.Subscriber for Sink.
+func (sub *Sink) Handle(ctx context.Context, ev event.Event) error {
+	table, ok := sub.tables[ev.Type()]
+	if !ok {
+		return nil
+	}
+	row, err := Columns(ev)
+	if err != nil {
+		return err
+	}
+	sub.mu.Lock()
+	rows := append(sub.rows[table], row)
+	if len(rows) < sub.maxRows {
+		sub.rows[table] = rows
+		if sub.timer == nil {
+			sub.timer = clockOr(sub.clock).AfterFunc(sub.interval, func() {
+				if err := sub.Flush(context.Background()); err != nil && sub.onError != nil {
+					sub.onError(err)
+				}
+			})
+		}
+		sub.mu.Unlock()
+		return nil
+	}
+	delete(sub.rows, table)
+	sub.mu.Unlock()
+	return sub.insert(ctx, table, rows)
+}
+
+// Flush inserts the rows of all the tables immediately, which is useful on
+// shutdown.
+func (sub *Sink) Flush(ctx context.Context) error {
+	sub.mu.Lock()
+	tables := sub.rows
+	sub.rows = make(map[string][]Row)
+	if sub.timer != nil {
+		sub.timer.Stop()
+		sub.timer = nil
+	}
+	sub.mu.Unlock()
+	var errs []string
+	for table, rows := range tables {
+		if err := sub.insert(ctx, table, rows); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (sub *Sink) insert(ctx context.Context, table string, rows []Row) error {
+	var err error
+	for i, wait := 0, sub.backoff; i < sub.attempts || i == 0; i, wait = i+1, wait*2 {
+		if i > 0 {
+			if !sub.retryable(err) {
+				break
+			}
+			timer := clockOr(sub.clock).NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C():
+			}
+		}
+		if err = sub.inserter.Insert(ctx, table, rows); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("insert into %s: %w", table, err)
+}
+
+// Columns maps the exported fields of the event struct into a row. The column
+// name is the value of the "column" tag of the field, or the field name in the
+// snake case. The fields with the tag "-" are skipped, and the fields of the
+// embedded structs are flattened.
+func Columns(ev event.Event) (Row, error) {
+	v := reflect.ValueOf(ev)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("event is not a struct: %T", ev)
+	}
+	row := make(Row)
+	columns(v, row)
+	return row, nil
+}
+
+func columns(v reflect.Value, row Row) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			columns(v.Field(i), row)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Tag.Get("column")
+		if name == "-" {
+			continue
+		} else if name == "" {
+			name = snakeCase(f.Name)
+		}
+		row[name] = v.Field(i).Interface()
+	}
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) ||
+				i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// clockOr returns the clock, or the global clock if nil.
+func clockOr(c event.Clock) event.Clock {
+	if c != nil {
+		return c
+	}
+	return event.CurrentClock()
+}
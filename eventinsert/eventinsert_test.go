@@ -0,0 +1,235 @@
+package eventinsert_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventinsert"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+const (
+	eventTypeCreated event.Type = iota
+	eventTypeUpdated
+	eventTypeOther
+)
+
+type base struct {
+	UserID int
+}
+
+type eventCreated struct {
+	base
+	HTTPStatus int
+	Name       string `column:"title"`
+	Secret     string `column:"-"`
+	internal   int
+}
+
+func (eventCreated) Type() event.Type {
+	return eventTypeCreated
+}
+
+type eventUpdated struct {
+	ID int
+}
+
+func (eventUpdated) Type() event.Type {
+	return eventTypeUpdated
+}
+
+type eventOther int
+
+func (eventOther) Type() event.Type {
+	return eventTypeOther
+}
+
+func TestColumns(t *testing.T) {
+	row, err := eventinsert.Columns(&eventCreated{base{1}, 200, "foo", "secret", 0})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := (eventinsert.Row{"user_id": 1, "http_status": 200, "title": "foo"}); !reflect.DeepEqual(row, expected) {
+		t.Errorf("expected %v, got %v", expected, row)
+	}
+	if _, err = eventinsert.Columns(eventOther(1)); err == nil ||
+		err.Error() != "event is not a struct: eventinsert_test.eventOther" {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+type inserter struct {
+	mu     sync.Mutex
+	tables []string
+	rows   [][]eventinsert.Row
+	errs   []error
+}
+
+func (ins *inserter) Insert(_ context.Context, table string, rows []eventinsert.Row) error {
+	ins.mu.Lock()
+	defer ins.mu.Unlock()
+	if len(ins.errs) > 0 {
+		err := ins.errs[0]
+		ins.errs = ins.errs[1:]
+		return err
+	}
+	ins.tables = append(ins.tables, table)
+	ins.rows = append(ins.rows, rows)
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	ctx := context.Background()
+	ins := &inserter{errs: []error{&eventinsert.StatusError{StatusCode: http.StatusTooManyRequests}}}
+	sub := eventinsert.New(ins,
+		eventinsert.Table(eventTypeCreated, "created"),
+		eventinsert.Table(eventTypeUpdated, "updated"),
+		eventinsert.MaxRows(2),
+		eventinsert.Retry(2, time.Millisecond, nil),
+	)
+	for _, ev := range []event.Event{
+		eventCreated{Name: "foo"}, eventOther(1), eventCreated{Name: "bar"}, eventCreated{Name: "baz"},
+	} {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []string{"created"}; !reflect.DeepEqual(ins.tables, expected) {
+		t.Errorf("expected %v, got %v", expected, ins.tables)
+	}
+	if err := sub.Handle(ctx, eventUpdated{1}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	ins.errs = []error{errors.New("insert error")}
+	if err := sub.Flush(ctx); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if expected := 2; len(ins.tables) != expected {
+		t.Errorf("expected %d insertions, got %v", expected, ins.tables)
+	}
+	if expected := [][]eventinsert.Row{{
+		{"user_id": 0, "http_status": 0, "title": "foo"},
+		{"user_id": 0, "http_status": 0, "title": "bar"},
+	}}; !reflect.DeepEqual(ins.rows[:1], expected) {
+		t.Errorf("expected %v, got %v", expected, ins.rows)
+	}
+}
+
+func TestSinkClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ins := &inserter{}
+	sub := eventinsert.New(ins,
+		eventinsert.Table(eventTypeCreated, "created"),
+		eventinsert.FlushInterval(time.Minute),
+		eventinsert.WithClock(clock),
+	)
+	if err := sub.Handle(ctx, eventCreated{Name: "foo"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(59 * time.Second)
+	if len(ins.tables) != 0 {
+		t.Fatalf("expected no insertions, got %v", ins.tables)
+	}
+	clock.Advance(time.Second)
+	if expected := []string{"created"}; !reflect.DeepEqual(ins.tables, expected) {
+		t.Errorf("expected %v, got %v", expected, ins.tables)
+	}
+}
+
+func TestClickHouse(t *testing.T) {
+	ctx := context.Background()
+	var queries, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+		queries = append(queries, r.URL.Query().Get("query"))
+		bodies = append(bodies, string(bs))
+		if got, expected := r.Header.Get("X-ClickHouse-User"), "user"; got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+		if len(queries) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("unavailable\n"))
+		}
+	}))
+	defer server.Close()
+	ins := &eventinsert.ClickHouse{URL: server.URL, Header: http.Header{"X-Clickhouse-User": {"user"}}}
+	rows := []eventinsert.Row{{"id": 1, "name": "foo"}, {"id": 2, "name": "bar"}}
+	if err := ins.Insert(ctx, "events", rows); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	err := ins.Insert(ctx, "events", rows)
+	var serr *eventinsert.StatusError
+	if !errors.As(err, &serr) || !serr.Temporary() || serr.Message != "unavailable" {
+		t.Errorf("expected a temporary status error, got %v", err)
+	}
+	if expected := "unexpected status code 503: unavailable"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+	if err := ins.Insert(ctx, "events", []eventinsert.Row{{"id": make(chan int)}}); err == nil {
+		t.Errorf("expected an error")
+	}
+	if expected := []string{"INSERT INTO events FORMAT JSONEachRow", "INSERT INTO events FORMAT JSONEachRow"}; !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected %v, got %v", expected, queries)
+	}
+	if expected := "{\"id\":1,\"name\":\"foo\"}\n{\"id\":2,\"name\":\"bar\"}\n"; bodies[0] != expected {
+		t.Errorf("expected %q, got %q", expected, bodies[0])
+	}
+}
+
+func TestClickHouseError(t *testing.T) {
+	ctx := context.Background()
+	rows := []eventinsert.Row{{"id": 1}}
+	if err := (&eventinsert.ClickHouse{URL: "%"}).Insert(ctx, "events", rows); err == nil {
+		t.Errorf("expected an error")
+	}
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	if err := (&eventinsert.ClickHouse{URL: server.URL}).Insert(ctx, "events", rows); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestSinkError(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var errs []error
+	ins := &inserter{errs: []error{errors.New("insert error")}}
+	sub := eventinsert.New(ins,
+		eventinsert.Table(eventTypeCreated, "created"),
+		eventinsert.Table(eventTypeOther, "other"),
+		eventinsert.FlushInterval(time.Minute),
+		eventinsert.ErrorHandler(func(err error) { errs = append(errs, err) }),
+		eventinsert.WithClock(clock),
+	)
+	if err := sub.Handle(ctx, eventOther(1)); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := sub.Handle(ctx, eventCreated{Name: "foo"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	if expected := "insert into created: insert error"; len(errs) != 1 || errs[0].Error() != expected {
+		t.Errorf("expected %q, got %v", expected, errs)
+	}
+	ins.errs = []error{&eventinsert.StatusError{StatusCode: http.StatusTooManyRequests}}
+	if err := sub.Handle(ctx, eventCreated{Name: "bar"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := sub.Flush(cctx); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if len(ins.tables) != 0 {
+		t.Errorf("expected no insertions, got %v", ins.tables)
+	}
+}
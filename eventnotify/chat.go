@@ -0,0 +1,97 @@
+package eventnotify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+// The max number of retries on the rate limiting of the chat services.
+const maxRateLimitRetries = 3
+
+// Slack is a sender to post the messages to a Slack incoming webhook. When the
+// webhook is rate limited, the message is retried after the duration of the
+// Retry-After header.
+type Slack struct {
+	URL string
+	// The function to build the payload of the message. The default payload
+	// is the text of the bold subject and the body.
+	Format func(Message) interface{}
+	// The client to send the requests. The nil client means
+	// http.DefaultClient.
+	Client *http.Client
+	// The clock to wait for the rate limiting. The nil clock means the global
+	// clock set by event.SetClock.
+	Clock event.Clock
+}
+
+// Send implements Sender for Slack.
+func (s *Slack) Send(ctx context.Context, msg Message) error {
+	var payload interface{}
+	if s.Format != nil {
+		payload = s.Format(msg)
+	} else {
+		payload = map[string]string{"text": "*" + msg.Subject + "*\n" + msg.Body}
+	}
+	return postChat(ctx, s.Client, s.Clock, s.URL, payload)
+}
+
+// The max length of the content of a Discord message.
+const discordMaxContent = 2000
+
+// Discord is a sender to post the messages to a Discord webhook. When the
+// webhook is rate limited, the message is retried after the duration of the
+// Retry-After header.
+type Discord struct {
+	URL string
+	// The function to build the payload of the message. The default payload
+	// is the content of the bold subject and the body, truncated to the max
+	// length of a message.
+	Format func(Message) interface{}
+	// The client to send the requests. The nil client means
+	// http.DefaultClient.
+	Client *http.Client
+	// The clock to wait for the rate limiting. The nil clock means the global
+	// clock set by event.SetClock.
+	Clock event.Clock
+}
+
+// Send implements Sender for Discord.
+func (s *Discord) Send(ctx context.Context, msg Message) error {
+	var payload interface{}
+	if s.Format != nil {
+		payload = s.Format(msg)
+	} else {
+		content := []rune("**" + msg.Subject + "**\n" + msg.Body)
+		if len(content) > discordMaxContent {
+			content = append(content[:discordMaxContent-1], '…')
+		}
+		payload = map[string]string{"content": string(content)}
+	}
+	return postChat(ctx, s.Client, s.Clock, s.URL, payload)
+}
+
+func postChat(ctx context.Context, client *http.Client, clock event.Clock, url string, payload interface{}) error {
+	for i := 0; ; i++ {
+		err := post(ctx, client, url, nil, payload)
+		var serr *StatusError
+		if i == maxRateLimitRetries || !errors.As(err, &serr) ||
+			serr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+		wait := serr.RetryAfter
+		if wait == 0 {
+			wait = time.Second
+		}
+		timer := clockOr(clock).NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
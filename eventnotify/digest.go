@@ -0,0 +1,133 @@
+package eventnotify
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+// RetryOption is an option for Retry.
+type RetryOption func(*retryOptions)
+
+type retryOptions struct {
+	clock event.Clock
+}
+
+// RetryClock sets the clock of the backoff instead of the global clock set by
+// event.SetClock.
+func RetryClock(c event.Clock) RetryOption {
+	return func(opts *retryOptions) { opts.clock = c }
+}
+
+// Retry returns a sender to retry sending the message up to the attempts, with
+// the interval from the backoff doubling on each retry.
+func Retry(sender Sender, attempts int, backoff time.Duration, opts ...RetryOption) Sender {
+	var o retryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return SenderFunc(func(ctx context.Context, msg Message) error {
+		var err error
+		for i, wait := 0, backoff; i < attempts || i == 0; i, wait = i+1, wait*2 {
+			if i > 0 {
+				timer := clockOr(o.clock).NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				case <-timer.C():
+				}
+			}
+			if err = sender.Send(ctx, msg); err == nil {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+// Digest is a sender to batch the messages into a digest message, which is
+// useful to send the digest emails of the frequent events. The messages are
+// sent together after the interval since the first message of the batch.
+// Register the notifiers of the event types sharing the digest, or separate
+// digests per type with event.Mapping.
+type Digest struct {
+	sender   Sender
+	subject  string
+	interval time.Duration
+	onError  func(error)
+	clock    event.Clock
+	mu       sync.Mutex
+	messages []Message
+	timer    event.Timer
+}
+
+// NewDigest creates a new digest sender with the subject of the digest message.
+func NewDigest(sender Sender, subject string, interval time.Duration) *Digest {
+	return &Digest{sender: sender, subject: subject, interval: interval}
+}
+
+// ErrorHandler sets the function to report the errors on sending the digest
+// messages after the interval. The errors are ignored by default. This method
+// returns the sender to allow method chaining.
+func (s *Digest) ErrorHandler(f func(error)) *Digest {
+	s.onError = f
+	return s
+}
+
+// Clock sets the clock of the interval instead of the global clock set by
+// event.SetClock. This method returns the sender to allow method chaining.
+func (s *Digest) Clock(c event.Clock) *Digest {
+	s.clock = c
+	return s
+}
+
+// Send implements Sender for Digest. The message is added to the batch.
+func (s *Digest) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	if s.timer == nil {
+		s.timer = clockOr(s.clock).AfterFunc(s.interval, func() {
+			if err := s.Flush(context.Background()); err != nil && s.onError != nil {
+				s.onError(err)
+			}
+		})
+	}
+	return nil
+}
+
+// Flush sends the digest message of the batch immediately, which is useful on
+// shutdown.
+func (s *Digest) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	messages := s.messages
+	s.messages = nil
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mu.Unlock()
+	if len(messages) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	for i, msg := range messages {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(msg.Subject + "\n" + strings.TrimRight(msg.Body, "\n") + "\n")
+	}
+	return s.sender.Send(ctx, Message{s.subject, b.String()})
+}
+
+// clockOr returns the clock, or the global clock if nil.
+func clockOr(c event.Clock) event.Clock {
+	if c != nil {
+		return c
+	}
+	return event.CurrentClock()
+}
@@ -0,0 +1,83 @@
+// Package eventnotify provides a subscriber to send notifications rendered from
+// the events, with the senders of the common destinations.
+package eventnotify
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+
+	"github.com/itchyny/event-go"
+)
+
+// Message is a notification message.
+type Message struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Sender is the interface to deliver the notification messages.
+type Sender interface {
+	Send(context.Context, Message) error
+}
+
+// SenderFunc is a sender built from a function.
+type SenderFunc func(context.Context, Message) error
+
+// Send implements Sender for SenderFunc.
+func (f SenderFunc) Send(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// Notifier is an event subscriber to render the notification messages from the
+// events with text/template, and send them with the sender.
+type Notifier struct {
+	sender  Sender
+	subject *template.Template
+	body    *template.Template
+}
+
+// New creates a new notifier with the templates of the subject and the body,
+// which are executed with the event as the data. The events of any types are
+// rendered, so register the notifier to the types to notify.
+func New(sender Sender, subject, body string) (*Notifier, error) {
+	s, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, err
+	}
+	b, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Notifier{sender, s, b}, nil
+}
+
+// Must is a helper that wraps a call to a function returning (*Notifier,
+// error) and panics if the error is non-nil.
+func Must(n *Notifier, err error) *Notifier {
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Handle implements event.Subscriber for Notifier.
+func (sub *Notifier) Handle(ctx context.Context, ev event.Event) error {
+	msg, err := sub.Render(ev)
+	if err != nil {
+		return err
+	}
+	return sub.sender.Send(ctx, msg)
+}
+
+// Render renders the notification message of the event.
+func (sub *Notifier) Render(ev event.Event) (Message, error) {
+	var subject, body bytes.Buffer
+	if err := sub.subject.Execute(&subject, ev); err != nil {
+		return Message{}, err
+	}
+	if err := sub.body.Execute(&body, ev); err != nil {
+		return Message{}, err
+	}
+	return Message{subject.String(), body.String()}, nil
+}
@@ -0,0 +1,381 @@
+package eventnotify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventnotify"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+const (
+	eventTypeCreated event.Type = iota
+	eventTypeUpdated
+)
+
+type eventCreated struct {
+	ID   int
+	Name string
+}
+
+func (eventCreated) Type() event.Type {
+	return eventTypeCreated
+}
+
+type eventUpdated eventCreated
+
+func (eventUpdated) Type() event.Type {
+	return eventTypeUpdated
+}
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+	var messages []eventnotify.Message
+	sub := eventnotify.Must(eventnotify.New(
+		eventnotify.SenderFunc(func(_ context.Context, msg eventnotify.Message) error {
+			messages = append(messages, msg)
+			return nil
+		}),
+		"Created {{.ID}}",
+		"{{.Name}} is created.",
+	))
+	if err := sub.Handle(ctx, eventCreated{1, "foo"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []eventnotify.Message{{"Created 1", "foo is created."}}; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+	if _, err := eventnotify.New(nil, "{{", ""); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := eventnotify.New(nil, "", "{{"); err == nil {
+		t.Errorf("expected an error")
+	}
+	sub = eventnotify.Must(eventnotify.New(nil, "{{.Unknown}}", ""))
+	if err := sub.Handle(ctx, eventCreated{}); err == nil {
+		t.Errorf("expected an error")
+	}
+	sub = eventnotify.Must(eventnotify.New(nil, "", "{{.Unknown}}"))
+	if err := sub.Handle(ctx, eventCreated{}); err == nil {
+		t.Errorf("expected an error")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+	eventnotify.Must(eventnotify.New(nil, "{{", ""))
+}
+
+func TestHTTP(t *testing.T) {
+	ctx := context.Background()
+	var messages []eventnotify.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, expected := r.Header.Get("Authorization"), "Bearer token"; got != expected {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+		var msg eventnotify.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("got error: %v", err)
+		}
+		if msg.Subject == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		messages = append(messages, msg)
+	}))
+	defer server.Close()
+	sender := &eventnotify.HTTP{URL: server.URL, Header: http.Header{"Authorization": {"Bearer token"}}}
+	if err := sender.Send(ctx, eventnotify.Message{"subject", "body"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	err := sender.Send(ctx, eventnotify.Message{})
+	var serr *eventnotify.StatusError
+	if !errors.As(err, &serr) || serr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a status error, got %v", err)
+	}
+	if expected := "unexpected status code 400: " + server.URL; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+	if expected := []eventnotify.Message{{"subject", "body"}}; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+	sender = &eventnotify.HTTP{URL: "%"}
+	if err := sender.Send(ctx, eventnotify.Message{"subject", "body"}); err == nil {
+		t.Errorf("expected an error")
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	sender = &eventnotify.HTTP{URL: closed.URL}
+	if err := sender.Send(ctx, eventnotify.Message{"subject", "body"}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func serveSMTP(t *testing.T, mails chan<- string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				c := textproto.NewConn(conn)
+				_ = c.PrintfLine("220 localhost")
+				for {
+					line, err := c.ReadLine()
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+					case "EHLO", "HELO", "MAIL", "RCPT":
+						_ = c.PrintfLine("250 OK")
+					case "DATA":
+						_ = c.PrintfLine("354 Go ahead")
+						data, err := c.ReadDotBytes()
+						if err != nil {
+							return
+						}
+						mails <- string(data)
+						_ = c.PrintfLine("250 OK")
+					case "QUIT":
+						_ = c.PrintfLine("221 Bye")
+						return
+					default:
+						_ = c.PrintfLine("502 Unknown command")
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestSMTP(t *testing.T) {
+	mails := make(chan string, 1)
+	sender := &eventnotify.SMTP{
+		Addr: serveSMTP(t, mails),
+		From: "from@example.com",
+		To:   []string{"to1@example.com", "to2@example.com"},
+	}
+	if err := sender.Send(context.Background(), eventnotify.Message{"Hello\nworld", "line1\nline2\n"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := "From: from@example.com\nTo: to1@example.com, to2@example.com\nSubject: Hello world\n" +
+		"MIME-Version: 1.0\nContent-Type: text/plain; charset=UTF-8\n\nline1\nline2\n"
+	if got := <-mails; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	ctx := context.Background()
+	var payloads []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("got error: %v", err)
+		}
+		payloads = append(payloads, payload)
+		if len(payloads) == 1 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+	sender := &eventnotify.Slack{URL: server.URL}
+	if err := sender.Send(ctx, eventnotify.Message{"subject", "body"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	sender.Format = func(msg eventnotify.Message) interface{} {
+		return map[string]string{"text": msg.Body, "username": msg.Subject}
+	}
+	if err := sender.Send(ctx, eventnotify.Message{"subject", "body"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := []map[string]string{
+		{"text": "*subject*\nbody"},
+		{"text": "*subject*\nbody"},
+		{"text": "body", "username": "subject"},
+	}
+	if !reflect.DeepEqual(payloads, expected) {
+		t.Errorf("expected %v, got %v", expected, payloads)
+	}
+}
+
+func TestDiscord(t *testing.T) {
+	ctx := context.Background()
+	var payloads []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("got error: %v", err)
+		}
+		payloads = append(payloads, payload)
+		w.Header().Set("Retry-After", "0.001")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	sender := &eventnotify.Discord{URL: server.URL}
+	err := sender.Send(ctx, eventnotify.Message{"subject", strings.Repeat("x", 3000)})
+	var serr *eventnotify.StatusError
+	if !errors.As(err, &serr) || serr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected a status error, got %v", err)
+	}
+	if got, expected := len(payloads), 4; got != expected {
+		t.Fatalf("expected %d requests, got %d", expected, got)
+	}
+	content := []rune(payloads[0]["content"])
+	if got, expected := len(content), 2000; got != expected {
+		t.Errorf("expected content length %d, got %d", expected, got)
+	}
+	if !strings.HasPrefix(string(content), "**subject**\nxxx") || content[len(content)-1] != '…' {
+		t.Errorf("unexpected content: %q", string(content))
+	}
+	sender.Format = func(eventnotify.Message) interface{} {
+		return make(chan int)
+	}
+	if err := sender.Send(ctx, eventnotify.Message{"subject", "body"}); err == nil {
+		t.Errorf("expected an error")
+	}
+	if got, expected := len(payloads), 4; got != expected {
+		t.Errorf("expected %d requests, got %d", expected, got)
+	}
+}
+
+func TestChatRateLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	go func() {
+		clock.WaitTimers(1)
+		clock.Advance(time.Second - time.Millisecond)
+		if got, expected := clock.Timers(), 1; got != expected {
+			t.Errorf("expected %d timers, got %d", expected, got)
+		}
+		clock.Advance(time.Millisecond)
+		clock.WaitTimers(1)
+		cancel()
+	}()
+	sender := &eventnotify.Slack{URL: server.URL, Clock: clock}
+	if err, expected := sender.Send(ctx, eventnotify.Message{"subject", "body"}), context.Canceled; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	var count int
+	sender := eventnotify.Retry(eventnotify.SenderFunc(func(context.Context, eventnotify.Message) error {
+		if count++; count < 3 {
+			return errors.New("send error")
+		}
+		return nil
+	}), 3, time.Millisecond)
+	if err := sender.Send(ctx, eventnotify.Message{}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	count = -10
+	if err, expected := sender.Send(ctx, eventnotify.Message{}), "send error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := -7; count != expected {
+		t.Errorf("expected count %d, got %d", expected, count)
+	}
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	count = 0
+	sender = eventnotify.Retry(eventnotify.SenderFunc(func(context.Context, eventnotify.Message) error {
+		if count++; count < 2 {
+			return errors.New("send error")
+		}
+		return nil
+	}), 3, time.Hour, eventnotify.RetryClock(clock))
+	go func() {
+		clock.WaitTimers(1)
+		clock.Advance(2 * time.Hour)
+	}()
+	if err := sender.Send(ctx, eventnotify.Message{}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	sender = eventnotify.Retry(eventnotify.SenderFunc(func(context.Context, eventnotify.Message) error {
+		cancel()
+		return errors.New("send error")
+	}), 3, time.Hour)
+	if err, expected := sender.Send(cctx, eventnotify.Message{}), "send error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	ctx := context.Background()
+	mails := make(chan string, 1)
+	smtp := &eventnotify.SMTP{Addr: serveSMTP(t, mails), From: "from@example.com", To: []string{"to@example.com"}}
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	digest := eventnotify.NewDigest(smtp, "Digest", time.Minute).ErrorHandler(func(err error) {
+		t.Errorf("got error: %v", err)
+	}).Clock(clock)
+	pub := event.NewMapping().
+		On(eventTypeCreated, eventnotify.Must(eventnotify.New(digest, "Created {{.ID}}", "{{.Name}} is created.\n"))).
+		On(eventTypeUpdated, eventnotify.Must(eventnotify.New(digest, "Updated {{.ID}}", "{{.Name}} is updated.")))
+	for _, ev := range []event.Event{eventCreated{1, "foo"}, eventUpdated{1, "bar"}, eventCreated{2, "baz"}} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	clock.Advance(time.Minute)
+	expected := "From: from@example.com\nTo: to@example.com\nSubject: Digest\n" +
+		"MIME-Version: 1.0\nContent-Type: text/plain; charset=UTF-8\n\n" +
+		"Created 1\nfoo is created.\n\nUpdated 1\nbar is updated.\n\nCreated 2\nbaz is created.\n"
+	if got := <-mails; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if err := digest.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestDigestError(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var errs []error
+	digest := eventnotify.NewDigest(eventnotify.SenderFunc(func(context.Context, eventnotify.Message) error {
+		return errors.New("send error")
+	}), "Digest", time.Minute).ErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}).Clock(clock)
+	if err := digest.Send(ctx, eventnotify.Message{"subject", "body"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	if expected := "send error"; len(errs) != 1 || errs[0].Error() != expected {
+		t.Errorf("expected %q, got %v", expected, errs)
+	}
+	digest = eventnotify.NewDigest(digest, "Digest", time.Hour)
+	if err := digest.Send(ctx, eventnotify.Message{"subject", "body"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := digest.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package eventnotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP is a sender to post the messages in JSON to the URL, like a generic
+// webhook endpoint.
+type HTTP struct {
+	URL    string
+	Header http.Header
+	// The client to send the requests. The nil client means
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// Send implements Sender for HTTP.
+func (s *HTTP) Send(ctx context.Context, msg Message) error {
+	return post(ctx, s.Client, s.URL, s.Header, msg)
+}
+
+func post(ctx context.Context, client *http.Client, url string, header http.Header, payload interface{}) error {
+	bs, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bs))
+	if err != nil {
+		return err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode >= http.StatusBadRequest {
+		err := &StatusError{URL: url, StatusCode: res.StatusCode}
+		if secs, e := strconv.ParseFloat(res.Header.Get("Retry-After"), 64); e == nil && secs >= 0 {
+			err.RetryAfter = time.Duration(secs * float64(time.Second))
+		}
+		return err
+	}
+	return nil
+}
+
+// StatusError is the error on the unsuccessful response status code.
+// RetryAfter is the duration of the Retry-After header, if any.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+// Error implements error for StatusError.
+func (err *StatusError) Error() string {
+	return "unexpected status code " + strconv.Itoa(err.StatusCode) + ": " + err.URL
+}
+
+// SMTP is a sender to send the messages as plain text emails.
+type SMTP struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+// Send implements Sender for SMTP. Note that the context is not respected
+// since net/smtp does not support it.
+func (s *SMTP) Send(_ context.Context, msg Message) error {
+	var b strings.Builder
+	b.WriteString("From: " + s.From + "\r\n")
+	b.WriteString("To: " + strings.Join(s.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + strings.Join(strings.Fields(msg.Subject), " ") + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return smtp.SendMail(s.Addr, s.Auth, s.From, s.To, []byte(b.String()))
+}
@@ -0,0 +1,433 @@
+// Package eventqueue provides a durable queue publisher backed by a file, for
+// the applications which need the durability of the events without a broker.
+package eventqueue
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+// Codec is the pair of functions to serialize the events into the file.
+type Codec struct {
+	Encode func(event.Event) ([]byte, error)
+	Decode func([]byte) (event.Event, error)
+}
+
+// Queue is an event publisher to append the events to a file, and deliver them
+// to the subscriber in background. The events are delivered at least once and
+// in order; the events failed to be handled are retried, and the undelivered
+// events are delivered after reopening the queue. The events expired by
+// event.Expirer are dropped before delivering, so the codec should keep the
+// expiry of the events.
+type Queue struct {
+	subscriber  event.Subscriber
+	codec       Codec
+	retry       time.Duration
+	maxAttempts int
+	compact     int64
+	sink        func(context.Context, *event.DeadLetterRecord) error
+	onError     func(error)
+	group       *event.Group
+	clock       event.Clock
+	mu          sync.Mutex
+	path        string
+	log         *os.File
+	offsetFile  *os.File
+	size        int64
+	offset      int64
+	notify      chan struct{}
+	cancel      context.CancelFunc
+	done        chan struct{}
+	closed      bool
+}
+
+// Option is an option for Open.
+type Option func(*Queue)
+
+// RetryInterval sets the interval to retry the events failed to be handled.
+// The default interval is one second.
+func RetryInterval(d time.Duration) Option {
+	return func(q *Queue) { q.retry = d }
+}
+
+// MaxAttempts sets the max number of attempts to deliver an event, and the
+// sink to pass the event failed in all the attempts, like event.DeadLetter.
+// The event is skipped after passed to the sink, or skipped without the sink
+// when the sink is nil, and DeadLetterError is reported to the ErrorHandler.
+// The Event of the record is nil when the record fails to be decoded. The
+// events are retried forever by default.
+func MaxAttempts(n int, sink func(context.Context, *event.DeadLetterRecord) error) Option {
+	return func(q *Queue) { q.maxAttempts, q.sink = n, sink }
+}
+
+// CompactThreshold sets the size of the delivered events to compact the file,
+// by moving the undelivered events to a new file, so that the file does not
+// grow forever while the events are constantly queued. The file is truncated
+// whenever all the events are delivered. The default threshold is 1 MiB.
+func CompactThreshold(size int64) Option {
+	return func(q *Queue) { q.compact = size }
+}
+
+// ErrorHandler sets the function to report the errors on delivering the
+// events. The errors are ignored by default.
+func ErrorHandler(f func(error)) Option {
+	return func(q *Queue) { q.onError = f }
+}
+
+// WithGroup sets the group to own the goroutine delivering the events. The
+// delivering stops when the context of the group is done, and the undelivered
+// events are delivered after reopening the queue.
+func WithGroup(g *event.Group) Option {
+	return func(q *Queue) { q.group = g }
+}
+
+// WithClock sets the clock of the retry interval and the times of the attempts
+// instead of the global clock set by event.SetClock.
+func WithClock(c event.Clock) Option {
+	return func(q *Queue) { q.clock = c }
+}
+
+// ErrClosed is the error returned on publishing to a closed queue.
+var ErrClosed = errors.New("eventqueue: queue closed")
+
+// DeadLetterError is the error reported on skipping an event failed in all the
+// attempts configured by MaxAttempts.
+type DeadLetterError struct {
+	Record *event.DeadLetterRecord
+}
+
+// Error implements error for DeadLetterError.
+func (err *DeadLetterError) Error() string {
+	return "eventqueue: dead-lettered after " + strconv.Itoa(len(err.Record.Attempts)) +
+		" attempts: " + err.Record.Err().Error()
+}
+
+// Unwrap returns the error of the last attempt.
+func (err *DeadLetterError) Unwrap() error {
+	return err.Record.Err()
+}
+
+const headerSize = 4
+
+// Open opens the queue file, and starts delivering the events to the
+// subscriber. The delivery offset is stored in the file with ".offset"
+// suffix. Call Close on shutdown to stop delivering.
+func Open(path string, sub event.Subscriber, codec Codec, opts ...Option) (*Queue, error) {
+	log, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	offsetFile, err := os.OpenFile(path+".offset", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		log.Close()
+		return nil, err
+	}
+	q := &Queue{
+		subscriber: event.SkipExpired(sub), codec: codec, retry: time.Second,
+		compact: 1 << 20, path: path, log: log, offsetFile: offsetFile,
+		notify: make(chan struct{}, 1), done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if err := q.recover(); err != nil {
+		log.Close()
+		offsetFile.Close()
+		return nil, err
+	}
+	if q.group == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		q.cancel = cancel
+		go q.deliver(ctx)
+	} else {
+		ctx, cancel := context.WithCancel(q.group.Context())
+		q.cancel = cancel
+		q.group.Go(func() error {
+			q.deliver(ctx)
+			return nil
+		})
+	}
+	return q, nil
+}
+
+// recover the size and the offset, truncating the partially written record.
+func (q *Queue) recover() error {
+	var b [8]byte
+	if _, err := q.offsetFile.ReadAt(b[:], 0); err == nil {
+		q.offset = int64(binary.BigEndian.Uint64(b[:]))
+	} else if err != io.EOF {
+		return err
+	}
+	fi, err := q.log.Stat()
+	if err != nil {
+		return err
+	}
+	var h [headerSize]byte
+	for q.size+headerSize <= fi.Size() {
+		if _, err := q.log.ReadAt(h[:], q.size); err != nil {
+			return err
+		}
+		next := q.size + headerSize + int64(binary.BigEndian.Uint32(h[:]))
+		if next > fi.Size() {
+			break
+		}
+		q.size = next
+	}
+	if q.size < fi.Size() {
+		if err := q.log.Truncate(q.size); err != nil {
+			return err
+		}
+	}
+	if q.offset > q.size {
+		q.offset = q.size
+	}
+	return nil
+}
+
+// Handle implements Subscriber for Queue.
+func (q *Queue) Handle(ctx context.Context, ev event.Event) error {
+	return q.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Queue. This method returns after the event
+// is written and synced to the file.
+func (q *Queue) Publish(_ context.Context, ev event.Event) error {
+	bs, err := q.codec.Encode(ev)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, headerSize+len(bs))
+	binary.BigEndian.PutUint32(buf, uint32(len(bs)))
+	copy(buf[headerSize:], bs)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if _, err := q.log.Write(buf); err != nil {
+		_ = q.log.Truncate(q.size)
+		return err
+	}
+	if err := q.log.Sync(); err != nil {
+		_ = q.log.Truncate(q.size)
+		return err
+	}
+	q.size += int64(len(buf))
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close stops delivering the events and closes the files. The event being
+// handled is canceled by the context, and delivered again after reopening.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.mu.Unlock()
+	q.cancel()
+	<-q.done
+	err := q.log.Close()
+	if e := q.offsetFile.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (q *Queue) deliver(ctx context.Context) {
+	defer close(q.done)
+	var attempts []event.DeadLetterAttempt
+	for {
+		bs, next, err := q.next()
+		if err != nil {
+			q.report(err)
+			if !q.sleep(ctx, q.retry) {
+				return
+			}
+			continue
+		}
+		if bs == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.notify:
+			}
+			continue
+		}
+		ev, err := q.codec.Decode(bs)
+		if err != nil {
+			ev = nil
+		} else {
+			err = q.subscriber.Handle(ctx, ev)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			q.report(err)
+			attempts = append(attempts, event.DeadLetterAttempt{Time: clockOr(q.clock).Now(), Err: err})
+			if q.maxAttempts <= 0 || len(attempts) < q.maxAttempts {
+				if !q.sleep(ctx, q.retry) {
+					return
+				}
+				continue
+			}
+			if err := q.deadLetter(ctx, ev, attempts); err != nil {
+				q.report(err)
+				if !q.sleep(ctx, q.retry) {
+					return
+				}
+				continue
+			}
+		}
+		attempts = nil
+		if err := q.commit(next); err != nil {
+			q.report(err)
+		}
+	}
+}
+
+// deadLetter passes the event to the sink, and reports DeadLetterError.
+func (q *Queue) deadLetter(ctx context.Context, ev event.Event, attempts []event.DeadLetterAttempt) error {
+	r := &event.DeadLetterRecord{
+		Event: ev, Subscriber: q.path, Attempts: attempts,
+		Disposition: event.DispositionDeadLettered,
+	}
+	if q.sink != nil {
+		if err := q.sink(ctx, r); err != nil {
+			return err
+		}
+	}
+	q.report(&DeadLetterError{r})
+	return nil
+}
+
+// next reads the next record, or returns nil if there is no record.
+func (q *Queue) next() ([]byte, int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.offset >= q.size {
+		return nil, 0, nil
+	}
+	var h [headerSize]byte
+	if _, err := q.log.ReadAt(h[:], q.offset); err != nil {
+		return nil, 0, err
+	}
+	bs := make([]byte, binary.BigEndian.Uint32(h[:]))
+	if _, err := q.log.ReadAt(bs, q.offset+headerSize); err != nil {
+		return nil, 0, err
+	}
+	return bs, q.offset + headerSize + int64(len(bs)), nil
+}
+
+// commit the offset, truncating the file when all the events are delivered,
+// or compacting the file when the offset passes the threshold. The offset is
+// reset before replacing the file, so that the events are delivered again
+// instead of lost on crashes.
+func (q *Queue) commit(offset int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.offset = offset
+	// The file is truncated or compacted again on the next commit on failures.
+	if q.offset == q.size {
+		if err := q.writeOffset(0); err != nil {
+			return err
+		}
+		if q.log.Truncate(0) == nil {
+			q.offset, q.size = 0, 0
+			return nil
+		}
+	} else if q.compact > 0 && q.offset >= q.compact {
+		err := q.compactLog()
+		if err == nil {
+			return nil
+		}
+		if e := q.writeOffset(q.offset); e != nil {
+			return e
+		}
+		return err
+	}
+	return q.writeOffset(q.offset)
+}
+
+// compactLog moves the undelivered events to a new file, and replaces the file.
+func (q *Queue) compactLog() error {
+	f, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, io.NewSectionReader(q.log, q.offset, q.size-q.offset))
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	log, err := os.OpenFile(f.Name(), os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if err = q.writeOffset(0); err == nil {
+		err = os.Rename(f.Name(), q.path)
+	}
+	if err != nil {
+		log.Close()
+		return err
+	}
+	_ = q.log.Close()
+	q.log, q.offset, q.size = log, 0, q.size-q.offset
+	return nil
+}
+
+// writeOffset writes the offset to the file, and syncs the file.
+func (q *Queue) writeOffset(offset int64) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(offset))
+	if _, err := q.offsetFile.WriteAt(b[:], 0); err != nil {
+		return err
+	}
+	return q.offsetFile.Sync()
+}
+
+func (q *Queue) report(err error) {
+	if q.onError != nil {
+		q.onError(err)
+	}
+}
+
+func (q *Queue) sleep(ctx context.Context, d time.Duration) bool {
+	timer := clockOr(q.clock).NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
+
+// clockOr returns the clock, or the global clock if nil.
+func clockOr(c event.Clock) event.Clock {
+	if c != nil {
+		return c
+	}
+	return event.CurrentClock()
+}
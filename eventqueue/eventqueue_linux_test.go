@@ -0,0 +1,73 @@
+package eventqueue_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventqueue"
+)
+
+func TestQueueFileError(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name   string
+		device string
+		err    error
+	}{
+		{"write", "/dev/full", syscall.ENOSPC},
+		{"sync", "/dev/null", syscall.EINVAL},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "queue")
+			if err := os.Symlink(tc.device, path); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			q, err := eventqueue.Open(path, &received{}, codec)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			defer q.Close()
+			if err := q.Publish(ctx, eventCreated(1)); !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestQueueOffsetError(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue")
+	if err := syscall.Mkfifo(path+".offset", 0o644); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := eventqueue.Open(path, &received{}, codec); !errors.Is(err, syscall.ESPIPE) {
+		t.Errorf("expected %v, got %v", syscall.ESPIPE, err)
+	}
+	path = filepath.Join(t.TempDir(), "queue")
+	if err := os.Symlink("/dev/full", path+".offset"); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	errs := make(chan error, 1)
+	sub := &received{}
+	q, err := eventqueue.Open(path, sub, codec, eventqueue.ErrorHandler(func(err error) { errs <- err }))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer q.Close()
+	if err := q.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := <-errs; !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("expected %v, got %v", syscall.ENOSPC, err)
+	}
+	if got, expected := sub.wait(t, 1), []event.Event{eventCreated(1)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,568 @@
+package eventqueue_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventqueue"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+const eventTypeCreated event.Type = iota
+
+type eventCreated int
+
+func (eventCreated) Type() event.Type {
+	return eventTypeCreated
+}
+
+var codec = eventqueue.Codec{
+	Encode: func(ev event.Event) ([]byte, error) {
+		if ev.(eventCreated) < 0 {
+			return nil, errors.New("encode error")
+		}
+		return []byte(strconv.Itoa(int(ev.(eventCreated)))), nil
+	},
+	Decode: func(bs []byte) (event.Event, error) {
+		i, err := strconv.Atoi(string(bs))
+		return eventCreated(i), err
+	},
+}
+
+type received struct {
+	mu     sync.Mutex
+	events []event.Event
+	fail   bool
+}
+
+func (r *received) Handle(_ context.Context, ev event.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("handle error")
+	}
+	r.events = append(r.events, ev)
+	return nil
+}
+
+func (r *received) wait(t *testing.T, n int) []event.Event {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		r.mu.Lock()
+		evs := r.events
+		r.mu.Unlock()
+		if len(evs) >= n {
+			return evs
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d events", n)
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue")
+	sub := &received{}
+	q, err := eventqueue.Open(path, sub, codec)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	evs := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)}
+	for _, ev := range evs {
+		if err := q.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if got := sub.wait(t, 3); !reflect.DeepEqual(got, evs) {
+		t.Errorf("handled events: expected %v, got %v", evs, got)
+	}
+	if err, expected := q.Handle(ctx, eventCreated(-1)), "encode error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := q.Close(); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err, expected := q.Publish(ctx, eventCreated(4)), eventqueue.ErrClosed; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("expected the queue file truncated, got %v, %v", fi.Size(), err)
+	}
+}
+
+func TestQueueReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue")
+	var errs int
+	var mu sync.Mutex
+	sub := &received{fail: true}
+	q, err := eventqueue.Open(path, sub, codec,
+		eventqueue.RetryInterval(time.Millisecond),
+		eventqueue.ErrorHandler(func(error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs++
+		}))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	evs := []event.Event{eventCreated(1), eventCreated(2)}
+	for _, ev := range evs {
+		if err := q.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := q.Close(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	mu.Lock()
+	if errs == 0 {
+		t.Errorf("expected errors reported")
+	}
+	mu.Unlock()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 10, '3'}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	f.Close()
+	sub = &received{}
+	q, err = eventqueue.Open(path, sub, codec)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer q.Close()
+	if err := q.Publish(ctx, eventCreated(4)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := sub.wait(t, 3), append(evs, eventCreated(4)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+}
+
+func TestQueueOpenError(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := eventqueue.Open(filepath.Join(dir, "missing", "queue"), &received{}, codec); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+	path := filepath.Join(dir, "queue")
+	if err := os.Mkdir(path+".offset", 0o755); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := eventqueue.Open(path, &received{}, codec); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestQueueOffset(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue")
+	if err := os.WriteFile(path+".offset", []byte{0, 0, 0, 0, 0, 0, 0, 100}, 0o644); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	sub := &received{}
+	q, err := eventqueue.Open(path, sub, codec)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer q.Close()
+	if err := q.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := sub.wait(t, 1), []event.Event{eventCreated(1)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+}
+
+func TestQueueCorrupted(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue")
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	handling, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var errs []error
+	q, err := eventqueue.Open(path, event.Func(func(context.Context, event.Event) error {
+		close(handling)
+		<-release
+		return nil
+	}), codec,
+		eventqueue.RetryInterval(time.Minute),
+		eventqueue.WithClock(clock),
+		eventqueue.ErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2)} {
+		if err := q.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	<-handling
+	if err := os.Truncate(path, 9); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	close(release)
+	clock.WaitTimers(1)
+	if err := os.Truncate(path, 7); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	clock.WaitTimers(1)
+	if err := q.Close(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(errs) != 2 || errs[0] != io.EOF || errs[1] != io.EOF {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue")
+	handling := make(chan struct{})
+	q, err := eventqueue.Open(path, event.Func(func(ctx context.Context, _ event.Event) error {
+		close(handling)
+		<-ctx.Done()
+		return ctx.Err()
+	}), codec)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := q.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	<-handling
+	if err := q.Close(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	sub := &received{}
+	q, err = eventqueue.Open(path, sub, codec)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer q.Close()
+	if got, expected := sub.wait(t, 1), []event.Event{eventCreated(1)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+}
+
+func TestQueueCompact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue")
+	started, handling := make(chan struct{}), make(chan struct{})
+	q, err := eventqueue.Open(path, event.Func(func(ctx context.Context, ev event.Event) error {
+		switch ev {
+		case eventCreated(1):
+			<-started
+		case eventCreated(3):
+			close(handling)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}), codec, eventqueue.CompactThreshold(5))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)} {
+		if err := q.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	close(started)
+	<-handling
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 5 {
+		t.Errorf("expected the queue file compacted, got %v, %v", fi.Size(), err)
+	}
+	if bs, err := os.ReadFile(path + ".offset"); err != nil || !reflect.DeepEqual(bs, make([]byte, 8)) {
+		t.Errorf("expected the offset reset, got %v, %v", bs, err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	sub := &received{}
+	q, err = eventqueue.Open(path, sub, codec)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer q.Close()
+	if got, expected := sub.wait(t, 1), []event.Event{eventCreated(3)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+	if entries, err := os.ReadDir(filepath.Dir(path)); err != nil || len(entries) != 2 {
+		t.Errorf("expected no temporary files, got %v, %v", entries, err)
+	}
+}
+
+func TestQueueSinkError(t *testing.T) {
+	ctx := context.Background()
+	sunk := make(chan struct{})
+	q, err := eventqueue.Open(filepath.Join(t.TempDir(), "queue"), &received{fail: true}, codec,
+		eventqueue.RetryInterval(time.Hour),
+		eventqueue.MaxAttempts(1, func(context.Context, *event.DeadLetterRecord) error {
+			close(sunk)
+			return errors.New("sink error")
+		}))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := q.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	<-sunk
+	if err := q.Close(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestQueueMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue")
+	if err := os.WriteFile(path, []byte{0, 0, 0, 1, 'x'}, 0o644); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	sub := &received{}
+	handle := event.Func(func(ctx context.Context, ev event.Event) error {
+		if ev == eventCreated(2) {
+			return errors.New("handle error")
+		}
+		return sub.Handle(ctx, ev)
+	})
+	var mu sync.Mutex
+	var records []*event.DeadLetterRecord
+	var errs []error
+	sinkErr := errors.New("sink error")
+	q, err := eventqueue.Open(path, handle, codec,
+		eventqueue.RetryInterval(time.Millisecond),
+		eventqueue.MaxAttempts(2, func(_ context.Context, r *event.DeadLetterRecord) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if err := sinkErr; err != nil {
+				sinkErr = nil
+				return err
+			}
+			records = append(records, r)
+			return nil
+		}),
+		eventqueue.ErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)} {
+		if err := q.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if got, expected := sub.wait(t, 2), []event.Event{eventCreated(1), eventCreated(3)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %v", records)
+	}
+	if r := records[0]; r.Event != nil || len(r.Attempts) != 3 || r.Subscriber != path ||
+		r.Err().Error() != `strconv.Atoi: parsing "x": invalid syntax` {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r := records[1]; r.Event != eventCreated(2) || len(r.Attempts) != 2 ||
+		r.Disposition != event.DispositionDeadLettered {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	var dlerrs []string
+	for _, err := range errs {
+		var dlerr *eventqueue.DeadLetterError
+		if errors.As(err, &dlerr) {
+			dlerrs = append(dlerrs, err.Error())
+		}
+	}
+	expected := []string{
+		`eventqueue: dead-lettered after 3 attempts: strconv.Atoi: parsing "x": invalid syntax`,
+		"eventqueue: dead-lettered after 2 attempts: handle error",
+	}
+	if !reflect.DeepEqual(dlerrs, expected) {
+		t.Errorf("expected %v, got %v", expected, dlerrs)
+	}
+	if !errors.Is(errs[len(errs)-1], errs[len(errs)-2]) {
+		t.Errorf("expected the error of the last attempt unwrapped, got %v", errs)
+	}
+	q, err = eventqueue.Open(path, handle, codec, eventqueue.MaxAttempts(1, nil))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer q.Close()
+	for _, ev := range []event.Event{eventCreated(2), eventCreated(4)} {
+		if err := q.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if got, expected := sub.wait(t, 3), []event.Event{eventCreated(1), eventCreated(3), eventCreated(4)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+}
+
+func TestQueueClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	sub := &received{fail: true}
+	q, err := eventqueue.Open(filepath.Join(t.TempDir(), "queue"), sub, codec,
+		eventqueue.RetryInterval(time.Minute), eventqueue.WithClock(clock))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer q.Close()
+	if err := q.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.WaitTimers(1)
+	sub.mu.Lock()
+	sub.fail = false
+	sub.mu.Unlock()
+	clock.Advance(time.Minute)
+	if got, expected := sub.wait(t, 1), []event.Event{eventCreated(1)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+}
+
+func TestSpill(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	spill, err := eventqueue.NewSpill(dir, codec)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	block := make(chan struct{})
+	sub := &received{}
+	pub := event.NewLanes(event.Func(func(ctx context.Context, ev event.Event) error {
+		<-block
+		return sub.Handle(ctx, ev)
+	})).Lane("default", 2, 1).Spill("default", spill)
+	var evs []event.Event
+	for i := 0; i < 10; i++ {
+		evs = append(evs, eventCreated(i))
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if got, expected := pub.Len("default"), 9; got < expected {
+		t.Errorf("expected at least %d queued events, got %d", expected, got)
+	}
+	if fis, err := os.ReadDir(dir); err != nil || len(fis) != 1 {
+		t.Fatalf("expected a spill file, got %v, %v", fis, err)
+	}
+	close(block)
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := sub.wait(t, 10); !reflect.DeepEqual(got, evs) {
+		t.Errorf("handled events: expected %v, got %v", evs, got)
+	}
+	if err := spill.Close(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if fis, err := os.ReadDir(dir); err != nil || len(fis) != 0 {
+		t.Errorf("expected the spill file removed, got %v, %v", fis, err)
+	}
+}
+
+func TestQueueGroup(t *testing.T) {
+	g, ctx := event.NewGroup(context.Background())
+	path := filepath.Join(t.TempDir(), "queue")
+	sub := &received{}
+	q, err := eventqueue.Open(path, sub, codec, eventqueue.WithGroup(g))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer q.Close()
+	if err := q.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := sub.wait(t, 1), []event.Event{eventCreated(1)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, got)
+	}
+	g.Go(func() error { return errors.New("group error") })
+	if err, expected := g.Wait(), "group error"; err == nil || err.Error() != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestSpillError(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := eventqueue.NewSpill(filepath.Join(dir, "missing"), codec); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+	spill, err := eventqueue.NewSpill(dir, eventqueue.Codec{
+		Encode: func(ev event.Event) ([]byte, error) {
+			if ev == eventCreated(0) {
+				return []byte("x"), nil
+			}
+			return codec.Encode(ev)
+		},
+		Decode: codec.Decode,
+	})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := spill.Push(eventCreated(-1)), "encode error"; err == nil || err.Error() != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if ev, ok, err := spill.Pop(); ev != nil || ok || err != nil {
+		t.Errorf("expected no events, got %v, %v, %v", ev, ok, err)
+	}
+	if err := spill.Push(eventCreated(0)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, _, err := spill.Pop(); err == nil {
+		t.Errorf("expected a decode error")
+	}
+	fis, err := os.ReadDir(dir)
+	if err != nil || len(fis) != 1 {
+		t.Fatalf("expected a spill file, got %v, %v", fis, err)
+	}
+	name := filepath.Join(dir, fis[0].Name())
+	if err := spill.Push(eventCreated(10)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := os.Truncate(name, 5); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, _, err := spill.Pop(); err != io.EOF {
+		t.Errorf("expected %v, got %v", io.EOF, err)
+	}
+	if err := os.Remove(name); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := spill.Close(); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+	if err := spill.Push(eventCreated(1)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected %v, got %v", os.ErrClosed, err)
+	}
+	if _, _, err := spill.Pop(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected %v, got %v", os.ErrClosed, err)
+	}
+}
@@ -0,0 +1,80 @@
+package eventqueue
+
+import (
+	"encoding/binary"
+	"os"
+
+	"github.com/itchyny/event-go"
+)
+
+// Spill is an overflow storage of event.Lanes backed by a temporary file. The
+// file is truncated whenever all the events are popped, and removed on Close.
+// Unlike Queue, the events are not synced to the file, and are lost on the
+// restart of the process.
+type Spill struct {
+	codec  Codec
+	file   *os.File
+	size   int64
+	offset int64
+}
+
+// NewSpill creates a temporary file in the directory, which is the default
+// directory for temporary files when empty.
+func NewSpill(dir string, codec Codec) (*Spill, error) {
+	file, err := os.CreateTemp(dir, "eventqueue-spill-")
+	if err != nil {
+		return nil, err
+	}
+	return &Spill{codec: codec, file: file}, nil
+}
+
+// Push implements event.Spill for Spill.
+func (s *Spill) Push(ev event.Event) error {
+	bs, err := s.codec.Encode(ev)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, headerSize+len(bs))
+	binary.BigEndian.PutUint32(buf, uint32(len(bs)))
+	copy(buf[headerSize:], bs)
+	if _, err := s.file.WriteAt(buf, s.size); err != nil {
+		return err
+	}
+	s.size += int64(len(buf))
+	return nil
+}
+
+// Pop implements event.Spill for Spill.
+func (s *Spill) Pop() (event.Event, bool, error) {
+	if s.offset >= s.size {
+		return nil, false, nil
+	}
+	var h [headerSize]byte
+	if _, err := s.file.ReadAt(h[:], s.offset); err != nil {
+		return nil, false, err
+	}
+	bs := make([]byte, binary.BigEndian.Uint32(h[:]))
+	if _, err := s.file.ReadAt(bs, s.offset+headerSize); err != nil {
+		return nil, false, err
+	}
+	if s.offset += headerSize + int64(len(bs)); s.offset == s.size {
+		// The events are written at the offsets, so the file is reused from
+		// the start even if truncating the file fails.
+		_ = s.file.Truncate(0)
+		s.offset, s.size = 0, 0
+	}
+	ev, err := s.codec.Decode(bs)
+	if err != nil {
+		return nil, false, err
+	}
+	return ev, true, nil
+}
+
+// Close closes and removes the file.
+func (s *Spill) Close() error {
+	err := s.file.Close()
+	if e := os.Remove(s.file.Name()); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,360 @@
+package eventstore_test
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventstore"
+)
+
+func testStore(t *testing.T, s event.Store) {
+	t.Helper()
+	ctx := context.Background()
+	if _, ok, err := s.Load(ctx, "foo"); err != nil || ok {
+		t.Fatalf("expected not found, got %v, %v", ok, err)
+	}
+	for _, value := range []string{"bar", "baz\r\nqux"} {
+		if err := s.Save(ctx, "foo", []byte(value)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if got, ok, err := s.Load(ctx, "foo"); err != nil || !ok || string(got) != value {
+			t.Fatalf("expected %q, got %q, %v, %v", value, got, ok, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Delete(ctx, "foo"); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if _, ok, err := s.Load(ctx, "foo"); err != nil || ok {
+		t.Fatalf("expected not found, got %v, %v", ok, err)
+	}
+}
+
+// redisServer is a fake Redis server supporting AUTH, SELECT, GET, SET, and
+// DEL.
+type redisServer struct {
+	net.Listener
+	password string
+	mu       sync.Mutex
+	values   map[string]string
+	commands []string
+}
+
+func newRedisServer(t *testing.T, password string) *redisServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	s := &redisServer{Listener: l, password: password, values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *redisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args[0])
+		var reply string
+		switch {
+		case args[0] == "AUTH":
+			if authed = args[1] == s.password; authed {
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case args[0] == "GET":
+			if v, ok := s.values[args[1]]; ok {
+				reply = "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+			} else {
+				reply = "$-1\r\n"
+			}
+		case args[0] == "SELECT":
+			reply = "+OK\r\n"
+		case args[0] == "SET":
+			s.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case args[0] == "DEL":
+			_, ok := s.values[args[1]]
+			delete(s.values, args[1])
+			if ok {
+				reply = ":1\r\n"
+			} else {
+				reply = ":0\r\n"
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		m, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		bs := make([]byte, m+2)
+		if _, err := io.ReadFull(r, bs); err != nil {
+			return nil, err
+		}
+		args[i] = string(bs[:m])
+	}
+	return args, nil
+}
+
+func TestRedis(t *testing.T) {
+	server := newRedisServer(t, "secret")
+	defer server.Close()
+	s := &eventstore.Redis{Addr: server.Addr().String(), Password: "secret", DB: 1, Prefix: "app:", Timeout: time.Second}
+	defer s.Close()
+	testStore(t, s)
+	if err := s.Save(context.Background(), "foo", []byte("bar")); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if got, expected := server.values["app:foo"], "bar"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if got, expected := strings.Join(server.commands, " "), "AUTH SELECT GET SET GET SET GET DEL DEL GET SET"; got != expected {
+		t.Errorf("expected commands %q, got %q", expected, got)
+	}
+}
+
+func TestRedisError(t *testing.T) {
+	server := newRedisServer(t, "secret")
+	defer server.Close()
+	s := &eventstore.Redis{Addr: server.Addr().String(), Password: "wrong"}
+	defer s.Close()
+	_, _, err := s.Load(context.Background(), "foo")
+	var rerr *eventstore.RedisError
+	if !errors.As(err, &rerr) || err.Error() != "redis: WRONGPASS invalid password" {
+		t.Fatalf("expected RedisError, got %v", err)
+	}
+	s = &eventstore.Redis{Addr: server.Addr().String(), Password: "secret"}
+	defer s.Close()
+	if err := s.Save(context.Background(), "foo", []byte("bar")); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, _, err := s.Load(ctx, "foo"); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if got, ok, err := s.Load(context.Background(), "foo"); err != nil || !ok || string(got) != "bar" {
+		t.Fatalf("expected %q, got %q, %v, %v", "bar", got, ok, err)
+	}
+	server.Close()
+	s = &eventstore.Redis{Addr: server.Addr().String()}
+	if _, _, err := s.Load(context.Background(), "foo"); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+// replyServer is a fake Redis server replying the raw reply to a command and
+// closing the connection.
+func replyServer(t *testing.T, reply string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if _, err := readCommand(bufio.NewReader(conn)); err != nil {
+					return
+				}
+				_, _ = io.WriteString(conn, reply)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestRedisReply(t *testing.T) {
+	testCases := []struct {
+		reply    string
+		expected string
+	}{
+		{"+OK\r\n", "eventstore: unexpected reply of GET"},
+		{"", "EOF"},
+		{"x\n", `eventstore: invalid reply: "x\n"`},
+		{"$5\r\nab", "unexpected EOF"},
+		{"*1\r\n", `eventstore: unexpected reply: "*1"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			s := &eventstore.Redis{Addr: replyServer(t, tc.reply)}
+			defer s.Close()
+			if _, _, err := s.Load(context.Background(), "foo"); err == nil || err.Error() != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+// sqlDriver is a fake SQL driver supporting the queries of eventstore.SQL.
+type sqlDriver struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	// The function called before inserting, to emulate the concurrent writes.
+	insert func(map[string][]byte) error
+}
+
+func (d *sqlDriver) Open(string) (driver.Conn, error) { return sqlConn{d}, nil }
+
+type sqlConn struct{ d *sqlDriver }
+
+func (c sqlConn) Prepare(query string) (driver.Stmt, error) { return sqlStmt{c.d, query}, nil }
+func (c sqlConn) Close() error                              { return nil }
+func (c sqlConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c sqlConn) Commit() error                             { return nil }
+func (c sqlConn) Rollback() error                           { return nil }
+
+type sqlStmt struct {
+	d     *sqlDriver
+	query string
+}
+
+func (s sqlStmt) Close() error  { return nil }
+func (s sqlStmt) NumInput() int { return strings.Count(s.query, "$") }
+
+func (s sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "INSERT INTO states (name, value) VALUES ($1, $2)"):
+		if s.d.insert != nil {
+			if err := s.d.insert(s.d.values); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := s.d.values[args[0].(string)]; ok {
+			return nil, errors.New("duplicate key")
+		}
+		s.d.values[args[0].(string)] = args[1].([]byte)
+	case strings.HasPrefix(s.query, "UPDATE states SET value = $1 WHERE name = $2"):
+		if _, ok := s.d.values[args[1].(string)]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		s.d.values[args[1].(string)] = args[0].([]byte)
+	case strings.HasPrefix(s.query, "DELETE FROM states WHERE name = $1"):
+		delete(s.d.values, args[0].(string))
+	default:
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	v, ok := s.d.values[args[0].(string)]
+	switch {
+	case strings.HasPrefix(s.query, "SELECT value FROM states WHERE name = $1"):
+		if !ok {
+			return &sqlRows{}, nil
+		}
+		return &sqlRows{[]driver.Value{v}}, nil
+	default:
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+}
+
+type sqlRows struct{ values []driver.Value }
+
+func (r *sqlRows) Columns() []string { return []string{"value"} }
+func (r *sqlRows) Close() error      { return nil }
+
+func (r *sqlRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func TestSQL(t *testing.T) {
+	d := &sqlDriver{values: make(map[string][]byte)}
+	sql.Register("eventstore", d)
+	db, err := sql.Open("eventstore", "")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer db.Close()
+	s := &eventstore.SQL{DB: db, Table: "states", Placeholder: eventstore.Dollar}
+	testStore(t, s)
+	ctx := context.Background()
+	d.insert = func(values map[string][]byte) error {
+		values["foo"] = []byte("concurrent")
+		return nil
+	}
+	if err := s.Save(ctx, "foo", []byte("bar")); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, _, err := s.Load(ctx, "foo"); err != nil || string(got) != "bar" {
+		t.Fatalf("expected %q, got %q, %v", "bar", got, err)
+	}
+	d.insert = func(map[string][]byte) error { return errors.New("insert error") }
+	if err, expected := s.Save(ctx, "qux", nil), "insert error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if _, _, err := (&eventstore.SQL{DB: db, Table: "states"}).Load(ctx, "foo"); err == nil {
+		t.Fatalf("expected an error")
+	}
+	s.Table = "unknown"
+	if err, expected := s.Save(ctx, "foo", nil), "unexpected query: UPDATE unknown SET value = $1 WHERE name = $2"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
@@ -0,0 +1,176 @@
+// Package eventstore provides the implementations of event.Store backed by
+// Redis and the SQL databases.
+package eventstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Redis is a store to keep the values in Redis, speaking the RESP protocol on
+// a single connection. The connection is established on the first operation,
+// and reestablished after the errors.
+type Redis struct {
+	// The address of the server, like "localhost:6379".
+	Addr     string
+	Password string
+	DB       int
+	// The prefix of the keys, to share the database with other applications.
+	Prefix string
+	// The timeout of each operation, in addition to the deadline of the
+	// context. The zero timeout means no timeout.
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Load implements event.Store for Redis.
+func (s *Redis) Load(ctx context.Context, key string) ([]byte, bool, error) {
+	v, err := s.do(ctx, "GET", s.Prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if v == nil {
+		return nil, false, nil
+	}
+	bs, ok := v.([]byte)
+	if !ok {
+		return nil, false, errors.New("eventstore: unexpected reply of GET")
+	}
+	return bs, true, nil
+}
+
+// Save implements event.Store for Redis.
+func (s *Redis) Save(ctx context.Context, key string, value []byte) error {
+	_, err := s.do(ctx, "SET", s.Prefix+key, string(value))
+	return err
+}
+
+// Delete implements event.Store for Redis.
+func (s *Redis) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.Prefix+key)
+	return err
+}
+
+// Close closes the connection.
+func (s *Redis) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.r = nil, nil
+	return err
+}
+
+func (s *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	v, err := s.command(ctx, args...)
+	if err != nil {
+		var rerr *RedisError
+		if !errors.As(err, &rerr) {
+			s.conn.Close()
+			s.conn, s.r = nil, nil
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *Redis) connect(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	s.conn, s.r = conn, bufio.NewReader(conn)
+	if s.Password != "" {
+		_, err = s.command(ctx, "AUTH", s.Password)
+	}
+	if err == nil && s.DB != 0 {
+		_, err = s.command(ctx, "SELECT", strconv.Itoa(s.DB))
+	}
+	if err != nil {
+		conn.Close()
+		s.conn, s.r = nil, nil
+	}
+	return err
+}
+
+func (s *Redis) command(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, _ := ctx.Deadline()
+	if s.Timeout > 0 {
+		if t := time.Now().Add(s.Timeout); deadline.IsZero() || t.Before(deadline) {
+			deadline = t
+		}
+	}
+	// The deadline fails only on the closed connection, on which the write
+	// below fails as well.
+	_ = s.conn.SetDeadline(deadline)
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"+arg+"\r\n"...)
+	}
+	if _, err := s.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return s.reply()
+}
+
+// reply reads a reply of the simple string, the error, the integer, or the
+// bulk string.
+func (s *Redis) reply() (interface{}, error) {
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("eventstore: invalid reply: " + strconv.Quote(line))
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, &RedisError{line[1:]}
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		bs := make([]byte, n+2)
+		if _, err := io.ReadFull(s.r, bs); err != nil {
+			return nil, err
+		}
+		return bs[:n], nil
+	default:
+		return nil, errors.New("eventstore: unexpected reply: " + strconv.Quote(line))
+	}
+}
+
+// RedisError is the error reply of Redis.
+type RedisError struct {
+	Message string
+}
+
+// Error implements error for RedisError.
+func (err *RedisError) Error() string {
+	return "redis: " + err.Message
+}
@@ -0,0 +1,85 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// SQL is a store to keep the values in a table of the SQL database, with the
+// name column of the primary key and the value column of the bytes.
+//
+//	CREATE TABLE states (name VARCHAR(255) PRIMARY KEY, value BLOB NOT NULL)
+type SQL struct {
+	DB    *sql.DB
+	Table string
+	// The function to format the placeholder of the n-th parameter, starting
+	// from 1. The placeholders are "?" by default, use Dollar for PostgreSQL.
+	Placeholder func(int) string
+}
+
+// Dollar formats the placeholders like "$1", for PostgreSQL.
+func Dollar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// Load implements event.Store for SQL.
+func (s *SQL) Load(ctx context.Context, key string) ([]byte, bool, error) {
+	var value []byte
+	err := s.DB.QueryRowContext(ctx,
+		"SELECT value FROM "+s.Table+" WHERE name = "+s.placeholder(1), key,
+	).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Save implements event.Store for SQL. The value is updated, or inserted when
+// the key is not found. When the insertion fails because the key is inserted
+// concurrently, the value is updated again.
+func (s *SQL) Save(ctx context.Context, key string, value []byte) error {
+	if ok, err := s.update(ctx, key, value); ok || err != nil {
+		return err
+	}
+	_, err := s.DB.ExecContext(ctx,
+		"INSERT INTO "+s.Table+" (name, value) VALUES ("+s.placeholder(1)+", "+s.placeholder(2)+")",
+		key, value,
+	)
+	if err != nil {
+		if ok, e := s.update(ctx, key, value); ok && e == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (s *SQL) update(ctx context.Context, key string, value []byte) (bool, error) {
+	res, err := s.DB.ExecContext(ctx,
+		"UPDATE "+s.Table+" SET value = "+s.placeholder(1)+" WHERE name = "+s.placeholder(2),
+		value, key,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Delete implements event.Store for SQL.
+func (s *SQL) Delete(ctx context.Context, key string) error {
+	_, err := s.DB.ExecContext(ctx,
+		"DELETE FROM "+s.Table+" WHERE name = "+s.placeholder(1), key,
+	)
+	return err
+}
+
+func (s *SQL) placeholder(n int) string {
+	if s.Placeholder == nil {
+		return "?"
+	}
+	return s.Placeholder(n)
+}
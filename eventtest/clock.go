@@ -0,0 +1,179 @@
+// Package eventtest provides the utilities to test the event subscribers and
+// publishers.
+package eventtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+// Clock is a manual clock for the tests, which implements event.Clock. The
+// time advances only by Advance, and the timers fire in the order of the
+// times. Inject the clock to the time-dependent components, or set it by
+// event.SetClock, to control them without real sleeps.
+type Clock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*timer
+}
+
+type timer struct {
+	clock  *Clock
+	when   time.Time
+	period time.Duration
+	c      chan time.Time
+	f      func()
+	active bool
+}
+
+// NewClock creates a new manual clock at the time.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now implements event.Clock for Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer implements event.Clock for Clock.
+func (c *Clock) NewTimer(d time.Duration) event.Timer {
+	return c.add(d, 0, nil)
+}
+
+// AfterFunc implements event.Clock for Clock. The function is called
+// synchronously in Advance.
+func (c *Clock) AfterFunc(d time.Duration, f func()) event.Timer {
+	return c.add(d, 0, f)
+}
+
+// NewTicker implements event.Clock for Clock.
+func (c *Clock) NewTicker(d time.Duration) event.Ticker {
+	if d <= 0 {
+		panic("eventtest: non-positive interval for NewTicker")
+	}
+	return ticker{c.add(d, d, nil)}
+}
+
+func (c *Clock) add(d, period time.Duration, f func()) *timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{clock: c, period: period, f: f}
+	if f == nil {
+		t.c = make(chan time.Time, 1)
+	}
+	c.schedule(t, d)
+	return t
+}
+
+func (c *Clock) schedule(t *timer, d time.Duration) {
+	t.when, t.active = c.now.Add(d), true
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+}
+
+// Advance advances the time by the duration, and fires the timers due by the
+// time in order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *timer
+		for _, t := range c.timers {
+			if t.active && !t.when.After(end) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		c.now = next.when
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+		} else {
+			c.remove(next)
+		}
+		if next.f != nil {
+			c.mu.Unlock()
+			next.f()
+			c.mu.Lock()
+		} else {
+			select {
+			case next.c <- c.now:
+			default:
+			}
+		}
+	}
+	if c.now.Before(end) {
+		c.now = end
+	}
+	c.mu.Unlock()
+}
+
+func (c *Clock) remove(t *timer) {
+	t.active = false
+	for i, u := range c.timers {
+		if u == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			break
+		}
+	}
+}
+
+// Timers returns the number of the active timers and tickers.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// WaitTimers blocks until the number of the active timers and tickers gets at
+// least n, which is useful to wait for the goroutines to start their timers
+// before Advance.
+func (c *Clock) WaitTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	if active {
+		t.clock.remove(t)
+	}
+	return active
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	if active {
+		t.clock.remove(t)
+	}
+	t.clock.schedule(t, d)
+	return active
+}
+
+type ticker struct {
+	*timer
+}
+
+func (t ticker) Stop() {
+	t.timer.Stop()
+}
@@ -0,0 +1,74 @@
+package eventtest_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := eventtest.NewClock(start)
+	var fired []string
+	clock.AfterFunc(3*time.Second, func() {
+		fired = append(fired, "func "+clock.Now().Sub(start).String())
+	})
+	timer := clock.NewTimer(2 * time.Second)
+	stopped := clock.NewTimer(time.Second)
+	ticker := clock.NewTicker(time.Second)
+	if got, expected := clock.Timers(), 4; got != expected {
+		t.Fatalf("expected %d timers, got %d", expected, got)
+	}
+	if !stopped.Stop() || stopped.Stop() {
+		t.Errorf("expected the timer to be stopped once")
+	}
+	clock.Advance(1500 * time.Millisecond)
+	if got, expected := <-ticker.C(), start.Add(time.Second); !got.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got, expected := clock.Now(), start.Add(1500*time.Millisecond); !got.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	clock.Advance(2 * time.Second)
+	if got, expected := <-timer.C(), start.Add(2*time.Second); !got.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got, expected := <-ticker.C(), start.Add(2*time.Second); !got.Equal(expected) {
+		t.Errorf("expected the dropped ticks, got %v", got)
+	}
+	if expected := []string{"func 3s"}; !reflect.DeepEqual(fired, expected) {
+		t.Errorf("expected %v, got %v", expected, fired)
+	}
+	if timer.Reset(time.Second) {
+		t.Errorf("expected the timer to be expired")
+	}
+	ticker.Stop()
+	clock.Advance(time.Second)
+	if got, expected := <-timer.C(), start.Add(4500*time.Millisecond); !got.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := clock.Timers(); got != 0 {
+		t.Errorf("expected no timers, got %d", got)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		clock.NewTimer(time.Second)
+	}()
+	clock.WaitTimers(1)
+	timer = clock.NewTimer(time.Second)
+	if !timer.Reset(2 * time.Second) {
+		t.Errorf("expected the timer to be active")
+	}
+	clock.Advance(time.Second)
+	if got, expected := clock.Timers(), 1; got != expected {
+		t.Errorf("expected %d timers, got %d", expected, got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+	clock.NewTicker(0)
+}
@@ -0,0 +1,157 @@
+package eventtest
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+// Fuzzer generates the random events to test the round trips of the codecs,
+// protecting the serialization from the changes of the schemas of the events.
+// The events are generated from the prototypes of the payload types, by
+// filling the exported fields recursively. The interfaces, the channels, and
+// the functions are left zero. The slices and the maps are either nil or
+// non-empty, and the floats are finite, so that the codecs like encoding/json
+// can reproduce the events.
+type Fuzzer struct {
+	seed int64
+	rand *rand.Rand
+}
+
+// NewFuzzer creates a new fuzzer of the seed. Use the seed reported by the
+// failure to reproduce the events.
+func NewFuzzer(seed int64) *Fuzzer {
+	return &Fuzzer{seed: seed, rand: rand.New(rand.NewSource(seed))}
+}
+
+// Event generates a random event of the same payload type as the prototype.
+func (f *Fuzzer) Event(proto event.Event) event.Event {
+	t := reflect.TypeOf(proto)
+	v := reflect.New(t).Elem()
+	f.fill(v, 0)
+	return v.Interface().(event.Event)
+}
+
+const fuzzDepth = 4
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (f *Fuzzer) fill(v reflect.Value, depth int) {
+	if v.Type() == timeType {
+		v.Set(reflect.ValueOf(time.Unix(f.rand.Int63n(1<<33), f.rand.Int63n(1e9)).UTC()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(f.rand.Intn(2) == 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(f.rand.Uint64()) >> (64 - v.Type().Bits()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(f.rand.Uint64() >> (64 - v.Type().Bits()))
+	case reflect.Float32:
+		v.SetFloat(float64(float32(f.float())))
+	case reflect.Float64:
+		v.SetFloat(f.float())
+	case reflect.String:
+		v.SetString(f.string())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			f.fill(v.Index(i), depth+1)
+		}
+	case reflect.Slice:
+		if n := f.length(depth); n > 0 {
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+			for i := 0; i < n; i++ {
+				f.fill(v.Index(i), depth+1)
+			}
+		}
+	case reflect.Map:
+		if n := f.length(depth); n > 0 {
+			v.Set(reflect.MakeMapWithSize(v.Type(), n))
+			for i := 0; i < n; i++ {
+				k, e := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+				f.fill(k, depth+1)
+				f.fill(e, depth+1)
+				v.SetMapIndex(k, e)
+			}
+		}
+	case reflect.Ptr:
+		if depth < fuzzDepth && f.rand.Intn(4) > 0 {
+			v.Set(reflect.New(v.Type().Elem()))
+			f.fill(v.Elem(), depth+1)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				f.fill(v.Field(i), depth+1)
+			}
+		}
+	}
+}
+
+func (f *Fuzzer) length(depth int) int {
+	if depth >= fuzzDepth || f.rand.Intn(4) == 0 {
+		return 0
+	}
+	return 1 + f.rand.Intn(4)
+}
+
+func (f *Fuzzer) float() float64 {
+	switch f.rand.Intn(4) {
+	case 0:
+		return 0
+	case 1:
+		return float64(f.rand.Int63n(1<<20) - 1<<19)
+	default:
+		return f.rand.NormFloat64() * math.Pow(10, float64(f.rand.Intn(20)-10))
+	}
+}
+
+var fuzzRunes = []rune("aZ09 _-.\"\\/\n\t\x00<>&é日本語🎉")
+
+func (f *Fuzzer) string() string {
+	rs := make([]rune, f.rand.Intn(16))
+	for i := range rs {
+		if f.rand.Intn(2) == 0 {
+			rs[i] = fuzzRunes[f.rand.Intn(len(fuzzRunes))]
+		} else {
+			rs[i] = rune(0x20 + f.rand.Intn(0x5f))
+		}
+	}
+	return string(rs)
+}
+
+// RoundTrip generates n random events of each prototype, and reports a failure
+// of the test unless the events decoded from the encoded events are deeply
+// equal to the original events.
+func (f *Fuzzer) RoundTrip(
+	t testing.TB, n int,
+	encode func(event.Event) ([]byte, error),
+	decode func([]byte) (event.Event, error),
+	protos ...event.Event,
+) {
+	t.Helper()
+	for _, proto := range protos {
+		for i := 0; i < n; i++ {
+			ev := f.Event(proto)
+			bs, err := encode(ev)
+			if err != nil {
+				t.Errorf("seed %d: encode %#v: %v", f.seed, ev, err)
+				break
+			}
+			got, err := decode(bs)
+			if err != nil {
+				t.Errorf("seed %d: decode %q: %v", f.seed, bs, err)
+				break
+			}
+			if !reflect.DeepEqual(got, ev) {
+				t.Errorf("seed %d: round trip of %#v: got %#v (encoded %q)", f.seed, ev, got, bs)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package eventtest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type userCreated struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Score     float64           `json:"score"`
+	Ratio     float32           `json:"ratio"`
+	Active    bool              `json:"active"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]uint16 `json:"labels"`
+	Address   *address          `json:"address,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	internal  int
+}
+
+type address struct {
+	City string      `json:"city"`
+	Zip  [2]uint8    `json:"zip"`
+	Next *address    `json:"next"`
+	Data []byte      `json:"data"`
+	Any  interface{} `json:"any"`
+}
+
+func (userCreated) Type() event.Type { return 1 }
+
+type envelope struct {
+	Type    event.Type      `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func encode(ev event.Event) ([]byte, error) {
+	bs, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope{ev.Type(), bs})
+}
+
+func decode(bs []byte) (event.Event, error) {
+	var env envelope
+	if err := json.Unmarshal(bs, &env); err != nil {
+		return nil, err
+	}
+	switch env.Type {
+	case 0:
+		var ev eventCreated
+		err := json.Unmarshal(env.Payload, &ev)
+		return ev, err
+	case 1:
+		var ev userCreated
+		err := json.Unmarshal(env.Payload, &ev)
+		return ev, err
+	default:
+		return nil, fmt.Errorf("unknown event type: %d", env.Type)
+	}
+}
+
+func TestFuzzer(t *testing.T) {
+	f := eventtest.NewFuzzer(1)
+	ev := f.Event(userCreated{})
+	if u, ok := ev.(userCreated); !ok {
+		t.Fatalf("expected userCreated, got %T", ev)
+	} else if u.internal != 0 {
+		t.Errorf("expected the unexported field to be zero, got %d", u.internal)
+	}
+	if reflect.DeepEqual(ev, f.Event(userCreated{})) {
+		t.Errorf("expected random events, got %#v twice", ev)
+	}
+	if !reflect.DeepEqual(ev, eventtest.NewFuzzer(1).Event(userCreated{})) {
+		t.Errorf("expected the same event of the same seed")
+	}
+	f.RoundTrip(t, 100, encode, decode, eventCreated(0), userCreated{})
+}
+
+func TestFuzzerRoundTripError(t *testing.T) {
+	f := eventtest.NewFuzzer(2)
+	r := &recorder{}
+	f.RoundTrip(r, 100, encode, func(bs []byte) (event.Event, error) {
+		ev, err := decode(bs)
+		if u, ok := ev.(userCreated); ok {
+			u.Name = strings.ToUpper(u.Name)
+			ev = u
+		}
+		return ev, err
+	}, eventCreated(0), userCreated{})
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "seed 2: round trip of eventtest_test.userCreated{") {
+		t.Errorf("expected an error of the round trip, got %v", r.errors)
+	}
+	r = &recorder{}
+	f.RoundTrip(r, 1, func(event.Event) ([]byte, error) {
+		return nil, errors.New("encode error")
+	}, decode, eventCreated(0))
+	if len(r.errors) != 1 || !strings.HasSuffix(r.errors[0], ": encode error") {
+		t.Errorf("expected an error of encoding, got %v", r.errors)
+	}
+	r = &recorder{}
+	f.RoundTrip(r, 1, encode, func([]byte) (event.Event, error) {
+		return nil, errors.New("decode error")
+	}, eventCreated(0))
+	if len(r.errors) != 1 || !strings.HasSuffix(r.errors[0], ": decode error") {
+		t.Errorf("expected an error of decoding, got %v", r.errors)
+	}
+}
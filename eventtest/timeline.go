@@ -0,0 +1,191 @@
+package eventtest
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+// Timeline records the start and the finish of handling the events by the
+// subscribers, to verify the concurrency of the compositions of the
+// subscribers, like Async, Limited, and Lanes. The times are the logical
+// ticks incremented on each start and finish, so the timeline is free from the
+// resolution of the clock.
+type Timeline struct {
+	mu    sync.Mutex
+	tick  int
+	names []string
+	spans []Span
+}
+
+// Span is the span of handling an event by a subscriber. The finish is zero
+// while the subscriber is running.
+type Span struct {
+	Subscriber string
+	Event      event.Event
+	Start      int
+	Finish     int
+	Err        error
+}
+
+// NewTimeline creates a new timeline.
+func NewTimeline() *Timeline {
+	return &Timeline{}
+}
+
+// Wrap returns the subscriber to record the spans of the subscriber by the
+// name on the timeline.
+func (tl *Timeline) Wrap(name string, sub event.Subscriber) event.Subscriber {
+	return event.Func(func(ctx context.Context, ev event.Event) error {
+		i := tl.start(name, ev)
+		err := sub.Handle(ctx, ev)
+		tl.finish(i, err)
+		return err
+	})
+}
+
+func (tl *Timeline) start(name string, ev event.Event) int {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.tick++
+	var found bool
+	for _, n := range tl.names {
+		if found = n == name; found {
+			break
+		}
+	}
+	if !found {
+		tl.names = append(tl.names, name)
+	}
+	tl.spans = append(tl.spans, Span{Subscriber: name, Event: ev, Start: tl.tick})
+	return len(tl.spans) - 1
+}
+
+func (tl *Timeline) finish(i int, err error) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.tick++
+	tl.spans[i].Finish, tl.spans[i].Err = tl.tick, err
+}
+
+// Spans returns the recorded spans in the order of the starts.
+func (tl *Timeline) Spans() []Span {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return append([]Span(nil), tl.spans...)
+}
+
+// Overlapped reports whether any span of the subscriber a overlapped with any
+// span of the subscriber b. Specify the same name to check whether the
+// subscriber ran concurrently with itself.
+func (tl *Timeline) Overlapped(a, b string) bool {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	for i, x := range tl.spans {
+		if x.Subscriber != a {
+			continue
+		}
+		for j, y := range tl.spans {
+			if i != j && y.Subscriber == b && x.Start < y.end(tl.tick+1) && y.Start < x.end(tl.tick+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MaxConcurrency returns the max number of the spans of the subscribers
+// running at the same time. Specify no names to count all the subscribers.
+func (tl *Timeline) MaxConcurrency(names ...string) int {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	var max int
+	for t := 1; t <= tl.tick; t++ {
+		if n := tl.running(t, names...); n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+func (tl *Timeline) running(t int, names ...string) int {
+	var n int
+	for _, s := range tl.spans {
+		if s.Start <= t && t < s.end(tl.tick+1) && (len(names) == 0 || contains(names, s.Subscriber)) {
+			n++
+		}
+	}
+	return n
+}
+
+func (s Span) end(tick int) int {
+	if s.Finish == 0 {
+		return tick
+	}
+	return s.Finish
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the timeline with a row per subscriber and a column per tick.
+// The columns show the number of the spans of the subscriber running, or a
+// space if none.
+//
+//	a |11    |
+//	b | 11   |
+//	c |    1 |
+func (tl *Timeline) String() string {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	var width int
+	for _, name := range tl.names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	var sb strings.Builder
+	for _, name := range tl.names {
+		sb.WriteString(name + strings.Repeat(" ", width-len(name)) + " |")
+		for t := 1; t <= tl.tick; t++ {
+			switch n := tl.running(t, name); {
+			case n == 0:
+				sb.WriteByte(' ')
+			case n < 10:
+				sb.WriteString(strconv.Itoa(n))
+			default:
+				sb.WriteByte('+')
+			}
+		}
+		sb.WriteString("|\n")
+	}
+	return sb.String()
+}
+
+// AssertOverlap reports a failure of the test unless the subscribers a and b
+// overlapped on the timeline.
+func AssertOverlap(t testing.TB, tl *Timeline, a, b string) {
+	t.Helper()
+	if !tl.Overlapped(a, b) {
+		t.Errorf("expected %s and %s to run concurrently:\n%s", a, b, tl)
+	}
+}
+
+// AssertNoOverlap reports a failure of the test if the subscribers a and b
+// overlapped on the timeline.
+func AssertNoOverlap(t testing.TB, tl *Timeline, a, b string) {
+	t.Helper()
+	if tl.Overlapped(a, b) {
+		t.Errorf("expected %s and %s not to run concurrently:\n%s", a, b, tl)
+	}
+}
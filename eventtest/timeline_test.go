@@ -0,0 +1,110 @@
+package eventtest_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type eventCreated int
+
+func (eventCreated) Type() event.Type { return 0 }
+
+func TestTimeline(t *testing.T) {
+	ctx := context.Background()
+	noop := event.Func(func(context.Context, event.Event) error { return nil })
+	tl := eventtest.NewTimeline()
+	sub := event.Ordered{tl.Wrap("a", noop), tl.Wrap("bb", noop)}
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	eventtest.AssertNoOverlap(t, tl, "a", "bb")
+	eventtest.AssertNoOverlap(t, tl, "a", "a")
+	if got, expected := tl.String(), "a  |1   |\nbb |  1 |\n"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if got, expected := len(tl.Spans()), 2; got != expected {
+		t.Errorf("expected %d spans, got %d", expected, got)
+	}
+
+	tl = eventtest.NewTimeline()
+	var wg sync.WaitGroup
+	wg.Add(3)
+	barrier := event.Func(func(context.Context, event.Event) error {
+		wg.Done()
+		wg.Wait()
+		return nil
+	})
+	sub = event.Ordered{
+		event.Async{tl.Wrap("a", barrier), tl.Wrap("a", barrier), tl.Wrap("b", barrier)},
+		tl.Wrap("c", noop),
+	}
+	if err := sub.Handle(ctx, eventCreated(2)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	eventtest.AssertOverlap(t, tl, "a", "b")
+	eventtest.AssertOverlap(t, tl, "a", "a")
+	eventtest.AssertNoOverlap(t, tl, "b", "b")
+	eventtest.AssertNoOverlap(t, tl, "a", "c")
+	eventtest.AssertNoOverlap(t, tl, "b", "c")
+	if got, expected := tl.MaxConcurrency(), 3; got != expected {
+		t.Errorf("expected max concurrency %d, got %d", expected, got)
+	}
+	if got, expected := tl.MaxConcurrency("a"), 2; got != expected {
+		t.Errorf("expected max concurrency %d, got %d", expected, got)
+	}
+	if got, expected := tl.MaxConcurrency("c"), 1; got != expected {
+		t.Errorf("expected max concurrency %d, got %d", expected, got)
+	}
+}
+
+func TestTimelineRunning(t *testing.T) {
+	ctx := context.Background()
+	tl := eventtest.NewTimeline()
+	var wg sync.WaitGroup
+	wg.Add(10)
+	barrier := event.Func(func(context.Context, event.Event) error {
+		wg.Done()
+		wg.Wait()
+		return nil
+	})
+	var async event.Async
+	for i := 0; i < 10; i++ {
+		async = append(async, tl.Wrap("a", barrier))
+	}
+	var running string
+	sub := event.Ordered{async, tl.Wrap("b", event.Func(func(context.Context, event.Event) error {
+		running = tl.String()
+		return nil
+	}))}
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := "a |123456789+987654321  |\nb |                    1|\n"; running != expected {
+		t.Errorf("expected %q, got %q", expected, running)
+	}
+	r := &recorder{}
+	eventtest.AssertOverlap(r, tl, "a", "b")
+	eventtest.AssertNoOverlap(r, tl, "a", "a")
+	if len(r.errors) != 2 ||
+		!strings.HasPrefix(r.errors[0], "expected a and b to run concurrently:\n") ||
+		!strings.HasPrefix(r.errors[1], "expected a and a not to run concurrently:\n") {
+		t.Errorf("expected the errors of the assertions, got %v", r.errors)
+	}
+}
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,39 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// Expirer is the interface for an event which expires at the time, like a
+// notification which is meaningless after a while. The zero time means the
+// event never expires. The transports keep the expiry across the process
+// boundaries, so that the consumers drop the expired events consistently.
+type Expirer interface {
+	Event
+	ExpiresAt() time.Time
+}
+
+// Expired reports whether the event implements Expirer and is expired by the
+// clock set by SetClock.
+func Expired(ev Event) bool {
+	e, ok := ev.(Expirer)
+	if !ok {
+		return false
+	}
+	t := e.ExpiresAt()
+	return !t.IsZero() && !CurrentClock().Now().Before(t)
+}
+
+// SkipExpired creates an event subscriber to drop the expired events before
+// handling by the subscriber. The dropped events are reported to the dropped
+// event observer as DropExpired.
+func SkipExpired(sub Subscriber) Func {
+	return func(ctx context.Context, ev Event) error {
+		if Expired(ev) {
+			dropped(ctx, ev, DropExpired)
+			return nil
+		}
+		return sub.Handle(ctx, ev)
+	}
+}
@@ -0,0 +1,54 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type eventExpiring struct {
+	eventCreated
+	expiresAt time.Time
+}
+
+func (ev eventExpiring) ExpiresAt() time.Time {
+	return ev.expiresAt
+}
+
+func TestSkipExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	event.SetClock(eventtest.NewClock(now))
+	defer event.SetClock(nil)
+	var drops []event.Event
+	event.SetDroppedEventObserver(func(_ context.Context, ev event.Event, reason event.DropReason) {
+		if reason == event.DropExpired {
+			drops = append(drops, ev)
+		}
+	})
+	defer event.SetDroppedEventObserver(nil)
+	sub1 := &logged{}
+	sub := event.SkipExpired(sub1)
+	evs := []event.Event{
+		eventCreated(1),
+		eventExpiring{eventCreated(2), time.Time{}},
+		eventExpiring{eventCreated(3), now.Add(time.Second)},
+		eventExpiring{eventCreated(4), now},
+		eventExpiring{eventCreated(5), now.Add(-time.Second)},
+	}
+	for _, ev := range evs {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := evs[:3]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := evs[3:]; !reflect.DeepEqual(drops, expected) {
+		t.Errorf("dropped events: expected %v, got %v", expected, drops)
+	}
+}
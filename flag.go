@@ -0,0 +1,18 @@
+package event
+
+import "context"
+
+// WhenFlag creates an event subscriber to handle the events by the subscriber
+// only when the feature flag is enabled, which is checked on each event. This
+// is useful to ship the experimental subscribers dark, and enable them per
+// request or tenant, by the flag function reading the context. The events are
+// reported to the dropped event observer while the flag is disabled.
+func WhenFlag(flag func(context.Context) bool, sub Subscriber) Subscriber {
+	return &wrapper{func(ctx context.Context, ev Event) error {
+		if !flag(ctx) {
+			dropped(ctx, ev, DropFlagDisabled)
+			return nil
+		}
+		return sub.Handle(ctx, ev)
+	}, sub}
+}
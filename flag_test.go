@@ -0,0 +1,57 @@
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+type tenantKey struct{}
+
+func TestWhenFlag(t *testing.T) {
+	var drops []string
+	event.SetDroppedEventObserver(func(_ context.Context, ev event.Event, reason event.DropReason) {
+		drops = append(drops, fmt.Sprint(ev, " ", reason))
+	})
+	defer event.SetDroppedEventObserver(nil)
+	sub1 := &logged{}
+	sub := event.WhenFlag(func(ctx context.Context) bool {
+		return ctx.Value(tenantKey{}) == "beta"
+	}, sub1)
+	for i, tenant := range []string{"alpha", "beta", "gamma", "beta"} {
+		ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+		if err := sub.Handle(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(3)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := []string{"0 flag disabled", "2 flag disabled"}; !reflect.DeepEqual(drops, expected) {
+		t.Errorf("dropped events: expected %v, got %v", expected, drops)
+	}
+}
+
+func TestWhenFlagName(t *testing.T) {
+	ctx := context.Background()
+	var names []string
+	record := event.Func(func(ctx context.Context, _ event.Event) error {
+		name, _ := pprof.Label(ctx, "subscriber")
+		names = append(names, name)
+		return nil
+	})
+	enabled := func(context.Context) bool { return true }
+	pub := event.NewMux(event.MuxLabels()).
+		On(eventTypeCreated, event.WhenFlag(enabled, event.Build(record).Named("record"))).
+		On(eventTypeCreated, event.WhenFlag(enabled, record))
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []string{"record", "event-go_test.TestWhenFlagName.func1"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
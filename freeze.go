@@ -0,0 +1,31 @@
+package event
+
+import "context"
+
+// Freeze makes the mux read-only, and returns the view of the mux which only
+// publishes the events. Registering the subscribers on the frozen mux panics,
+// so that the mux is provably immutable after the startup and safe to publish
+// the events concurrently without locks.
+func (pub *Mux) Freeze() Publisher {
+	pub.frozen = true
+	return frozenMux{pub}
+}
+
+type frozenMux struct {
+	mux *Mux
+}
+
+// Handle implements Subscriber for the frozen mux.
+func (pub frozenMux) Handle(ctx context.Context, ev Event) error {
+	return pub.mux.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for the frozen mux.
+func (pub frozenMux) Publish(ctx context.Context, ev Event) error {
+	return pub.mux.Publish(ctx, ev)
+}
+
+// Ready implements Readier for the frozen mux.
+func (pub frozenMux) Ready(ctx context.Context) error {
+	return pub.mux.Ready(ctx)
+}
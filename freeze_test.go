@@ -0,0 +1,45 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestMuxFreeze(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	mux := event.NewMux().On(eventTypeCreated, sub1)
+	pub := mux.Freeze()
+	if _, ok := pub.(*event.Mux); ok {
+		t.Fatalf("expected a read-only view, got %T", pub)
+	}
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := pub.(event.Subscriber).Handle(ctx, eventCreated(2)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := event.WaitReady(ctx, pub.(event.Subscriber)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(2)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	for _, register := range []func(){
+		func() { mux.On(eventTypeCreated, sub1) },
+		func() { mux.Default(sub1) },
+		func() { mux.OnNamespace(1, sub1) },
+	} {
+		func() {
+			defer func() {
+				if r, expected := recover(), "event: subscriber registered on a frozen mux"; r != expected {
+					t.Errorf("expected panic %q, got %v", expected, r)
+				}
+			}()
+			register()
+		}()
+	}
+}
@@ -0,0 +1,180 @@
+// Package fsm provides a finite state machine driven by events, which is
+// useful for modeling the lifecycle of orders or workflows.
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/itchyny/event-go"
+)
+
+// State is the state of a state machine.
+type State string
+
+// Machine is an event subscriber to transition the states on the events. The
+// state is tracked for each key of the events, so that a machine handles the
+// states of all the orders for example.
+type Machine struct {
+	initial     State
+	transitions map[State]map[event.Type]State
+	triggers    map[event.Type]bool
+	entry       map[State]event.Subscriber
+	exit        map[State]event.Subscriber
+	key         func(event.Event) interface{}
+	typ         event.Type
+	publisher   event.Publisher
+	mu          sync.Mutex
+	states      map[interface{}]State
+}
+
+// New creates a new state machine in the initial state.
+func New(initial State) *Machine {
+	return &Machine{
+		initial:     initial,
+		transitions: make(map[State]map[event.Type]State),
+		triggers:    make(map[event.Type]bool),
+		entry:       make(map[State]event.Subscriber),
+		exit:        make(map[State]event.Subscriber),
+		key:         func(event.Event) interface{} { return nil },
+		states:      make(map[interface{}]State),
+	}
+}
+
+// Transition registers the transition from the state to another state on the
+// event type. This method returns the machine to allow method chaining. Note
+// that the methods to configure the machine are not goroutine safe so
+// configure the machine before starting event publishing.
+func (m *Machine) Transition(from State, typ event.Type, to State) *Machine {
+	if m.transitions[from] == nil {
+		m.transitions[from] = make(map[event.Type]State)
+	}
+	m.transitions[from][typ] = to
+	m.triggers[typ] = true
+	return m
+}
+
+// OnEntry registers the subscriber to handle the event which transitions the
+// machine into the state. This method returns the machine to allow method
+// chaining.
+func (m *Machine) OnEntry(state State, sub event.Subscriber) *Machine {
+	m.entry[state] = appendSubscriber(m.entry[state], sub)
+	return m
+}
+
+// OnExit registers the subscriber to handle the event which transitions the
+// machine out of the state. This method returns the machine to allow method
+// chaining.
+func (m *Machine) OnExit(state State, sub event.Subscriber) *Machine {
+	m.exit[state] = appendSubscriber(m.exit[state], sub)
+	return m
+}
+
+func appendSubscriber(s, sub event.Subscriber) event.Subscriber {
+	if s == nil {
+		return sub
+	}
+	if o, ok := s.(event.Ordered); ok {
+		return append(o, sub)
+	}
+	return event.Ordered{s, sub}
+}
+
+// Key sets the function to return the key of the event to track the states
+// separately. All the events share the same state by default. This method
+// returns the machine to allow method chaining.
+func (m *Machine) Key(key func(event.Event) interface{}) *Machine {
+	m.key = key
+	return m
+}
+
+// Emit sets the publisher to publish a StateChanged event of the type on each
+// transition. This method returns the machine to allow method chaining.
+func (m *Machine) Emit(typ event.Type, pub event.Publisher) *Machine {
+	m.typ, m.publisher = typ, pub
+	return m
+}
+
+// State returns the current state of the key.
+func (m *Machine) State(key interface{}) State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state, ok := m.states[key]; ok {
+		return state
+	}
+	return m.initial
+}
+
+// Delete forgets the state of the key, which is useful to release the memory
+// of the keys reaching a final state. The state of the key is the initial
+// state after this.
+func (m *Machine) Delete(key interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.states, key)
+}
+
+// Handle implements Subscriber for Machine. The events of the types which
+// trigger no transitions are ignored, and an event which is not allowed in the
+// current state is reported by TransitionError. The state is transitioned
+// before the exit and entry subscribers handle the event, so the state does
+// not roll back on their errors.
+func (m *Machine) Handle(ctx context.Context, ev event.Event) error {
+	if !m.triggers[ev.Type()] {
+		return nil
+	}
+	key := m.key(ev)
+	m.mu.Lock()
+	from, ok := m.states[key]
+	if !ok {
+		from = m.initial
+	}
+	to, ok := m.transitions[from][ev.Type()]
+	if !ok {
+		m.mu.Unlock()
+		return &TransitionError{State: from, Event: ev}
+	}
+	m.states[key] = to
+	m.mu.Unlock()
+	if sub := m.exit[from]; sub != nil {
+		if err := sub.Handle(ctx, ev); err != nil {
+			return err
+		}
+	}
+	if sub := m.entry[to]; sub != nil {
+		if err := sub.Handle(ctx, ev); err != nil {
+			return err
+		}
+	}
+	if m.publisher != nil {
+		return m.publisher.Publish(ctx, &StateChanged{m.typ, key, from, to, ev})
+	}
+	return nil
+}
+
+// StateChanged is the event published by Machine on each transition.
+type StateChanged struct {
+	typ   event.Type
+	Key   interface{}
+	From  State
+	To    State
+	Event event.Event
+}
+
+// Type implements event.Event for StateChanged.
+func (ev *StateChanged) Type() event.Type {
+	return ev.typ
+}
+
+// TransitionError is the error reported by Machine on an event which is not
+// allowed in the current state.
+type TransitionError struct {
+	State State
+	Event event.Event
+}
+
+// Error implements error for TransitionError.
+func (err *TransitionError) Error() string {
+	return fmt.Sprintf("invalid transition from state %s on event type: %d", err.State, err.Event.Type())
+}
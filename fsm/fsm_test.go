@@ -0,0 +1,175 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/fsm"
+)
+
+const (
+	eventTypePaid event.Type = iota
+	eventTypeShipped
+	eventTypeCanceled
+	eventTypeOther
+	eventTypeStateChanged
+)
+
+type eventPaid int
+
+func (eventPaid) Type() event.Type {
+	return eventTypePaid
+}
+
+type eventShipped int
+
+func (eventShipped) Type() event.Type {
+	return eventTypeShipped
+}
+
+type eventCanceled int
+
+func (eventCanceled) Type() event.Type {
+	return eventTypeCanceled
+}
+
+type eventOther int
+
+func (eventOther) Type() event.Type {
+	return eventTypeOther
+}
+
+func newMachine() *fsm.Machine {
+	return fsm.New("pending").
+		Transition("pending", eventTypePaid, "paid").
+		Transition("pending", eventTypeCanceled, "canceled").
+		Transition("paid", eventTypeShipped, "shipped").
+		Transition("paid", eventTypeCanceled, "canceled").
+		Key(func(ev event.Event) interface{} {
+			return reflect.ValueOf(ev).Int()
+		})
+}
+
+func TestMachine(t *testing.T) {
+	ctx := context.Background()
+	var actions []string
+	var changed []event.Event
+	m := newMachine().
+		OnExit("pending", event.Func(func(context.Context, event.Event) error {
+			actions = append(actions, "exit pending")
+			return nil
+		})).
+		OnEntry("paid", event.Func(func(context.Context, event.Event) error {
+			actions = append(actions, "enter paid")
+			return nil
+		})).
+		OnEntry("paid", event.Func(func(context.Context, event.Event) error {
+			actions = append(actions, "enter paid again")
+			return nil
+		})).
+		Emit(eventTypeStateChanged, event.Func(func(_ context.Context, ev event.Event) error {
+			changed = append(changed, ev)
+			return nil
+		}))
+	evs := []event.Event{eventPaid(1), eventCanceled(2), eventOther(1), eventShipped(1)}
+	for _, ev := range evs {
+		if err := m.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	for key, expected := range map[int64]fsm.State{1: "shipped", 2: "canceled", 3: "pending"} {
+		if got := m.State(key); got != expected {
+			t.Errorf("state of %d: expected %s, got %s", key, expected, got)
+		}
+	}
+	if expected := []string{"exit pending", "enter paid", "enter paid again", "exit pending"}; !reflect.DeepEqual(actions, expected) {
+		t.Errorf("actions: expected %v, got %v", expected, actions)
+	}
+	if len(changed) != 3 {
+		t.Fatalf("expected 3 state changed events, got %v", changed)
+	}
+	if ev, expected := changed[2].(*fsm.StateChanged), "shipped"; ev.Type() != eventTypeStateChanged ||
+		ev.Key != int64(1) || ev.From != "paid" || ev.To != fsm.State(expected) || ev.Event != evs[3] {
+		t.Errorf("unexpected state changed event: %#v", ev)
+	}
+}
+
+func TestMachineError(t *testing.T) {
+	ctx := context.Background()
+	m := newMachine().
+		OnEntry("canceled", event.Func(func(context.Context, event.Event) error {
+			return errors.New("entry error")
+		}))
+	err := m.Handle(ctx, eventShipped(1))
+	if expected := "invalid transition from state pending on event type: 1"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	var terr *fsm.TransitionError
+	if !errors.As(err, &terr) || terr.State != "pending" || terr.Event != eventShipped(1) {
+		t.Errorf("expected TransitionError, got %v", err)
+	}
+	if err, expected := m.Handle(ctx, eventCanceled(1)), "entry error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if got, expected := m.State(int64(1)), fsm.State("canceled"); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestMachineExitError(t *testing.T) {
+	ctx := context.Background()
+	var actions []string
+	action := func(name string) event.Subscriber {
+		return event.Func(func(context.Context, event.Event) error {
+			actions = append(actions, name)
+			return nil
+		})
+	}
+	m := fsm.New("pending").
+		Transition("pending", eventTypePaid, "paid").
+		Transition("paid", eventTypeShipped, "shipped").
+		OnExit("pending", action("exit 1")).
+		OnExit("pending", action("exit 2")).
+		OnExit("pending", event.Func(func(context.Context, event.Event) error {
+			return errors.New("exit error")
+		})).
+		OnEntry("paid", action("enter paid"))
+	if err, expected := m.Handle(ctx, eventPaid(1)), "exit error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := m.Handle(ctx, eventShipped(2)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := m.State(nil), fsm.State("shipped"); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	if expected := []string{"exit 1", "exit 2"}; !reflect.DeepEqual(actions, expected) {
+		t.Errorf("actions: expected %v, got %v", expected, actions)
+	}
+}
+
+func TestMachineDelete(t *testing.T) {
+	ctx := context.Background()
+	m := newMachine()
+	m.OnEntry("canceled", event.Func(func(_ context.Context, ev event.Event) error {
+		m.Delete(int64(ev.(eventCanceled)))
+		return nil
+	}))
+	for _, ev := range []event.Event{eventPaid(1), eventPaid(2), eventCanceled(2)} {
+		if err := m.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	for key, expected := range map[int64]fsm.State{1: "paid", 2: "pending"} {
+		if got := m.State(key); got != expected {
+			t.Errorf("state of %d: expected %s, got %s", key, expected, got)
+		}
+	}
+	m.Delete(int64(1))
+	if got, expected := m.State(int64(1)), fsm.State("pending"); got != expected {
+		t.Errorf("state of 1: expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,83 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// SequenceGap is the meta event published by GapDetector on the gap of the
+// sequence numbers, which means the events from From to To inclusive are
+// missing before the event in the stream.
+type SequenceGap struct {
+	Stream interface{}
+	From   uint64
+	To     uint64
+	Event  Event
+}
+
+// Type implements Event for SequenceGap.
+func (*SequenceGap) Type() Type {
+	return TypeMeta
+}
+
+// GapDetector is an event subscriber to detect the gaps of the sequence
+// numbers carried by the contexts, like restored by the transport consumers
+// by WithSequence, to catch the silent loss of the events in the brokers or
+// the bridges. The duplicate and the late events do not make the gaps. The
+// events without the sequence numbers are handled without the detection.
+type GapDetector struct {
+	subscriber Subscriber
+	meta       Publisher
+	stream     func(Event) interface{}
+	mu         sync.Mutex
+	next       map[interface{}]uint64
+	missing    uint64
+}
+
+// NewGapDetector creates a new subscriber to handle the events by the
+// subscriber, publishing SequenceGap meta events to the meta publisher on the
+// gaps. Count the meta events by Metrics to alert on the gaps.
+func NewGapDetector(sub Subscriber, meta Publisher) *GapDetector {
+	return &GapDetector{subscriber: sub, meta: meta, next: make(map[interface{}]uint64)}
+}
+
+// Stream sets the function to return the stream of the event, for the events
+// numbered per stream, like per partition. The events are in the same stream
+// by default. This method returns the subscriber to allow method chaining.
+func (sub *GapDetector) Stream(stream func(Event) interface{}) *GapDetector {
+	sub.stream = stream
+	return sub
+}
+
+// Handle implements Subscriber for GapDetector. The gap is published before
+// handling the event, and the error of the meta publisher is ignored.
+func (sub *GapDetector) Handle(ctx context.Context, ev Event) error {
+	if seq, ok := SequenceFromContext(ctx, ev); ok {
+		var stream interface{}
+		if sub.stream != nil {
+			stream = sub.stream(ev)
+		}
+		sub.mu.Lock()
+		next, seen := sub.next[stream]
+		if !seen || seq >= next {
+			sub.next[stream] = seq + 1
+		}
+		gap := seen && seq > next
+		if gap {
+			sub.missing += seq - next
+		}
+		sub.mu.Unlock()
+		if gap {
+			_ = sub.meta.Publish(ctx, &SequenceGap{stream, next, seq - 1, ev})
+		}
+	}
+	return sub.subscriber.Handle(ctx, ev)
+}
+
+// Missing returns the total number of the missing events of the gaps, counted
+// on the detection even if the events arrive late.
+func (sub *GapDetector) Missing() uint64 {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.missing
+}
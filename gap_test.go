@@ -0,0 +1,50 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestGapDetector(t *testing.T) {
+	ctx := context.Background()
+	var gaps []event.SequenceGap
+	meta := event.NewMapping().On(event.TypeMeta, event.Func(func(_ context.Context, ev event.Event) error {
+		gaps = append(gaps, *ev.(*event.SequenceGap))
+		return nil
+	}))
+	sub1 := &logged{}
+	sub := event.NewGapDetector(sub1, meta).Stream(func(ev event.Event) interface{} {
+		return ev.Type()
+	})
+	for _, x := range []struct {
+		ev  event.Event
+		seq uint64
+	}{
+		{eventCreated(1), 5}, {eventCreated(2), 6}, {eventUpdated(1), 1}, {eventCreated(3), 9},
+		{eventCreated(4), 7}, {eventCreated(5), 9}, {eventUpdated(2), 2}, {eventUpdated(3), 5},
+		{eventCreated(6), 10},
+	} {
+		if err := sub.Handle(event.WithSequence(ctx, x.seq, x.ev), x.ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := sub.Handle(ctx, eventCreated(7)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := []event.SequenceGap{
+		{eventTypeCreated, 7, 8, eventCreated(3)},
+		{eventTypeUpdated, 3, 4, eventUpdated(3)},
+	}
+	if !reflect.DeepEqual(gaps, expected) {
+		t.Errorf("expected %v, got %v", expected, gaps)
+	}
+	if got, expected := sub.Missing(), uint64(4); got != expected {
+		t.Errorf("expected %d, got %d", expected, got)
+	}
+	if got, expected := len(sub1.Events()), 10; got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+}
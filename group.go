@@ -0,0 +1,99 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// Group is a group of goroutines like errgroup, to own the goroutines spawned
+// by the subscribers and the publishers, so that the tests and the shutdown
+// can wait for all of them. The goroutines of Async, AsyncN, DispatchAsync,
+// PublishNoWait, and the readiness checks are owned by the group of the
+// context, and the workers of Lanes and Replicator are owned by the group set
+// by their Group methods. The context of the group is canceled on the first
+// error of the goroutines started by Go or on returning from Wait. The errors
+// of the spawned goroutines are reported to their callers, not to the group,
+// so that an error of an event does not shut down the workers of the group.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	err    error
+}
+
+type groupKey struct{}
+
+// NewGroup creates a new group, and returns the derived context carrying the
+// group. Pass the context to the publishers to spawn the goroutines in the
+// group.
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	g := &Group{}
+	g.ctx, g.cancel = context.WithCancel(ctx)
+	g.ctx = context.WithValue(g.ctx, groupKey{}, g)
+	return g, g.ctx
+}
+
+// GroupFromContext returns the group carried by the context, or nil if the
+// context does not carry a group.
+func GroupFromContext(ctx context.Context) *Group {
+	g, _ := ctx.Value(groupKey{}).(*Group)
+	return g
+}
+
+// Context returns the context of the group, which carries the group.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Go calls the function on a new goroutine owned by the group. The first
+// error of the goroutines cancels the context of the group.
+func (g *Group) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(); err != nil {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if g.err == nil {
+				g.err = err
+				g.cancel()
+			}
+		}
+	}()
+}
+
+// Wait blocks until all the goroutines of the group return, and returns the
+// first error of them.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.Err()
+}
+
+// Err returns the first error of the goroutines of the group, or nil if no
+// goroutine has failed yet.
+func (g *Group) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// spawnIn calls the function on a new goroutine, which is owned by the group
+// if not nil.
+func spawnIn(g *Group, f func()) {
+	if g != nil {
+		g.Go(func() error {
+			f()
+			return nil
+		})
+		return
+	}
+	go f()
+}
+
+// spawn calls the function on a new goroutine, which is owned by the group of
+// the context if any.
+func spawn(ctx context.Context, f func()) {
+	spawnIn(GroupFromContext(ctx), f)
+}
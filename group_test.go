@@ -0,0 +1,111 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestGroup(t *testing.T) {
+	g, ctx := event.NewGroup(context.Background())
+	if event.GroupFromContext(ctx) != g {
+		t.Fatalf("expected the context to carry the group")
+	}
+	if event.GroupFromContext(context.Background()) != nil {
+		t.Fatalf("expected no group in the background context")
+	}
+	var handled int32
+	release := make(chan struct{})
+	sub := event.Func(func(ctx context.Context, ev event.Event) error {
+		<-release
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	pub := event.NewMapping().
+		On(eventTypeCreated, event.Async{sub, sub}).
+		On(eventTypeUpdated, suberr{})
+	ch := event.PublishNoWait(ctx, pub, eventCreated(1))
+	close(release)
+	if err := <-ch; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := g.Err(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := <-event.PublishNoWait(ctx, pub, eventUpdated(2)), "handle error"; err == nil || err.Error() != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	g.Go(func() error { return errors.New("group error") })
+	if err, expected := g.Wait(), "group error"; err == nil || err.Error() != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if got, expected := atomic.LoadInt32(&handled), int32(2); got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+	if err, expected := ctx.Err(), context.Canceled; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestGroupPublishNoWaitError(t *testing.T) {
+	g, ctx := event.NewGroup(context.Background())
+	var handled int32
+	lanes := event.NewLanes(event.Func(func(context.Context, event.Event) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})).Group(g).Lane("default", 1, 1)
+	pub := event.NewMapping().
+		On(eventTypeCreated, lanes).
+		On(eventTypeUpdated, event.Async{suberr{}}).
+		On(eventTypeDeleted, suberr{})
+	for _, ev := range []event.Event{eventUpdated(1), eventDeleted(2)} {
+		if err, expected := <-event.PublishNoWait(ctx, pub, ev), "handle error"; err == nil || err.Error() != expected {
+			t.Errorf("expected %v, got %v", expected, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("expected the context of the group alive, got %v", err)
+	}
+	if err := g.Err(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := <-event.PublishNoWait(ctx, pub, eventCreated(3)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := lanes.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := atomic.LoadInt32(&handled), int32(1); got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+}
+
+func TestGroupDispatchAsync(t *testing.T) {
+	g, ctx := event.NewGroup(context.Background())
+	var handled int32
+	pub := event.NewBuffer(event.Func(func(ctx context.Context, ev event.Event) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}))
+	for i := 0; i < 5; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub.DispatchAsync(ctx, 3); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	g.Go(func() error { return errors.New("group error") })
+	if err, expected := g.Wait(), "group error"; err == nil || err.Error() != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if got, expected := atomic.LoadInt32(&handled), int32(5); got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+}
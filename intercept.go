@@ -0,0 +1,41 @@
+package event
+
+import "context"
+
+// Interceptor is a function to intercept handling an event by the next
+// subscriber, like the interceptors of gRPC. Unlike the middlewares built by
+// the functions of the subscribers, the interceptors are written as a single
+// function receiving the next subscriber, so that they can act on the result
+// of the next subscriber; translate the errors, retry conditionally, and
+// record the outcome. The interceptor may skip the next subscriber, or call it
+// more than once.
+type Interceptor func(ctx context.Context, ev Event, next Subscriber) error
+
+// Middleware returns the subscriber to handle the events by the interceptor,
+// with the subscriber as the next. The method value is the middleware for
+// MuxMiddleware.
+func (f Interceptor) Middleware(next Subscriber) Subscriber {
+	return Func(func(ctx context.Context, ev Event) error {
+		return f(ctx, ev, next)
+	})
+}
+
+// Intercept wraps the subscriber by the interceptors. The interceptors are
+// applied in order, so the first interceptor is the outermost.
+func Intercept(sub Subscriber, interceptors ...Interceptor) Subscriber {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		sub = interceptors[i].Middleware(sub)
+	}
+	return sub
+}
+
+// MuxInterceptor sets the interceptors to wrap each subscriber on
+// registering, as well as MuxMiddleware. The interceptors and the
+// middlewares are applied in the order of the options.
+func MuxInterceptor(interceptors ...Interceptor) MuxOption {
+	return func(pub *Mux) {
+		for _, f := range interceptors {
+			pub.middlewares = append(pub.middlewares, f.Middleware)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestIntercept(t *testing.T) {
+	ctx := context.Background()
+	var logs []string
+	errNotFound := errors.New("not found")
+	record := func(ctx context.Context, ev event.Event, next event.Subscriber) error {
+		err := next.Handle(ctx, ev)
+		logs = append(logs, fmt.Sprint("record ", ev, " ", err))
+		return err
+	}
+	translate := func(ctx context.Context, ev event.Event, next event.Subscriber) error {
+		if err := next.Handle(ctx, ev); err != nil && err.Error() == "handle error" {
+			return errNotFound
+		}
+		return nil
+	}
+	sub1 := &logged{}
+	sub := event.Intercept(event.Ordered{sub1, suberr{}}, record, translate)
+	if err, expected := sub.Handle(ctx, eventCreated(1)), errNotFound; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := []string{"record 1 not found"}; !reflect.DeepEqual(logs, expected) {
+		t.Errorf("logs: expected %v, got %v", expected, logs)
+	}
+}
+
+func TestMuxInterceptor(t *testing.T) {
+	ctx := context.Background()
+	var logs []string
+	retry := func(ctx context.Context, ev event.Event, next event.Subscriber) error {
+		err := next.Handle(ctx, ev)
+		for i := 0; err != nil && i < 2; i++ {
+			logs = append(logs, fmt.Sprint("retry ", ev))
+			err = next.Handle(ctx, ev)
+		}
+		return err
+	}
+	middleware := func(sub event.Subscriber) event.Subscriber {
+		return event.Func(func(ctx context.Context, ev event.Event) error {
+			logs = append(logs, fmt.Sprint("middleware ", ev))
+			return sub.Handle(ctx, ev)
+		})
+	}
+	sub1 := &logged{}
+	pub := event.NewMux(
+		event.MuxInterceptor(retry),
+		event.MuxMiddleware(middleware),
+	).On(eventTypeCreated, sub1).On(eventTypeUpdated, suberr{})
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := pub.Publish(ctx, eventUpdated(2)), "handle error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	expected := []string{
+		"middleware 1",
+		"middleware 2", "retry 2", "middleware 2", "retry 2", "middleware 2",
+	}
+	if !reflect.DeepEqual(logs, expected) {
+		t.Errorf("logs: expected %v, got %v", expected, logs)
+	}
+}
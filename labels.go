@@ -0,0 +1,52 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+)
+
+func labeled(sub Subscriber) Subscriber {
+	name := subscriberName(sub)
+	return Func(func(ctx context.Context, ev Event) (err error) {
+		typ, ok := TypeName(ev.Type())
+		if !ok {
+			typ = strconv.Itoa(int(ev.Type()))
+		}
+		pprof.Do(ctx, pprof.Labels("event_type", typ, "subscriber", name), func(ctx context.Context) {
+			err = sub.Handle(ctx, ev)
+		})
+		return
+	})
+}
+
+// wrapper is a subscriber wrapping another subscriber, which is named after the
+// wrapped subscriber, so that the wrappers do not collapse the names in the
+// labels and the statistics.
+type wrapper struct {
+	Func
+	subscriber Subscriber
+}
+
+// subscriberName returns the name of the subscriber by the Name() string
+// method, the function name of Func without the package path, or the type
+// name. The wrappers are named after the wrapped subscribers.
+func subscriberName(sub Subscriber) string {
+	if w, ok := sub.(*wrapper); ok {
+		return subscriberName(w.subscriber)
+	}
+	if n, ok := sub.(interface{ Name() string }); ok && n.Name() != "" {
+		return n.Name()
+	}
+	if f, ok := sub.(Func); ok {
+		name := strings.TrimSuffix(runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name(), "-fm")
+		if name != "" {
+			return name[strings.LastIndexByte(name, '/')+1:]
+		}
+	}
+	return fmt.Sprintf("%T", sub)
+}
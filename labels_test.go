@@ -0,0 +1,37 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestMuxLabels(t *testing.T) {
+	ctx := context.Background()
+	event.MustRegisterType(eventTypeUpdated, "Updated")
+	var labels []map[string]string
+	record := func(ctx context.Context, _ event.Event) error {
+		m := make(map[string]string)
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			m[key] = value
+			return true
+		})
+		labels = append(labels, m)
+		return nil
+	}
+	pub := event.NewMux(event.MuxLabels()).
+		On(eventTypeCreated, event.Build(event.Func(record)).Named("record")).
+		On(eventTypeUpdated, event.Func(record))
+	_ = pub.Publish(ctx, eventCreated(1))
+	_ = pub.Publish(ctx, eventUpdated(1))
+	expected := []map[string]string{
+		{"event_type": "0", "subscriber": "record"},
+		{"event_type": "Updated", "subscriber": "event-go_test.TestMuxLabels.func1"},
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected %v, got %v", expected, labels)
+	}
+}
@@ -0,0 +1,544 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Lanes is an event publisher to handle the events in background by the lanes
+// selected by the event types. Each lane has its own queue capacity and
+// workers, so that the bulk events never starve the critical events.
+type Lanes struct {
+	subscriber Subscriber
+	onError    func(error)
+	mu         sync.RWMutex
+	types      map[Type]*lane
+	fallback   *lane
+	lanes      []*lane
+	quiescing  bool
+	pause      *lanesPause
+	closed     chan struct{}
+	closeOnce  sync.Once
+	senders    sync.WaitGroup
+	done       chan struct{}
+	wg         sync.WaitGroup
+	handoff    Publisher
+	handedOff  int64
+	meta       Publisher
+	stop       chan struct{}
+	stopOnce   sync.Once
+	group      *Group
+	clock      Clock
+}
+
+type lane struct {
+	name    string
+	events  chan bufferedEvent
+	mu      sync.Mutex
+	spill   Spill
+	spilled int
+	wake    chan struct{}
+	closing chan struct{}
+	drain   chan struct{}
+	stopped chan struct{}
+	pending *bufferedEvent
+}
+
+// lanesPause is the state of pausing the workers. The paused channel is closed
+// on pausing, and the resumed channel is closed on resuming.
+type lanesPause struct {
+	paused  chan struct{}
+	resumed chan struct{}
+}
+
+func newLanesPause() *lanesPause {
+	return &lanesPause{paused: make(chan struct{}), resumed: make(chan struct{})}
+}
+
+// Spill is the interface for the overflow storage of a lane, like a temporary
+// file on the disk. The events are pushed on the full queue of the lane, and
+// popped in the same order when the queue drains. The methods are not called
+// concurrently.
+type Spill interface {
+	// Push the event to the end of the storage.
+	Push(Event) error
+	// Pop the event from the start of the storage, or return false if the
+	// storage is empty.
+	Pop() (Event, bool, error)
+}
+
+// ErrLanesClosed is the error returned on publishing to the closed lanes.
+var ErrLanesClosed = errors.New("lanes closed")
+
+// NewLanes creates a new publisher to handle the events by the subscriber.
+func NewLanes(sub Subscriber) *Lanes {
+	return &Lanes{
+		subscriber: sub, types: make(map[Type]*lane), pause: newLanesPause(),
+		closed: make(chan struct{}), done: make(chan struct{}), stop: make(chan struct{}),
+	}
+}
+
+// Lane adds a lane with the capacity of the queue and the number of the
+// workers, for the events of the types. The lane without the types is the
+// default lane for the events of the other types. This method returns the
+// publisher to allow method chaining.
+func (pub *Lanes) Lane(name string, capacity, workers int, types ...Type) *Lanes {
+	l := &lane{name: name, events: make(chan bufferedEvent, capacity), drain: make(chan struct{})}
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	pub.lanes = append(pub.lanes, l)
+	if len(types) == 0 {
+		pub.fallback = l
+	}
+	for _, typ := range types {
+		pub.types[typ] = l
+	}
+	pub.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		spawnIn(pub.group, func() { pub.work(l) })
+	}
+	return pub
+}
+
+// Spill sets the overflow storage of the lane. The events published on the
+// full queue are pushed to the storage instead of blocking, and moved back to
+// the queue when it drains, trading the latency for no drops during spikes.
+// The events are kept in order, but the traces of the spilled events are not
+// kept. Set the storage before publishing the events. This method returns the
+// publisher to allow method chaining.
+func (pub *Lanes) Spill(name string, spill Spill) *Lanes {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	for _, l := range pub.lanes {
+		if l.name == name && l.spill == nil {
+			l.spill = spill
+			l.wake, l.closing = make(chan struct{}, 1), make(chan struct{})
+			l.stopped = make(chan struct{})
+			spawnIn(pub.group, func() { pub.refill(l) })
+		}
+	}
+	return pub
+}
+
+// Handoff sets the publisher to hand off the undelivered events when the
+// context of Close is done before handling all the events, like a durable
+// outbox, so that the restarts do not rely only on draining fast enough. The
+// events being handled are not handed off. This method returns the publisher
+// to allow method chaining.
+func (pub *Lanes) Handoff(to Publisher) *Lanes {
+	pub.handoff = to
+	return pub
+}
+
+// Clock sets the clock to measure the elapsed time of quiescing instead of the
+// global clock. This method returns the publisher to allow method chaining.
+func (pub *Lanes) Clock(c Clock) *Lanes {
+	pub.clock = c
+	return pub
+}
+
+// Group sets the group to own the goroutines of the workers, so that waiting
+// for the group waits for the workers to handle the queued events. Set the
+// group before adding the lanes, and close the lanes before waiting for the
+// group. The lanes are closed when the context of the group is done. This
+// method returns the publisher to allow method chaining.
+func (pub *Lanes) Group(g *Group) *Lanes {
+	pub.group = g
+	g.Go(func() error {
+		select {
+		case <-g.Context().Done():
+			return pub.Close(context.Background())
+		case <-pub.done:
+			return nil
+		}
+	})
+	return pub
+}
+
+// Meta sets the publisher to publish a QueueOverflowed meta event on each event
+// published to the full queue of a lane, which is blocked or spilled. This
+// method returns the publisher to allow method chaining.
+func (pub *Lanes) Meta(meta Publisher) *Lanes {
+	pub.meta = meta
+	return pub
+}
+
+// ErrorHandler sets the function to report the errors on handling the events.
+// The errors are ignored by default. This method returns the publisher to allow
+// method chaining.
+func (pub *Lanes) ErrorHandler(f func(error)) *Lanes {
+	pub.onError = f
+	return pub
+}
+
+// Pause stops the workers from handling the queued events until Resume, like
+// during the maintenance of a downstream dependency. The events are still
+// queued, and spilled when the lane has the overflow storage, so the publishing
+// blocks once the queue gets full. The events being handled are not waited for.
+// Closing the paused lanes waits for Resume, or hands off the queued events
+// when the context is done.
+func (pub *Lanes) Pause() {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	select {
+	case <-pub.pause.paused:
+	default:
+		close(pub.pause.paused)
+	}
+}
+
+// Resume restarts the workers stopped by Pause.
+func (pub *Lanes) Resume() {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	select {
+	case <-pub.pause.paused:
+		close(pub.pause.resumed)
+		pub.pause = newLanesPause()
+	default:
+	}
+}
+
+func (pub *Lanes) work(l *lane) {
+	defer pub.wg.Done()
+	for {
+		pub.mu.RLock()
+		pause := pub.pause
+		pub.mu.RUnlock()
+		select {
+		case <-pub.stop:
+			return
+		case <-pause.paused:
+			select {
+			case <-pause.resumed:
+				continue
+			case <-pub.stop:
+				return
+			}
+		default:
+		}
+		select {
+		case <-pub.stop:
+			return
+		case <-pause.paused:
+		case ev := <-l.events:
+			pub.handle(ev)
+		case <-l.drain:
+			if len(l.events) == 0 {
+				return
+			}
+		}
+	}
+}
+
+func (pub *Lanes) handle(ev bufferedEvent) {
+	ctx := context.Background()
+	if ev.trace != nil {
+		ctx = context.WithValue(ctx, traceKey{}, ev.trace)
+	}
+	if err := pub.subscriber.Handle(ctx, ev.event); err != nil && pub.onError != nil {
+		pub.onError(err)
+	}
+}
+
+// refill moves the spilled events back to the queue, and lets the workers
+// drain the queue after moving all the events on closing the lanes. On handing
+// off the events, the event being moved is kept pending.
+func (pub *Lanes) refill(l *lane) {
+	defer close(l.stopped)
+	var closing bool
+	for {
+		select {
+		case <-l.wake:
+		case <-l.closing:
+			closing = true
+		}
+		for {
+			l.mu.Lock()
+			if l.spilled == 0 {
+				l.mu.Unlock()
+				break
+			}
+			ev, ok, err := l.spill.Pop()
+			if !ok || err != nil {
+				l.spilled = 0
+				l.mu.Unlock()
+				if err != nil && pub.onError != nil {
+					pub.onError(err)
+				}
+				break
+			}
+			l.mu.Unlock()
+			select {
+			case l.events <- bufferedEvent{ev, nil}:
+			case <-pub.stop:
+				l.mu.Lock()
+				l.pending = &bufferedEvent{ev, nil}
+				l.mu.Unlock()
+				return
+			}
+			l.mu.Lock()
+			l.spilled--
+			l.mu.Unlock()
+		}
+		if closing {
+			close(l.drain)
+			return
+		}
+	}
+}
+
+// Handle implements Subscriber for Lanes.
+func (pub *Lanes) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Lanes. The event is queued to the lane of
+// the event type, and this method blocks while the queue is full until the
+// context is done or the lanes are closed, unless the lane has the overflow
+// storage. The events
+// without the lane are reported as UnhandledError.
+func (pub *Lanes) Publish(ctx context.Context, ev Event) error {
+	pub.mu.RLock()
+	select {
+	case <-pub.closed:
+		pub.mu.RUnlock()
+		return pub.closedErr()
+	default:
+	}
+	l, ok := pub.types[ev.Type()]
+	if !ok {
+		if l = pub.fallback; l == nil {
+			pub.mu.RUnlock()
+			return &UnhandledError{ev}
+		}
+	}
+	pub.senders.Add(1)
+	pub.mu.RUnlock()
+	defer pub.senders.Done()
+	if l.spill != nil {
+		spilled, err := l.publish(bufferedEvent{ev, TraceFromContext(ctx)})
+		if spilled {
+			pub.overflowed(ctx, l, ev)
+		}
+		return err
+	}
+	select {
+	case l.events <- bufferedEvent{ev, TraceFromContext(ctx)}:
+		return nil
+	default:
+	}
+	pub.overflowed(ctx, l, ev)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-pub.closed:
+		return pub.closedErr()
+	case l.events <- bufferedEvent{ev, TraceFromContext(ctx)}:
+		return nil
+	}
+}
+
+func (pub *Lanes) closedErr() error {
+	pub.mu.RLock()
+	defer pub.mu.RUnlock()
+	if pub.quiescing {
+		return ErrQuiescing
+	}
+	return ErrLanesClosed
+}
+
+// publish queues the event, or pushes the event to the overflow storage while
+// the queue is full or the spilled events remain. This method reports whether
+// the event is pushed to the storage.
+func (l *lane) publish(ev bufferedEvent) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.spilled == 0 {
+		select {
+		case l.events <- ev:
+			return false, nil
+		default:
+		}
+	}
+	if err := l.spill.Push(ev.event); err != nil {
+		return false, err
+	}
+	l.spilled++
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+	return true, nil
+}
+
+// overflowed publishes a QueueOverflowed meta event to the meta publisher. The
+// meta events are not reported again, not to publish the meta events
+// infinitely.
+func (pub *Lanes) overflowed(ctx context.Context, l *lane, ev Event) {
+	if pub.meta != nil && ev.Type() != TypeMeta {
+		_ = pub.meta.Publish(ctx, &QueueOverflowed{l.name, ev})
+	}
+}
+
+// len returns the number of the queued and the spilled events.
+func (l *lane) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.events) + l.spilled
+}
+
+// Len returns the number of the queued events of the lane, including the
+// spilled events.
+func (pub *Lanes) Len(name string) int {
+	pub.mu.RLock()
+	defer pub.mu.RUnlock()
+	for _, l := range pub.lanes {
+		if l.name == name {
+			return l.len()
+		}
+	}
+	return 0
+}
+
+// Close stops accepting the events, and waits for the queued events to be
+// handled until the context is done. The publishing blocked on the full queue
+// returns ErrLanesClosed. When the context is done, the events not handled yet
+// are handed off to the publisher set by Handoff, without waiting for the
+// events being handled.
+func (pub *Lanes) Close(ctx context.Context) error {
+	pub.close()
+	select {
+	case <-ctx.Done():
+		if pub.handoff == nil {
+			return ctx.Err()
+		}
+		return pub.handOff()
+	case <-pub.done:
+		return nil
+	}
+}
+
+// Snapshot stops accepting the events like Close, but hands off the queued
+// events to the publisher set by Handoff without handling them, which is
+// useful to persist the undelivered events quickly on shutdown, like to
+// eventqueue.Queue to deliver them after restarts. This method waits for the
+// events being handled until the context is done.
+func (pub *Lanes) Snapshot(ctx context.Context) error {
+	if pub.handoff == nil {
+		return errors.New("handoff publisher not set")
+	}
+	pub.close()
+	err := pub.handOff()
+	select {
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	case <-pub.done:
+	}
+	return err
+}
+
+// close stops accepting the events, and lets the workers drain the queues.
+func (pub *Lanes) close() {
+	pub.closeOnce.Do(func() {
+		pub.mu.Lock()
+		close(pub.closed)
+		lanes := pub.lanes
+		pub.mu.Unlock()
+		spawnIn(pub.group, func() {
+			pub.senders.Wait()
+			for _, l := range lanes {
+				if l.spill != nil {
+					close(l.closing)
+				} else {
+					close(l.drain)
+				}
+			}
+			pub.wg.Wait()
+			close(pub.done)
+		})
+	})
+}
+
+// handOff stops the workers and hands off the queued, the pending, and the
+// spilled events in order.
+func (pub *Lanes) handOff() error {
+	pub.stopOnce.Do(func() { close(pub.stop) })
+	pub.senders.Wait()
+	pub.mu.RLock()
+	defer pub.mu.RUnlock()
+	var err error
+	for _, l := range pub.lanes {
+		if l.spill != nil {
+			<-l.stopped
+		}
+		var evs []bufferedEvent
+	drain:
+		for {
+			select {
+			case ev := <-l.events:
+				evs = append(evs, ev)
+			default:
+				break drain
+			}
+		}
+		l.mu.Lock()
+		if l.pending != nil {
+			evs = append(evs, *l.pending)
+			l.pending = nil
+			l.spilled--
+		}
+		for ; l.spilled > 0; l.spilled-- {
+			ev, ok, e := l.spill.Pop()
+			if !ok || e != nil {
+				l.spilled = 0
+				if e != nil {
+					err = e
+				}
+				break
+			}
+			evs = append(evs, bufferedEvent{ev, nil})
+		}
+		l.mu.Unlock()
+		for _, ev := range evs {
+			if e := ev.publish(context.Background(), pub.handoff); e != nil {
+				err = e
+				continue
+			}
+			atomic.AddInt64(&pub.handedOff, 1)
+		}
+	}
+	return err
+}
+
+// Quiesce implements Quiescer for Lanes. This method closes the lanes, and
+// the publishing returns ErrQuiescing instead of ErrLanesClosed.
+func (pub *Lanes) Quiesce(ctx context.Context) (DrainSummary, error) {
+	clock := clockOr(pub.clock)
+	start := clock.Now()
+	pub.mu.Lock()
+	pub.quiescing = true
+	queued, handedOff := pub.queued(), atomic.LoadInt64(&pub.handedOff)
+	pub.mu.Unlock()
+	err := pub.Close(ctx)
+	pub.mu.RLock()
+	remaining := pub.queued()
+	pub.mu.RUnlock()
+	handedOff = atomic.LoadInt64(&pub.handedOff) - handedOff
+	return DrainSummary{
+		Drained: queued - remaining - int(handedOff), Remaining: remaining,
+		HandedOff: int(handedOff), Elapsed: clock.Now().Sub(start),
+	}, err
+}
+
+func (pub *Lanes) queued() int {
+	var n int
+	for _, l := range pub.lanes {
+		n += l.len()
+	}
+	return n
+}
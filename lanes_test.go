@@ -0,0 +1,515 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestLanes(t *testing.T) {
+	ctx := context.Background()
+	block := make(chan struct{})
+	handled := make(chan event.Event, 10)
+	var mu sync.Mutex
+	var errs []error
+	pub := event.NewLanes(event.Func(func(_ context.Context, ev event.Event) error {
+		if _, ok := ev.(eventUpdated); ok {
+			<-block
+		}
+		handled <- ev
+		if _, ok := ev.(eventDeleted); ok {
+			return errors.New("handle error")
+		}
+		return nil
+	})).
+		Lane("critical", 1, 1, eventTypeCreated, eventTypeDeleted).
+		Lane("bulk", 1, 1, eventTypeUpdated).
+		ErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		})
+	for _, ev := range []event.Event{eventUpdated(1), eventUpdated(2)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err, expected := pub.Publish(cctx, eventUpdated(3)), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if got, expected := pub.Len("bulk"), 1; got != expected {
+		t.Errorf("expected %d queued events, got %d", expected, got)
+	}
+	if got, expected := pub.Len("unknown"), 0; got != expected {
+		t.Errorf("expected %d queued events, got %d", expected, got)
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventDeleted(2)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if got := <-handled; got != ev {
+			t.Errorf("expected %v, got %v", ev, got)
+		}
+	}
+	var uerr *event.UnhandledError
+	if err := pub.Publish(ctx, eventOther(1)); !errors.As(err, &uerr) {
+		t.Errorf("expected an unhandled error, got %v", err)
+	}
+	cctx, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err, expected := pub.Close(cctx), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	close(block)
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, expected := len(handled), 2; got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+	if err, expected := pub.Handle(ctx, eventCreated(1)), event.ErrLanesClosed; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if len(errs) != 1 || errs[0].Error() != "handle error" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestLanesCloseReentrant(t *testing.T) {
+	ctx := context.Background()
+	started, release := make(chan struct{}), make(chan struct{})
+	errs := make(chan error, 2)
+	var pub *event.Lanes
+	pub = event.NewLanes(event.Func(func(ctx context.Context, ev event.Event) error {
+		if ev == eventCreated(1) {
+			close(started)
+			<-release
+			errs <- pub.Publish(ctx, eventCreated(10))
+		}
+		return nil
+	})).Lane("default", 1, 1)
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	<-started
+	go func() { errs <- pub.Publish(ctx, eventCreated(3)) }()
+	time.Sleep(10 * time.Millisecond)
+	closed := make(chan error)
+	go func() {
+		cctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		closed <- pub.Close(cctx)
+	}()
+	if err, expected := <-errs, event.ErrLanesClosed; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	close(release)
+	if err, expected := <-errs, event.ErrLanesClosed; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestLanesPause(t *testing.T) {
+	ctx := context.Background()
+	handled := make(chan event.Event, 10)
+	outbox := &logged{}
+	pub := event.NewLanes(event.Func(func(_ context.Context, ev event.Event) error {
+		handled <- ev
+		return nil
+	})).
+		Lane("default", 2, 1).
+		Handoff(event.Func(outbox.Handle))
+	pub.Pause()
+	pub.Pause()
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got, expected := pub.Len("default"), 2; got != expected || len(handled) != 0 {
+		t.Errorf("expected %d queued events, got %d", expected, got)
+	}
+	pub.Resume()
+	pub.Resume()
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2)} {
+		if got := <-handled; got != ev {
+			t.Errorf("expected %v, got %v", ev, got)
+		}
+	}
+	pub.Pause()
+	if err := pub.Publish(ctx, eventCreated(3)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := pub.Close(cctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(3)}; !reflect.DeepEqual(outbox.Events(), expected) {
+		t.Errorf("handed off events: expected %v, got %v", expected, outbox.Events())
+	}
+	if len(handled) != 0 {
+		t.Errorf("expected no handled events, got %d", len(handled))
+	}
+}
+
+func TestLanesGroup(t *testing.T) {
+	g, ctx := event.NewGroup(context.Background())
+	var mu sync.Mutex
+	var handled []event.Event
+	pub := event.NewLanes(event.Func(func(_ context.Context, ev event.Event) error {
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, ev)
+		return nil
+	})).
+		Group(g).
+		Lane("default", 10, 1).
+		Spill("default", &sliceSpill{})
+	evs := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)}
+	for _, ev := range evs {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	g.Go(func() error { return errors.New("group error") })
+	if err, expected := g.Wait(), "group error"; err == nil || err.Error() != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if !reflect.DeepEqual(handled, evs) {
+		t.Errorf("expected %v, got %v", evs, handled)
+	}
+	if err, expected := pub.Publish(ctx, eventCreated(4)), event.ErrLanesClosed; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	g, ctx = event.NewGroup(context.Background())
+	pub = event.NewLanes(event.Discard).Group(g).Lane("default", 1, 1)
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestLanesTrace(t *testing.T) {
+	ctx := context.Background()
+	var traces []*event.Trace
+	pub := event.NewLanes(event.Func(func(ctx context.Context, _ event.Event) error {
+		traces = append(traces, event.TraceFromContext(ctx))
+		return nil
+	})).Lane("default", 1, 1)
+	if err := event.NewTracer(pub).Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(traces) != 1 || traces[0] == nil {
+		t.Errorf("unexpected traces: %v", traces)
+	}
+}
+
+type sliceSpill []event.Event
+
+func (s *sliceSpill) Push(ev event.Event) error {
+	*s = append(*s, ev)
+	return nil
+}
+
+func (s *sliceSpill) Pop() (event.Event, bool, error) {
+	if len(*s) == 0 {
+		return nil, false, nil
+	}
+	ev := (*s)[0]
+	*s = (*s)[1:]
+	return ev, true, nil
+}
+
+// failingSpill fails to push the deleted events, and fails to pop the events
+// after popping n events.
+type failingSpill struct {
+	sliceSpill
+	n int
+}
+
+func (s *failingSpill) Push(ev event.Event) error {
+	if ev.Type() == eventTypeDeleted {
+		return errors.New("push error")
+	}
+	return s.sliceSpill.Push(ev)
+}
+
+func (s *failingSpill) Pop() (event.Event, bool, error) {
+	if s.n == 0 {
+		return nil, false, errors.New("pop error")
+	}
+	s.n--
+	return s.sliceSpill.Pop()
+}
+
+func TestLanesSpill(t *testing.T) {
+	ctx := context.Background()
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var handled []event.Event
+	pub := event.NewLanes(event.Func(func(_ context.Context, ev event.Event) error {
+		<-block
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, ev)
+		return nil
+	})).Lane("default", 1, 1).Spill("default", &sliceSpill{})
+	var evs []event.Event
+	for i := 0; i < 100; i++ {
+		ev := event.Event(eventCreated(i))
+		if i%3 == 0 {
+			ev = eventUpdated(i)
+		}
+		evs = append(evs, ev)
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if i == 50 {
+			close(block)
+		}
+	}
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := pub.Len("default"); got != 0 {
+		t.Errorf("expected no queued events, got %d", got)
+	}
+	if !reflect.DeepEqual(handled, evs) {
+		t.Errorf("expected %v, got %v", evs, handled)
+	}
+}
+
+func TestLanesHandoff(t *testing.T) {
+	ctx := context.Background()
+	block, handling := make(chan struct{}), make(chan struct{}, 10)
+	defer close(block)
+	outbox := &logged{}
+	pub := event.NewLanes(event.Func(func(context.Context, event.Event) error {
+		handling <- struct{}{}
+		<-block
+		return nil
+	})).
+		Lane("critical", 1, 1, eventTypeCreated).
+		Lane("bulk", 2, 1, eventTypeUpdated).
+		Spill("bulk", &sliceSpill{}).
+		Handoff(event.Func(outbox.Handle))
+	var expected []event.Event
+	for i := 0; i < 6; i++ {
+		if err := pub.Publish(ctx, eventUpdated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if i == 0 {
+			<-handling
+		} else {
+			expected = append(expected, eventUpdated(i))
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if i == 0 {
+			<-handling
+		} else {
+			expected = append([]event.Event{eventCreated(i)}, expected...)
+		}
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	s, err := event.Quiesce(cctx, pub)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if s.HandedOff != 6 || s.Drained != 0 || s.Remaining != 0 {
+		t.Errorf("unexpected summary: %v", s)
+	}
+	if !reflect.DeepEqual(outbox.Events(), expected) {
+		t.Errorf("handed off events: expected %v, got %v", expected, outbox.Events())
+	}
+}
+
+func TestLanesSnapshot(t *testing.T) {
+	ctx := context.Background()
+	handling, release := make(chan struct{}), make(chan struct{})
+	var handled []event.Event
+	outbox := &logged{}
+	pub := event.NewLanes(event.Func(func(_ context.Context, ev event.Event) error {
+		close(handling)
+		<-release
+		handled = append(handled, ev)
+		return nil
+	})).
+		Lane("default", 3, 1)
+	if err := pub.Snapshot(ctx); err == nil {
+		t.Fatalf("expected an error without the handoff publisher")
+	}
+	pub.Handoff(event.Func(outbox.Handle))
+	for i := 1; i <= 3; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if i == 1 {
+			<-handling
+		}
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	if err := pub.Snapshot(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(handled, expected) {
+		t.Errorf("handled events: expected %v, got %v", expected, handled)
+	}
+	if expected := []event.Event{eventCreated(2), eventCreated(3)}; !reflect.DeepEqual(outbox.Events(), expected) {
+		t.Errorf("handed off events: expected %v, got %v", expected, outbox.Events())
+	}
+	if err, expected := pub.Publish(ctx, eventCreated(4)), event.ErrLanesClosed; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestLanesMeta(t *testing.T) {
+	ctx := context.Background()
+	block, handling := make(chan struct{}), make(chan struct{}, 10)
+	metas := &logged{}
+	pub := event.NewLanes(event.Func(func(context.Context, event.Event) error {
+		handling <- struct{}{}
+		<-block
+		return nil
+	})).
+		Lane("critical", 1, 1, eventTypeCreated).
+		Lane("bulk", 1, 1, eventTypeUpdated).
+		Spill("bulk", &sliceSpill{}).
+		Meta(event.Func(metas.Handle))
+	for i := 1; i <= 2; i++ {
+		for _, ev := range []event.Event{eventCreated(i), eventUpdated(i)} {
+			if err := pub.Publish(ctx, ev); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+		}
+		if i == 1 {
+			<-handling
+			<-handling
+		}
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err, expected := pub.Publish(cctx, eventCreated(3)), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := pub.Publish(ctx, eventUpdated(3)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	close(block)
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := []event.Event{
+		&event.QueueOverflowed{Queue: "critical", Event: eventCreated(3)},
+		&event.QueueOverflowed{Queue: "bulk", Event: eventUpdated(3)},
+	}
+	if !reflect.DeepEqual(metas.Events(), expected) {
+		t.Errorf("meta events: expected %v, got %v", expected, metas.Events())
+	}
+}
+
+func TestLanesSpillError(t *testing.T) {
+	ctx := context.Background()
+	block, handling := make(chan struct{}), make(chan struct{}, 10)
+	var mu sync.Mutex
+	var errs []error
+	sub1 := &logged{}
+	pub := event.NewLanes(event.Func(func(ctx context.Context, ev event.Event) error {
+		handling <- struct{}{}
+		<-block
+		return sub1.Handle(ctx, ev)
+	})).
+		Lane("default", 1, 1).
+		Spill("default", &failingSpill{}).
+		ErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		})
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2), eventDeleted(3), eventCreated(4)} {
+		err := pub.Publish(ctx, ev)
+		if ev == eventDeleted(3) {
+			if expected := "push error"; err == nil || err.Error() != expected {
+				t.Fatalf("expected %v, got %v", expected, err)
+			}
+		} else if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if ev == eventCreated(1) {
+			<-handling
+		}
+	}
+	close(block)
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(2)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("expected %v, got %v", expected, sub1.Events())
+	}
+	if len(errs) != 1 || errs[0].Error() != "pop error" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestLanesHandoffError(t *testing.T) {
+	ctx := context.Background()
+	block, handling := make(chan struct{}), make(chan struct{}, 10)
+	defer close(block)
+	outbox := &logged{}
+	pub := event.NewLanes(event.Func(func(context.Context, event.Event) error {
+		handling <- struct{}{}
+		<-block
+		return nil
+	})).
+		Lane("default", 1, 1).
+		Spill("default", &failingSpill{n: 1}).
+		Handoff(event.Func(func(ctx context.Context, ev event.Event) error {
+			if ev == eventCreated(2) {
+				return errors.New("handoff error")
+			}
+			return outbox.Handle(ctx, ev)
+		}))
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2), eventCreated(3), eventCreated(4)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if ev == eventCreated(1) {
+			<-handling
+		}
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err, expected := pub.Close(cctx), "handoff error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []event.Event{eventCreated(3)}; !reflect.DeepEqual(outbox.Events(), expected) {
+		t.Errorf("handed off events: expected %v, got %v", expected, outbox.Events())
+	}
+}
@@ -0,0 +1,67 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Lazy is an event subscriber to defer the initialization of the subscriber
+// until the first event, which is useful to improve the startup time of the
+// applications with the rarely handled events requiring expensive resources.
+type Lazy struct {
+	init       func(context.Context) (Subscriber, error)
+	retryAfter time.Duration
+	clock      Clock
+	mu         sync.Mutex
+	subscriber Subscriber
+	err        error
+	failed     time.Time
+}
+
+// NewLazy creates a new lazily initialized subscriber.
+func NewLazy(init func(context.Context) (Subscriber, error)) *Lazy {
+	return &Lazy{init: init}
+}
+
+// RetryAfter sets the duration to cache the initialization error. The error is
+// not cached by default, so the initialization is retried on the next event.
+// This method returns the subscriber to allow method chaining.
+func (sub *Lazy) RetryAfter(d time.Duration) *Lazy {
+	sub.retryAfter = d
+	return sub
+}
+
+// Clock sets the clock to expire the cached initialization error instead of the
+// global clock. This method returns the subscriber to allow method chaining.
+func (sub *Lazy) Clock(c Clock) *Lazy {
+	sub.clock = c
+	return sub
+}
+
+// Handle implements Subscriber for Lazy.
+func (sub *Lazy) Handle(ctx context.Context, ev Event) error {
+	s, err := sub.get(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Handle(ctx, ev)
+}
+
+func (sub *Lazy) get(ctx context.Context) (Subscriber, error) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.subscriber != nil {
+		return sub.subscriber, nil
+	}
+	if sub.err != nil && clockOr(sub.clock).Now().Sub(sub.failed) < sub.retryAfter {
+		return nil, sub.err
+	}
+	s, err := sub.init(ctx)
+	if err != nil {
+		sub.err, sub.failed = err, clockOr(sub.clock).Now()
+		return nil, err
+	}
+	sub.subscriber, sub.err = s, nil
+	return s, nil
+}
@@ -0,0 +1,73 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestLazy(t *testing.T) {
+	ctx := context.Background()
+	var inits int
+	sub1 := &logged{}
+	sub := event.NewLazy(func(context.Context) (event.Subscriber, error) {
+		inits++
+		return sub1, nil
+	})
+	if inits != 0 {
+		t.Fatalf("expected no initialization, got %d", inits)
+	}
+	evs := []event.Event{eventCreated(1), eventCreated(2)}
+	for _, ev := range evs {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := 1; inits != expected {
+		t.Errorf("expected %d initialization, got %d", expected, inits)
+	}
+	if !reflect.DeepEqual(sub1.Events(), evs) {
+		t.Errorf("sub1 handled events: expected %v, got %v", evs, sub1.Events())
+	}
+}
+
+func TestLazyError(t *testing.T) {
+	ctx := context.Background()
+	for _, retryAfter := range []time.Duration{0, 100 * time.Millisecond} {
+		clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+		var inits int
+		sub := event.NewLazy(func(context.Context) (event.Subscriber, error) {
+			if inits++; inits < 3 {
+				return nil, errors.New("init error")
+			}
+			return &logged{}, nil
+		}).RetryAfter(retryAfter).Clock(clock)
+		for i := 0; i < 3; i++ {
+			err := sub.Handle(ctx, eventCreated(i))
+			if i < 2 || retryAfter > 0 {
+				if expected := "init error"; err == nil || err.Error() != expected {
+					t.Fatalf("expected %v, got %v", expected, err)
+				}
+			} else if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+		}
+		if retryAfter > 0 {
+			if expected := 1; inits != expected {
+				t.Errorf("expected %d initializations, got %d", expected, inits)
+			}
+			clock.Advance(2 * retryAfter)
+			if err := sub.Handle(ctx, eventCreated(0)); err == nil {
+				t.Fatalf("expected an error")
+			}
+			if expected := 2; inits != expected {
+				t.Errorf("expected %d initializations, got %d", expected, inits)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service is the interface for a component with the lifecycle, like a
+// subscriber connecting to a message broker, or a database sink.
+type Service interface {
+	Start(context.Context) error
+	Stop(context.Context) error
+}
+
+// Lifecycle is a manager to start and stop the services in the order of their
+// dependencies. The services are started after their dependencies, and stopped
+// before their dependencies.
+type Lifecycle struct {
+	services []*service
+	timeout  time.Duration
+	mu       sync.Mutex
+	started  []*service
+}
+
+type service struct {
+	name      string
+	service   Service
+	dependsOn []string
+}
+
+// NewLifecycle creates a new lifecycle manager.
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// Add adds the service with the names of the services it depends on. This
+// method returns the manager to allow method chaining.
+func (l *Lifecycle) Add(name string, s Service, dependsOn ...string) *Lifecycle {
+	l.services = append(l.services, &service{name, s, dependsOn})
+	return l
+}
+
+// Timeout sets the timeout of starting and stopping each service. There is no
+// timeout by default. This method returns the manager to allow method chaining.
+func (l *Lifecycle) Timeout(d time.Duration) *Lifecycle {
+	l.timeout = d
+	return l
+}
+
+// Start starts the services in the order of the dependencies. When a service
+// fails to start, the services already started are stopped and the error is
+// returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	order, err := l.order()
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.started) > 0 {
+		return errors.New("lifecycle already started")
+	}
+	for _, s := range order {
+		if err := l.call(ctx, s.service.Start); err != nil {
+			err = fmt.Errorf("start %s: %w", s.name, err)
+			if e := l.stop(ctx); e != nil {
+				err = fmt.Errorf("%w; %v", err, e)
+			}
+			return err
+		}
+		l.started = append(l.started, s)
+	}
+	return nil
+}
+
+// Stop stops the started services in the reverse order of starting. All the
+// services are stopped even on errors, and the errors are joined.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs []string
+	for i := len(l.started) - 1; i >= 0; i-- {
+		s := l.started[i]
+		if err := l.call(ctx, s.service.Stop); err != nil {
+			errs = append(errs, "stop "+s.name+": "+err.Error())
+		}
+	}
+	l.started = nil
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (l *Lifecycle) call(ctx context.Context, f func(context.Context) error) error {
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+	return f(ctx)
+}
+
+func (l *Lifecycle) order() ([]*service, error) {
+	services := make(map[string]*service, len(l.services))
+	for _, s := range l.services {
+		if _, ok := services[s.name]; ok {
+			return nil, fmt.Errorf("duplicate service: %s", s.name)
+		}
+		services[s.name] = s
+	}
+	const (
+		visiting = iota + 1
+		visited
+	)
+	states := make(map[string]int, len(l.services))
+	order := make([]*service, 0, len(l.services))
+	var visit func(*service, []string) error
+	visit = func(s *service, path []string) error {
+		switch states[s.name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, s.name), " -> "))
+		case visited:
+			return nil
+		}
+		states[s.name] = visiting
+		for _, name := range s.dependsOn {
+			d, ok := services[name]
+			if !ok {
+				return fmt.Errorf("unknown dependency of %s: %s", s.name, name)
+			}
+			if err := visit(d, append(path, s.name)); err != nil {
+				return err
+			}
+		}
+		states[s.name] = visited
+		order = append(order, s)
+		return nil
+	}
+	for _, s := range l.services {
+		if err := visit(s, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,142 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+type lifecycleService struct {
+	name  string
+	log   *[]string
+	start func(context.Context) error
+	stop  error
+}
+
+func (s *lifecycleService) Start(ctx context.Context) error {
+	*s.log = append(*s.log, "start "+s.name)
+	if s.start != nil {
+		return s.start(ctx)
+	}
+	return nil
+}
+
+func (s *lifecycleService) Stop(context.Context) error {
+	*s.log = append(*s.log, "stop "+s.name)
+	return s.stop
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	l := event.NewLifecycle().
+		Add("projection", &lifecycleService{name: "projection", log: &log}, "db", "broker").
+		Add("db", &lifecycleService{name: "db", log: &log}).
+		Add("broker", &lifecycleService{name: "broker", log: &log}, "db").
+		Add("http", &lifecycleService{name: "http", log: &log}, "projection")
+	if err := l.Start(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := l.Stop(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	expected := []string{
+		"start db", "start broker", "start projection", "start http",
+		"stop http", "stop projection", "stop broker", "stop db",
+	}
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("expected %v, got %v", expected, log)
+	}
+}
+
+func TestLifecycleStartError(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	l := event.NewLifecycle().Timeout(10*time.Millisecond).
+		Add("db", &lifecycleService{name: "db", log: &log}).
+		Add("broker", &lifecycleService{name: "broker", log: &log, start: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}}, "db").
+		Add("projection", &lifecycleService{name: "projection", log: &log}, "broker")
+	err := l.Start(ctx)
+	if expected := "start broker: context deadline exceeded"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected to wrap %v", context.DeadlineExceeded)
+	}
+	if expected := []string{"start db", "start broker", "stop db"}; !reflect.DeepEqual(log, expected) {
+		t.Errorf("expected %v, got %v", expected, log)
+	}
+}
+
+func TestLifecycleStopError(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	l := event.NewLifecycle().
+		Add("db", &lifecycleService{name: "db", log: &log, stop: errors.New("db error")}).
+		Add("broker", &lifecycleService{name: "broker", log: &log, stop: errors.New("broker error")}, "db")
+	if err := l.Start(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := l.Start(ctx), "lifecycle already started"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err, expected := l.Stop(ctx), "stop broker: broker error; stop db: db error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	l.Add("projection", &lifecycleService{name: "projection", log: &log, start: func(context.Context) error {
+		return errors.New("projection error")
+	}}, "broker")
+	err := l.Start(ctx)
+	if expected := "start projection: projection error; stop broker: broker error; stop db: db error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	expected := []string{
+		"start db", "start broker", "stop broker", "stop db",
+		"start db", "start broker", "start projection", "stop broker", "stop db",
+	}
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("expected %v, got %v", expected, log)
+	}
+}
+
+func TestLifecycleDependencyError(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	testCases := []struct {
+		lifecycle *event.Lifecycle
+		expected  string
+	}{
+		{
+			event.NewLifecycle().
+				Add("a", &lifecycleService{name: "a", log: &log}, "b").
+				Add("b", &lifecycleService{name: "b", log: &log}, "c").
+				Add("c", &lifecycleService{name: "c", log: &log}, "a"),
+			"dependency cycle: a -> b -> c -> a",
+		},
+		{
+			event.NewLifecycle().Add("a", &lifecycleService{name: "a", log: &log}, "b"),
+			"unknown dependency of a: b",
+		},
+		{
+			event.NewLifecycle().
+				Add("a", &lifecycleService{name: "a", log: &log}).
+				Add("a", &lifecycleService{name: "a", log: &log}),
+			"duplicate service: a",
+		},
+	}
+	for _, tc := range testCases {
+		if err := tc.lifecycle.Start(ctx); err == nil || err.Error() != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, err)
+		}
+	}
+	if len(log) != 0 {
+		t.Errorf("expected no services started, got %v", log)
+	}
+}
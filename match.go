@@ -0,0 +1,40 @@
+package event
+
+import "context"
+
+// Metadata is the interface for an event with metadata, like the tenant, the
+// region or the source of the event.
+type Metadata interface {
+	Event
+	Metadata() map[string]string
+}
+
+// Matcher is a predicate of events to filter the events on subscribing.
+type Matcher func(Event) bool
+
+// MetaEquals returns a matcher of the events with the metadata of the key
+// equal to the value. The events not implementing Metadata never match.
+func MetaEquals(key, value string) Matcher {
+	return func(ev Event) bool {
+		if ev, ok := ev.(Metadata); ok {
+			v, ok := ev.Metadata()[key]
+			return ok && v == value
+		}
+		return false
+	}
+}
+
+// OnMatch registers the subscriber to listen on the events of the type matching
+// the matcher, so that the subscriber does not need to filter the events by
+// itself. The events not matching the matcher are reported to the dropped
+// event observer as DropUnmatched. This method returns the publisher to allow
+// method chaining, and is not goroutine safe as well as On.
+func (pub *Mux) OnMatch(typ Type, match Matcher, sub Subscriber) *Mux {
+	return pub.On(typ, &wrapper{func(ctx context.Context, ev Event) error {
+		if !match(ev) {
+			dropped(ctx, ev, DropUnmatched)
+			return nil
+		}
+		return sub.Handle(ctx, ev)
+	}, sub})
+}
@@ -0,0 +1,68 @@
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+type eventTenant struct {
+	eventCreated
+	tenant string
+}
+
+func (ev eventTenant) Metadata() map[string]string {
+	return map[string]string{"tenant": ev.tenant}
+}
+
+func TestMuxOnMatch(t *testing.T) {
+	ctx := context.Background()
+	var drops []string
+	event.SetDroppedEventObserver(func(_ context.Context, ev event.Event, reason event.DropReason) {
+		drops = append(drops, fmt.Sprint(ev, " ", reason))
+	})
+	defer event.SetDroppedEventObserver(nil)
+	sub1, sub2 := &logged{}, &logged{}
+	pub := event.NewMux().
+		OnMatch(eventTypeCreated, event.MetaEquals("tenant", "acme"), sub1).
+		On(eventTypeCreated, sub2)
+	evs := []event.Event{
+		eventTenant{1, "acme"}, eventTenant{2, "other"}, eventCreated(3), eventTenant{4, "acme"},
+	}
+	for _, ev := range evs {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []event.Event{evs[0], evs[3]}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := evs; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	if expected := []string{"{2 other} unmatched", "3 unmatched"}; !reflect.DeepEqual(drops, expected) {
+		t.Errorf("dropped events: expected %v, got %v", expected, drops)
+	}
+}
+
+func TestMuxOnMatchName(t *testing.T) {
+	ctx := context.Background()
+	pub := event.NewMux(event.MuxProfile()).
+		OnMatch(eventTypeCreated, event.MetaEquals("tenant", "acme"), &logged{}).
+		OnMatch(eventTypeCreated, event.MetaEquals("tenant", "other"), event.Build(&logged{}).Named("other"))
+	if err := pub.Publish(ctx, eventTenant{1, "acme"}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	var names []string
+	for name := range pub.Stats()[eventTypeCreated].Subscribers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if expected := []string{"*event_test.logged", "other"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
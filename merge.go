@@ -0,0 +1,143 @@
+package event
+
+import "strconv"
+
+// MergePolicy is the policy of Merge and MergeMappings on the subscribers
+// registered on the same event type in multiple muxes or mappings.
+type MergePolicy int
+
+const (
+	// MergeOrdered combines the subscribers into Ordered, in the order of the
+	// muxes.
+	MergeOrdered MergePolicy = iota
+	// MergeStrict reports DuplicateError on the subscribers of the same event
+	// type, namespace, or the default subscribers.
+	MergeStrict
+)
+
+// Clone returns a copy of the mux, with the same subscribers and options.
+// Registering the subscribers on the copy does not affect the original mux,
+// and the copy is not frozen even if the original mux is. The statistics are
+// not copied.
+func (pub *Mux) Clone() *Mux {
+	m := *pub
+	m.subscribers = make(map[Type]Subscriber, len(pub.subscribers))
+	for typ, sub := range pub.subscribers {
+		m.subscribers[typ] = cloneSubscriber(sub)
+	}
+	if pub.namespaces != nil {
+		m.namespaces = make(map[Namespace]Subscriber, len(pub.namespaces))
+		for ns, sub := range pub.namespaces {
+			m.namespaces[ns] = cloneSubscriber(sub)
+		}
+	}
+	m.fallback = cloneSubscriber(pub.fallback)
+	m.middlewares = append([]func(Subscriber) Subscriber(nil), pub.middlewares...)
+	m.readiers = append([]Subscriber(nil), pub.readiers...)
+	if pub.stats != nil {
+		MuxStats()(&m)
+	}
+	m.frozen = false
+	return &m
+}
+
+// Merge returns a new mux with the subscribers of the muxes, which is useful
+// to compose the muxes built by the modular packages at startup. The new mux
+// is a clone of the first mux, with the options of it. The subscribers are
+// already wrapped by the options of each mux, so they are not wrapped again.
+func Merge(policy MergePolicy, muxes ...*Mux) (*Mux, error) {
+	if len(muxes) == 0 {
+		return NewMux(), nil
+	}
+	m := muxes[0].Clone()
+	for _, pub := range muxes[1:] {
+		for typ, sub := range pub.subscribers {
+			if s, ok := m.subscribers[typ]; ok {
+				if policy == MergeStrict {
+					return nil, &DuplicateError{"event type " + strconv.Itoa(int(typ))}
+				}
+				sub = mergeSubscriber(s, sub)
+			}
+			m.subscribers[typ] = cloneSubscriber(sub)
+		}
+		for ns, sub := range pub.namespaces {
+			if m.namespaces == nil {
+				m.namespaces = make(map[Namespace]Subscriber)
+			}
+			if s, ok := m.namespaces[ns]; ok {
+				if policy == MergeStrict {
+					return nil, &DuplicateError{"namespace " + strconv.Itoa(int(ns))}
+				}
+				sub = mergeSubscriber(s, sub)
+			}
+			m.namespaces[ns] = cloneSubscriber(sub)
+		}
+		if pub.fallback != nil {
+			if m.fallback != nil && policy == MergeStrict {
+				return nil, &DuplicateError{"default subscribers"}
+			}
+			m.fallback = cloneSubscriber(mergeSubscriber(m.fallback, pub.fallback))
+		}
+		m.readiers = append(m.readiers, pub.readiers...)
+	}
+	return m, nil
+}
+
+// Clone returns a copy of the mapping. Registering the subscribers on the copy
+// does not affect the original mapping.
+func (pub Mapping) Clone() Mapping {
+	m := make(Mapping, len(pub))
+	for typ, sub := range pub {
+		m[typ] = cloneSubscriber(sub)
+	}
+	return m
+}
+
+// MergeMappings returns a new mapping with the subscribers of the mappings, as
+// well as Merge for the muxes.
+func MergeMappings(policy MergePolicy, maps ...Mapping) (Mapping, error) {
+	m := NewMapping()
+	for _, pub := range maps {
+		for typ, sub := range pub {
+			if s, ok := m[typ]; ok {
+				if policy == MergeStrict {
+					return nil, &DuplicateError{"event type " + strconv.Itoa(int(typ))}
+				}
+				sub = mergeSubscriber(s, sub)
+			}
+			m[typ] = cloneSubscriber(sub)
+		}
+	}
+	return m, nil
+}
+
+// mergeSubscriber appends the subscriber to s, flattening Ordered.
+func mergeSubscriber(s, sub Subscriber) Subscriber {
+	if o, ok := sub.(Ordered); ok {
+		for _, sub := range o {
+			s = appendSubscriber(s, sub)
+		}
+		return s
+	}
+	return appendSubscriber(s, sub)
+}
+
+// cloneSubscriber copies Ordered not to share the array with the original
+// mux on appending the subscribers.
+func cloneSubscriber(sub Subscriber) Subscriber {
+	if o, ok := sub.(Ordered); ok {
+		return append(Ordered(nil), o...)
+	}
+	return sub
+}
+
+// DuplicateError is the error returned by Merge and MergeMappings with
+// MergeStrict on the subscribers registered in multiple muxes or mappings.
+type DuplicateError struct {
+	Target string
+}
+
+// Error implements error for DuplicateError.
+func (err *DuplicateError) Error() string {
+	return "duplicate subscribers of " + err.Target
+}
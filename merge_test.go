@@ -0,0 +1,182 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestMuxClone(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2, sub3 := &logged{}, &logged{}, &logged{}
+	pub1 := event.NewMux(event.MuxStats()).On(eventTypeCreated, sub1).On(eventTypeCreated, sub1)
+	pub1.Freeze()
+	pub2 := pub1.Clone().On(eventTypeCreated, sub2).On(eventTypeUpdated, sub2)
+	pub3 := pub1.Clone().On(eventTypeCreated, sub3)
+	for _, pub := range []*event.Mux{pub1, pub2, pub3} {
+		if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if err := pub.Publish(ctx, eventUpdated(2)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if got, expected := len(sub1.Events()), 6; got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+	if expected := []event.Event{eventCreated(1), eventUpdated(2)}; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(sub3.Events(), expected) {
+		t.Errorf("sub3 handled events: expected %v, got %v", expected, sub3.Events())
+	}
+	for _, pub := range []*event.Mux{pub1, pub2, pub3} {
+		if got, expected := pub.Stats()[eventTypeCreated].Count, int64(1); got != expected {
+			t.Errorf("expected %d published events, got %d", expected, got)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	sub := func(name string) event.Func {
+		return func(context.Context, event.Event) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	users := event.NewMux(event.MuxStrict()).
+		On(eventTypeCreated, sub("users")).
+		On(eventTypeUpdated, sub("users"))
+	orders := event.NewMux().
+		On(eventTypeCreated, sub("orders1")).
+		On(eventTypeCreated, sub("orders2")).
+		OnNamespace(namespaceUsers, sub("orders"))
+	pub, err := event.Merge(event.MergeOrdered, users, orders)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventUpdated(2), eventNamespaced(eventTypeUserCreated)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []string{"users", "orders1", "orders2", "users", "orders"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+	var uerr *event.UnhandledError
+	if err := pub.Publish(ctx, eventDeleted(4)); !errors.As(err, &uerr) {
+		t.Errorf("expected an unhandled error, got %v", err)
+	}
+	_, err = event.Merge(event.MergeStrict, users, orders)
+	if expected := "duplicate subscribers of event type 0"; err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+	_, err = event.Merge(event.MergeStrict, users, event.NewMux(event.MuxStrict()))
+	if expected := "duplicate subscribers of default subscribers"; err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+	_, err = event.Merge(event.MergeStrict, orders, event.NewMux().OnNamespace(namespaceUsers, sub("users")))
+	if expected := "duplicate subscribers of namespace 1"; err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestMergeOrdered(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	sub := func(name string) event.Func {
+		return func(context.Context, event.Event) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	orders := event.NewMux().
+		On(eventTypeCreated, sub("orders1")).
+		On(eventTypeCreated, sub("orders2")).
+		OnNamespace(namespaceUsers, sub("orders"))
+	users := event.NewMux().
+		On(eventTypeCreated, sub("users")).
+		OnNamespace(namespaceUsers, sub("users")).
+		Default(sub("users"))
+	pub, err := event.Merge(event.MergeOrdered, orders, users, event.NewMux().Default(sub("default")))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventNamespaced(eventTypeUserCreated), eventDeleted(3)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	expected := []string{"orders1", "orders2", "users", "orders", "users", "users", "default"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+	if pub, err = event.Merge(event.MergeStrict); err != nil || pub == nil {
+		t.Errorf("expected an empty mux, got %v, %v", pub, err)
+	}
+}
+
+func TestMappingClone(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2 := &logged{}, &logged{}
+	pub1 := event.NewMapping().On(eventTypeCreated, sub1).On(eventTypeCreated, sub1)
+	pub2 := pub1.Clone().On(eventTypeCreated, sub2).On(eventTypeUpdated, sub2)
+	for _, pub := range []event.Mapping{pub1, pub2} {
+		if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if got, expected := len(sub1.Events()), 4; got != expected {
+		t.Errorf("expected %d handled events, got %d", expected, got)
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	if _, ok := pub1[eventTypeUpdated]; ok {
+		t.Errorf("expected the original mapping not to be modified")
+	}
+}
+
+func TestMergeMappings(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	sub := func(name string) event.Func {
+		return func(context.Context, event.Event) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	users := event.NewMapping().
+		On(eventTypeCreated, sub("users")).
+		On(eventTypeUpdated, sub("users"))
+	orders := event.NewMapping().
+		On(eventTypeCreated, sub("orders1")).
+		On(eventTypeCreated, sub("orders2"))
+	pub, err := event.MergeMappings(event.MergeOrdered, users, orders)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventUpdated(2)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []string{"users", "orders1", "orders2", "users"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+	if got, expected := len(orders[eventTypeCreated].(event.Ordered)), 2; got != expected {
+		t.Errorf("expected %d subscribers, got %d", expected, got)
+	}
+	_, err = event.MergeMappings(event.MergeStrict, users, orders)
+	if expected := "duplicate subscribers of event type 0"; err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+	if pub, err = event.MergeMappings(event.MergeStrict); err != nil || pub == nil {
+		t.Errorf("expected an empty mapping, got %v, %v", pub, err)
+	}
+}
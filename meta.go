@@ -0,0 +1,70 @@
+package event
+
+import "context"
+
+// TypeMeta is the event type of the meta events, which are the events about
+// the subscribers of this package themselves. Register the subscribers on this
+// type to react to the health of the subscribers.
+const TypeMeta Type = -1
+
+// SubscriberFailed is the meta event published by Monitor on the errors of the
+// subscriber, and by Mux with MuxFailures.
+type SubscriberFailed struct {
+	Subscriber string
+	Event      Event
+	Err        error
+}
+
+// Type implements Event for SubscriberFailed.
+func (*SubscriberFailed) Type() Type {
+	return TypeMeta
+}
+
+// EventDeadLettered is the meta event published by DeadLetter on each
+// dead-lettered or quarantined event.
+type EventDeadLettered struct {
+	Record *DeadLetterRecord
+}
+
+// Type implements Event for EventDeadLettered.
+func (*EventDeadLettered) Type() Type {
+	return TypeMeta
+}
+
+// ErrorBudgetExceeded is the meta event published by ErrorBudget when the
+// error rate exceeds the budget.
+type ErrorBudgetExceeded struct {
+	Subscriber string
+	Rate       float64
+}
+
+// Type implements Event for ErrorBudgetExceeded.
+func (*ErrorBudgetExceeded) Type() Type {
+	return TypeMeta
+}
+
+// QueueOverflowed is the meta event published by Lanes on each event published
+// to the full queue of a lane.
+type QueueOverflowed struct {
+	Queue string
+	Event Event
+}
+
+// Type implements Event for QueueOverflowed.
+func (*QueueOverflowed) Type() Type {
+	return TypeMeta
+}
+
+// Monitor creates an event subscriber to publish a SubscriberFailed meta event
+// to the meta publisher on each error of the subscriber. The errors of the
+// meta events are not published again, not to publish the meta events
+// infinitely.
+func Monitor(name string, sub Subscriber, meta Publisher) Func {
+	return func(ctx context.Context, ev Event) error {
+		err := sub.Handle(ctx, ev)
+		if err != nil && ev.Type() != TypeMeta {
+			_ = meta.Publish(ctx, &SubscriberFailed{name, ev, err})
+		}
+		return err
+	}
+}
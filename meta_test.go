@@ -0,0 +1,89 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestMeta(t *testing.T) {
+	ctx := context.Background()
+	var metas []event.Event
+	meta := event.NewMapping().On(event.TypeMeta, event.Func(func(_ context.Context, ev event.Event) error {
+		metas = append(metas, ev)
+		return errors.New("meta error")
+	}))
+	meta.On(event.TypeMeta, event.Monitor("meta", suberr{}, meta))
+	sub := event.NewDeadLetter("sub",
+		event.NewErrorBudget("sub", event.Monitor("sub", suberr{}, meta), 0.5, time.Minute, nil).Meta(meta),
+		func(context.Context, *event.DeadLetterRecord) error { return nil },
+	).Meta(meta)
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(metas) != 3 {
+		t.Fatalf("expected 3 meta events, got %v", metas)
+	}
+	if ev, ok := metas[0].(*event.SubscriberFailed); !ok ||
+		ev.Subscriber != "sub" || ev.Event != eventCreated(1) || ev.Err.Error() != "handle error" {
+		t.Errorf("unexpected meta event: %#v", metas[0])
+	}
+	if ev, ok := metas[1].(*event.ErrorBudgetExceeded); !ok || ev.Subscriber != "sub" || ev.Rate != 1.0 {
+		t.Errorf("unexpected meta event: %#v", metas[1])
+	}
+	if ev, ok := metas[2].(*event.EventDeadLettered); !ok ||
+		ev.Record.Event != eventCreated(1) || ev.Record.Disposition != event.DispositionDeadLettered {
+		t.Errorf("unexpected meta event: %#v", metas[2])
+	}
+	for _, ev := range metas {
+		if ev.Type() != event.TypeMeta {
+			t.Errorf("unexpected event type: %d", ev.Type())
+		}
+	}
+}
+
+func TestMuxFailures(t *testing.T) {
+	ctx := context.Background()
+	var metas []event.Event
+	meta := event.NewMapping().On(event.TypeMeta, event.Func(func(_ context.Context, ev event.Event) error {
+		metas = append(metas, ev)
+		return nil
+	}))
+	var attempts int
+	retry := func(sub event.Subscriber) event.Subscriber {
+		return event.Func(func(ctx context.Context, ev event.Event) error {
+			if err := sub.Handle(ctx, ev); err == nil {
+				return nil
+			}
+			return sub.Handle(ctx, ev)
+		})
+	}
+	pub := event.NewMux(event.MuxMiddleware(retry), event.MuxFailures(meta)).
+		On(eventTypeCreated, event.Func(func(_ context.Context, ev event.Event) error {
+			if attempts++; ev == eventCreated(2) || attempts == 1 {
+				return errors.New("handle error")
+			}
+			return nil
+		})).
+		On(eventTypeUpdated, suberr{}).
+		On(event.TypeMeta, suberr{})
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2), eventUpdated(3), &event.SubscriberFailed{}} {
+		if err := pub.Publish(ctx, ev); (err != nil) != (ev != eventCreated(1)) {
+			t.Errorf("unexpected error on %v: %v", ev, err)
+		}
+	}
+	if len(metas) != 2 {
+		t.Fatalf("expected 2 meta events, got %v", metas)
+	}
+	if ev, ok := metas[0].(*event.SubscriberFailed); !ok ||
+		ev.Subscriber != "event-go_test.TestMuxFailures.func3" || ev.Event != eventCreated(2) || ev.Err.Error() != "handle error" {
+		t.Errorf("unexpected meta event: %#v", metas[0])
+	}
+	if ev, ok := metas[1].(*event.SubscriberFailed); !ok ||
+		ev.Subscriber != "event_test.suberr" || ev.Event != eventUpdated(3) {
+		t.Errorf("unexpected meta event: %#v", metas[1])
+	}
+}
@@ -0,0 +1,160 @@
+package event
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Metrics is an event subscriber to aggregate the events into the counters,
+// gauges and histograms declared per event type, with the values extracted
+// from the events. The metrics are exposed through MetricsExporter, which is
+// useful to adapt to the metrics libraries.
+type Metrics struct {
+	mu      sync.Mutex
+	types   map[Type][]extractor
+	metrics []*metric
+}
+
+type metricKind int
+
+const (
+	metricCounter metricKind = iota
+	metricGauge
+	metricHistogram
+)
+
+type metric struct {
+	kind    metricKind
+	name    string
+	value   float64
+	buckets []float64
+	counts  []uint64
+	count   uint64
+}
+
+// MetricsExporter is the interface to receive the current values of the
+// metrics.
+type MetricsExporter interface {
+	Counter(name string, value float64)
+	Gauge(name string, value float64)
+	Histogram(name string, h HistogramSnapshot)
+}
+
+// HistogramSnapshot is the current values of a histogram. Counts[i] is the
+// number of the values less than or equal to Buckets[i] and greater than the
+// previous bucket, and the last element of Counts is the number of the values
+// greater than the last bucket.
+type HistogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// NewMetrics creates a new metrics subscriber.
+func NewMetrics() *Metrics {
+	return &Metrics{types: make(map[Type][]extractor)}
+}
+
+// Counter declares a counter incremented by the value extracted from the events
+// of the type. The nil extractor counts the events. The metrics of the same
+// name are shared among the event types. This method returns the subscriber to
+// allow method chaining.
+func (sub *Metrics) Counter(typ Type, name string, value func(Event) float64) *Metrics {
+	if value == nil {
+		value = func(Event) float64 { return 1 }
+	}
+	sub.declare(typ, metricCounter, name, nil, value)
+	return sub
+}
+
+// Gauge declares a gauge set to the value extracted from the events of the
+// type. This method returns the subscriber to allow method chaining.
+func (sub *Metrics) Gauge(typ Type, name string, value func(Event) float64) *Metrics {
+	sub.declare(typ, metricGauge, name, nil, value)
+	return sub
+}
+
+// Histogram declares a histogram to observe the value extracted from the events
+// of the type. The buckets are the upper bounds of the buckets. This method
+// returns the subscriber to allow method chaining.
+func (sub *Metrics) Histogram(typ Type, name string, buckets []float64, value func(Event) float64) *Metrics {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	sub.declare(typ, metricHistogram, name, buckets, value)
+	return sub
+}
+
+type extractor struct {
+	metric *metric
+	value  func(Event) float64
+}
+
+func (sub *Metrics) declare(typ Type, kind metricKind, name string, buckets []float64, value func(Event) float64) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	var m *metric
+	for _, n := range sub.metrics {
+		if n.name == name {
+			if n.kind != kind {
+				panic("event: metric " + strconv.Quote(name) + " is declared with different kinds")
+			}
+			m = n
+			break
+		}
+	}
+	if m == nil {
+		m = &metric{kind: kind, name: name, buckets: buckets}
+		if kind == metricHistogram {
+			m.counts = make([]uint64, len(buckets)+1)
+		}
+		sub.metrics = append(sub.metrics, m)
+	}
+	sub.types[typ] = append(sub.types[typ], extractor{m, value})
+}
+
+// Handle implements Subscriber for Metrics.
+func (sub *Metrics) Handle(_ context.Context, ev Event) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for _, e := range sub.types[ev.Type()] {
+		v, m := e.value(ev), e.metric
+		switch m.kind {
+		case metricCounter:
+			m.value += v
+		case metricGauge:
+			m.value = v
+		case metricHistogram:
+			m.counts[sort.SearchFloat64s(m.buckets, v)]++
+			m.count++
+			m.value += v
+		}
+	}
+	return nil
+}
+
+// Export reports the current values of the metrics to the exporter, in the
+// order of the declarations.
+func (sub *Metrics) Export(e MetricsExporter) {
+	sub.mu.Lock()
+	metrics := make([]metric, len(sub.metrics))
+	for i, m := range sub.metrics {
+		metrics[i] = *m
+		metrics[i].counts = append([]uint64(nil), m.counts...)
+	}
+	sub.mu.Unlock()
+	for _, m := range metrics {
+		switch m.kind {
+		case metricCounter:
+			e.Counter(m.name, m.value)
+		case metricGauge:
+			e.Gauge(m.name, m.value)
+		case metricHistogram:
+			e.Histogram(m.name, HistogramSnapshot{
+				append([]float64(nil), m.buckets...), m.counts, m.count, m.value,
+			})
+		}
+	}
+}
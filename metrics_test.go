@@ -0,0 +1,64 @@
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+type metricsExporter []string
+
+func (e *metricsExporter) Counter(name string, value float64) {
+	*e = append(*e, fmt.Sprintf("counter %s %v", name, value))
+}
+
+func (e *metricsExporter) Gauge(name string, value float64) {
+	*e = append(*e, fmt.Sprintf("gauge %s %v", name, value))
+}
+
+func (e *metricsExporter) Histogram(name string, h event.HistogramSnapshot) {
+	*e = append(*e, fmt.Sprintf("histogram %s %v %v %d %v", name, h.Buckets, h.Counts, h.Count, h.Sum))
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	value := func(ev event.Event) float64 {
+		return float64(ev.(eventCreated))
+	}
+	sub := event.NewMetrics().
+		Counter(eventTypeCreated, "events", nil).
+		Counter(eventTypeUpdated, "events", nil).
+		Counter(eventTypeCreated, "total", value).
+		Gauge(eventTypeCreated, "last", value).
+		Histogram(eventTypeCreated, "sizes", []float64{10, 1}, value)
+	for _, ev := range []event.Event{
+		eventCreated(1), eventCreated(5), eventUpdated(2), eventCreated(20), eventCreated(3), eventDeleted(4),
+	} {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	var e metricsExporter
+	sub.Export(&e)
+	expected := metricsExporter{
+		"counter events 5",
+		"counter total 29",
+		"gauge last 3",
+		"histogram sizes [1 10] [1 2 1] 4 29",
+	}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("expected %v, got %v", expected, e)
+	}
+}
+
+func TestMetricsKindMismatch(t *testing.T) {
+	defer func() {
+		if got, expected := recover(), `event: metric "events" is declared with different kinds`; got != expected {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	}()
+	event.NewMetrics().Counter(eventTypeCreated, "events", nil).Gauge(eventTypeUpdated, "events", nil)
+}
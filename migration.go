@@ -0,0 +1,153 @@
+package event
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Migration is an event subscriber to migrate the handling from the subscriber
+// v1 to the rewritten subscriber v2 in stages; shadowing the events to v2,
+// routing a percentage of the events to v2, and flipping over to v2. The stage
+// is switched atomically while handling the events, and the parity of the
+// subscribers is recorded to decide the next stage.
+type Migration struct {
+	v1, v2     Subscriber
+	key        func(Event) (interface{}, bool)
+	onMismatch func(ev Event, errV1, errV2 error)
+	stage      atomic.Value
+	stats      MigrationStats
+}
+
+// MigrationStage is the stage of Migration.
+type MigrationStage int
+
+// The stages of Migration.
+const (
+	// MigrationV1 handles the events by v1.
+	MigrationV1 MigrationStage = iota
+	// MigrationShadow handles the events by both v1 and v2, and returns the
+	// error of v1. The panics of v2 are recovered as well as Shadow.
+	MigrationShadow
+	// MigrationCanary handles a percentage of the events by v2, and the rest
+	// by v1.
+	MigrationCanary
+	// MigrationV2 handles the events by v2.
+	MigrationV2
+)
+
+type migrationStage struct {
+	stage   MigrationStage
+	percent int
+}
+
+// MigrationStats is the statistics of Migration. The mismatches are the events
+// failed by only one of the subscribers on the shadow stage.
+type MigrationStats struct {
+	V1, V2     SplitStats
+	Shadowed   int64
+	Mismatched int64
+}
+
+// NewMigration creates a new migration subscriber on the stage MigrationV1.
+func NewMigration(v1, v2 Subscriber) *Migration {
+	sub := &Migration{v1: v1, v2: v2}
+	sub.stage.Store(migrationStage{MigrationV1, 0})
+	return sub
+}
+
+// Sticky makes the subscriber select the subscriber on the canary stage by the
+// hash of the key of the event, as well as Splitter. This method returns the
+// subscriber to allow method chaining.
+func (sub *Migration) Sticky(key func(Event) (interface{}, bool)) *Migration {
+	sub.key = key
+	return sub
+}
+
+// OnMismatch sets the function called on the mismatches on the shadow stage.
+// This method returns the subscriber to allow method chaining.
+func (sub *Migration) OnMismatch(report func(ev Event, errV1, errV2 error)) *Migration {
+	sub.onMismatch = report
+	return sub
+}
+
+// Shadow switches to the stage MigrationShadow.
+func (sub *Migration) Shadow() {
+	sub.stage.Store(migrationStage{MigrationShadow, 0})
+}
+
+// Canary switches to the stage MigrationCanary with the percentage of the
+// events handled by v2.
+func (sub *Migration) Canary(percentToV2 int) {
+	sub.stage.Store(migrationStage{MigrationCanary, percentToV2})
+}
+
+// Flip switches to the stage MigrationV2.
+func (sub *Migration) Flip() {
+	sub.stage.Store(migrationStage{MigrationV2, 0})
+}
+
+// Rollback switches back to the stage MigrationV1.
+func (sub *Migration) Rollback() {
+	sub.stage.Store(migrationStage{MigrationV1, 0})
+}
+
+// Stage returns the current stage, and the percentage on the canary stage.
+func (sub *Migration) Stage() (MigrationStage, int) {
+	s := sub.stage.Load().(migrationStage)
+	return s.stage, s.percent
+}
+
+// Handle implements Subscriber for Migration.
+func (sub *Migration) Handle(ctx context.Context, ev Event) error {
+	switch s := sub.stage.Load().(migrationStage); s.stage {
+	case MigrationShadow:
+		err := sub.handle(ctx, 0, ev, false)
+		e := sub.handle(ctx, 1, ev, true)
+		atomic.AddInt64(&sub.stats.Shadowed, 1)
+		if (err == nil) != (e == nil) {
+			atomic.AddInt64(&sub.stats.Mismatched, 1)
+			if sub.onMismatch != nil {
+				sub.onMismatch(ev, err, e)
+			}
+		}
+		return err
+	case MigrationCanary:
+		if bucket(sub.key, ev) < s.percent {
+			return sub.handle(ctx, 1, ev, false)
+		}
+		return sub.handle(ctx, 0, ev, false)
+	case MigrationV2:
+		return sub.handle(ctx, 1, ev, false)
+	default:
+		return sub.handle(ctx, 0, ev, false)
+	}
+}
+
+func (sub *Migration) handle(ctx context.Context, i int, ev Event, recover bool) error {
+	s, stats := sub.v1, &sub.stats.V1
+	if i == 1 {
+		s, stats = sub.v2, &sub.stats.V2
+	}
+	atomic.AddInt64(&stats.Handled, 1)
+	var err error
+	if recover {
+		err = handleRecover(ctx, s, ev)
+	} else {
+		err = s.Handle(ctx, ev)
+	}
+	if err != nil {
+		atomic.AddInt64(&stats.Failed, 1)
+	}
+	return err
+}
+
+// Stats returns the statistics of the migration.
+func (sub *Migration) Stats() MigrationStats {
+	load := func(s *SplitStats) SplitStats {
+		return SplitStats{atomic.LoadInt64(&s.Handled), atomic.LoadInt64(&s.Failed)}
+	}
+	return MigrationStats{
+		load(&sub.stats.V1), load(&sub.stats.V2),
+		atomic.LoadInt64(&sub.stats.Shadowed), atomic.LoadInt64(&sub.stats.Mismatched),
+	}
+}
@@ -0,0 +1,69 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestMigration(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2 := &logged{}, &logged{}
+	var mismatched []event.Event
+	sub := event.NewMigration(sub1, event.Ordered{sub2, event.Func(func(_ context.Context, ev event.Event) error {
+		if ev == eventCreated(2) {
+			panic("v2 panic")
+		}
+		return nil
+	})}).OnMismatch(func(ev event.Event, errV1, errV2 error) {
+		if errV1 != nil || errV2 == nil || errV2.Error() != "panic: v2 panic" {
+			t.Errorf("unexpected errors: %v, %v", errV1, errV2)
+		}
+		mismatched = append(mismatched, ev)
+	}).Sticky(func(ev event.Event) (interface{}, bool) {
+		return int(ev.(eventCreated)), true
+	})
+	handle := func(evs ...event.Event) {
+		for _, ev := range evs {
+			if err := sub.Handle(ctx, ev); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+		}
+	}
+	handle(eventCreated(1))
+	sub.Shadow()
+	handle(eventCreated(2), eventCreated(3))
+	if stage, _ := sub.Stage(); stage != event.MigrationShadow {
+		t.Errorf("expected stage %v, got %v", event.MigrationShadow, stage)
+	}
+	sub.Canary(100)
+	handle(eventCreated(4))
+	sub.Canary(0)
+	handle(eventCreated(5))
+	sub.Flip()
+	handle(eventCreated(6))
+	sub.Rollback()
+	handle(eventCreated(7))
+	if expected := []event.Event{
+		eventCreated(1), eventCreated(2), eventCreated(3), eventCreated(5), eventCreated(7),
+	}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := []event.Event{
+		eventCreated(2), eventCreated(3), eventCreated(4), eventCreated(6),
+	}; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	if expected := []event.Event{eventCreated(2)}; !reflect.DeepEqual(mismatched, expected) {
+		t.Errorf("mismatched events: expected %v, got %v", expected, mismatched)
+	}
+	expected := event.MigrationStats{
+		V1: event.SplitStats{Handled: 5}, V2: event.SplitStats{Handled: 4, Failed: 1},
+		Shadowed: 2, Mismatched: 1,
+	}
+	if got := sub.Stats(); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
@@ -0,0 +1,43 @@
+package event
+
+// NamespaceShift is the number of the low bits of a Type for the event within
+// its namespace. The high bits are for the namespace.
+const NamespaceShift = 16
+
+// Namespace is the namespace of event types, which is useful to define the
+// event types of the domains in separate packages without collisions. Define
+// the event types in a namespace by shifting the namespace.
+//
+//	const Users event.Namespace = 1
+//	const (
+//		UserCreated = event.Type(Users)<<event.NamespaceShift + iota
+//		UserRetired
+//	)
+type Namespace int
+
+// Type returns the event type of the number in the namespace.
+func (ns Namespace) Type(n int) Type {
+	return Type(ns)<<NamespaceShift | Type(n)&(1<<NamespaceShift-1)
+}
+
+// Namespace returns the namespace of the event type.
+func (typ Type) Namespace() Namespace {
+	return Namespace(typ >> NamespaceShift)
+}
+
+// Local returns the number of the event type within its namespace.
+func (typ Type) Local() int {
+	return int(typ & (1<<NamespaceShift - 1))
+}
+
+// OnNamespace registers the subscriber to listen on all the events in the
+// namespace. The subscriber handles the events after the subscribers
+// registered by On. This method returns the publisher to allow method
+// chaining, and is not goroutine safe as well as On.
+func (pub *Mux) OnNamespace(ns Namespace, sub Subscriber) *Mux {
+	if pub.namespaces == nil {
+		pub.namespaces = make(map[Namespace]Subscriber)
+	}
+	pub.namespaces[ns] = appendSubscriber(pub.namespaces[ns], pub.wrap(sub))
+	return pub
+}
@@ -0,0 +1,76 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+const (
+	namespaceUsers event.Namespace = iota + 1
+	namespaceOrders
+)
+
+const (
+	eventTypeUserCreated = event.Type(namespaceUsers)<<event.NamespaceShift + iota
+	eventTypeUserRetired
+)
+
+type eventNamespaced event.Type
+
+func (ev eventNamespaced) Type() event.Type {
+	return event.Type(ev)
+}
+
+func TestNamespace(t *testing.T) {
+	for _, tc := range []struct {
+		typ event.Type
+		ns  event.Namespace
+		n   int
+	}{
+		{eventTypeUserCreated, namespaceUsers, 0},
+		{eventTypeUserRetired, namespaceUsers, 1},
+		{namespaceOrders.Type(2), namespaceOrders, 2},
+		{eventTypeCreated, 0, 0},
+	} {
+		if got := tc.typ.Namespace(); got != tc.ns {
+			t.Errorf("namespace of %d: expected %d, got %d", tc.typ, tc.ns, got)
+		}
+		if got := tc.typ.Local(); got != tc.n {
+			t.Errorf("local of %d: expected %d, got %d", tc.typ, tc.n, got)
+		}
+		if got := tc.ns.Type(tc.n); got != tc.typ {
+			t.Errorf("type of %d in %d: expected %d, got %d", tc.n, tc.ns, tc.typ, got)
+		}
+	}
+}
+
+func TestMuxOnNamespace(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2, sub3 := &logged{}, &logged{}, &logged{}
+	pub := event.NewMux().
+		On(eventTypeUserCreated, sub1).
+		OnNamespace(namespaceUsers, sub2).
+		Default(sub3)
+	evs := []event.Event{
+		eventNamespaced(eventTypeUserCreated),
+		eventNamespaced(eventTypeUserRetired),
+		eventNamespaced(namespaceOrders.Type(0)),
+	}
+	for _, ev := range evs {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := evs[:1]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := evs[:2]; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	if expected := evs[2:]; !reflect.DeepEqual(sub3.Events(), expected) {
+		t.Errorf("sub3 handled events: expected %v, got %v", expected, sub3.Events())
+	}
+}
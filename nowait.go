@@ -0,0 +1,18 @@
+package event
+
+import "context"
+
+// PublishNoWait publishes the event to the publisher on a new goroutine, and
+// returns the channel to receive the result, which is a middle ground between
+// the blocking Publish and Lanes. The channel is buffered and closed after the
+// result, so the callers can ignore the result without leaking the goroutine.
+// Note that the publishing is not canceled when the context of the request is
+// done, unless the publisher respects the context.
+func PublishNoWait(ctx context.Context, pub Publisher, ev Event) <-chan error {
+	ch := make(chan error, 1)
+	spawn(ctx, func() {
+		defer close(ch)
+		ch <- pub.Publish(ctx, ev)
+	})
+	return ch
+}
@@ -0,0 +1,41 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestPublishNoWait(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	sub1 := &logged{}
+	pub := event.NewMapping().
+		On(eventTypeCreated, event.Func(func(ctx context.Context, ev event.Event) error {
+			<-release
+			return sub1.Handle(ctx, ev)
+		})).
+		On(eventTypeUpdated, suberr{})
+	ch := event.PublishNoWait(ctx, pub, eventCreated(1))
+	select {
+	case err := <-ch:
+		t.Fatalf("expected publishing not to complete, got %v", err)
+	default:
+	}
+	close(release)
+	if err := <-ch; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, ok := <-ch; ok {
+		t.Errorf("expected the channel closed")
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if err, expected := <-event.PublishNoWait(ctx, pub, eventUpdated(2)), "handle error"; err == nil || err.Error() != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	event.PublishNoWait(ctx, pub, eventUpdated(3))
+}
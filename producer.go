@@ -0,0 +1,76 @@
+package event
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+var producers = struct {
+	sync.Mutex
+	allowed map[Type]map[string]bool
+}{allowed: make(map[Type]map[string]bool)}
+
+// RegisterProducer registers the producer allowed to publish the event types,
+// to guard the taxonomy of the events against the architectural violations.
+// The events of the types without the registered producers are allowed to be
+// published by any producer.
+//
+//	func init() {
+//		event.RegisterProducer("users", EventTypeUserCreated, EventTypeUserDeleted)
+//	}
+func RegisterProducer(producer string, typs ...Type) {
+	producers.Lock()
+	defer producers.Unlock()
+	for _, typ := range typs {
+		if producers.allowed[typ] == nil {
+			producers.allowed[typ] = make(map[string]bool)
+		}
+		producers.allowed[typ][producer] = true
+	}
+}
+
+type producerKey struct{}
+
+// WithProducer returns the context carrying the name of the component
+// publishing the events, which is verified by CheckProducer.
+func WithProducer(ctx context.Context, producer string) context.Context {
+	return context.WithValue(ctx, producerKey{}, producer)
+}
+
+// ProducerFromContext returns the producer carried by the context.
+func ProducerFromContext(ctx context.Context) (string, bool) {
+	producer, ok := ctx.Value(producerKey{}).(string)
+	return producer, ok
+}
+
+// CheckProducer verifies that the producer of the context is allowed to
+// publish the event, and returns ProducerError otherwise. The events published
+// with the context without the producer are not verified. Mux verifies the
+// producers on publishing in the builds with the eventdebug tag, so that the
+// violations are caught in tests without the cost in production.
+func CheckProducer(ctx context.Context, ev Event) error {
+	producer, ok := ProducerFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	producers.Lock()
+	defer producers.Unlock()
+	if allowed, ok := producers.allowed[ev.Type()]; ok && !allowed[producer] {
+		return &ProducerError{producer, ev}
+	}
+	return nil
+}
+
+// ProducerError is the error on publishing an event by the producer not
+// registered by RegisterProducer.
+type ProducerError struct {
+	Producer string
+	Event    Event
+}
+
+// Error implements error for ProducerError.
+func (err *ProducerError) Error() string {
+	return "producer " + strconv.Quote(err.Producer) +
+		" is not allowed to publish event type " + strconv.Itoa(int(err.Event.Type()))
+}
@@ -0,0 +1,15 @@
+//go:build eventdebug
+// +build eventdebug
+
+package event
+
+import "context"
+
+// Publish implements Publisher for Mux. The producer of the context is
+// verified by CheckProducer before publishing.
+func (pub *Mux) Publish(ctx context.Context, ev Event) error {
+	if err := CheckProducer(ctx, ev); err != nil {
+		return err
+	}
+	return pub.observe(ctx, ev)
+}
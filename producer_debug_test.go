@@ -0,0 +1,28 @@
+//go:build eventdebug
+// +build eventdebug
+
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestMuxProducer(t *testing.T) {
+	event.RegisterProducer("users", eventTypeCreated)
+	sub1 := &logged{}
+	pub := event.NewMux().On(eventTypeCreated, sub1)
+	if err := pub.Publish(event.WithProducer(context.Background(), "users"), eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	err := pub.Publish(event.WithProducer(context.Background(), "orders"), eventCreated(2))
+	if _, ok := err.(*event.ProducerError); !ok {
+		t.Fatalf("expected ProducerError, got %v", err)
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !eventdebug
+// +build !eventdebug
+
+package event
+
+import "context"
+
+// Publish implements Publisher for Mux.
+func (pub *Mux) Publish(ctx context.Context, ev Event) error {
+	return pub.observe(ctx, ev)
+}
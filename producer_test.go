@@ -0,0 +1,36 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestCheckProducer(t *testing.T) {
+	event.RegisterProducer("users", eventTypeCreated, eventTypeDeleted)
+	event.RegisterProducer("admin", eventTypeDeleted)
+	ctx := context.Background()
+	if _, ok := event.ProducerFromContext(ctx); ok {
+		t.Fatalf("expected no producer in the background context")
+	}
+	if err := event.CheckProducer(ctx, eventCreated(1)); err != nil {
+		t.Errorf("got error: %v", err)
+	}
+	ctx = event.WithProducer(ctx, "admin")
+	if producer, ok := event.ProducerFromContext(ctx); !ok || producer != "admin" {
+		t.Fatalf("expected the producer of the context, got %q", producer)
+	}
+	for _, ev := range []event.Event{eventUpdated(2), eventDeleted(3)} {
+		if err := event.CheckProducer(ctx, ev); err != nil {
+			t.Errorf("got error: %v", err)
+		}
+	}
+	err := event.CheckProducer(ctx, eventCreated(4))
+	if expected := `producer "admin" is not allowed to publish event type 0`; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err, ok := err.(*event.ProducerError); !ok || err.Producer != "admin" || err.Event != eventCreated(4) {
+		t.Errorf("expected ProducerError, got %#v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrQuiescing is the error returned on publishing to the quiescing publisher.
+var ErrQuiescing = errors.New("publisher quiescing")
+
+// Quiescer is the interface for a publisher which handles the events in
+// background, and can stop accepting the events and finish handling the
+// accepted events. Quiesce blocks until the events are handled or the context
+// is done.
+type Quiescer interface {
+	Quiesce(context.Context) (DrainSummary, error)
+}
+
+// DrainSummary is the summary of draining a publisher by Quiesce. InFlight is
+// the number of the publishing waited for by Gate, Drained is the number of
+// the queued events taken for handling while draining, Remaining is the
+// number of the events left unhandled when the context is done, and HandedOff
+// is the number of the events handed off to another publisher instead.
+type DrainSummary struct {
+	InFlight  int
+	Drained   int
+	Remaining int
+	HandedOff int
+	Elapsed   time.Duration
+}
+
+// String implements fmt.Stringer for DrainSummary.
+func (s DrainSummary) String() string {
+	return "in-flight: " + strconv.Itoa(s.InFlight) +
+		", drained: " + strconv.Itoa(s.Drained) +
+		", remaining: " + strconv.Itoa(s.Remaining) +
+		", handed off: " + strconv.Itoa(s.HandedOff) +
+		", elapsed: " + s.Elapsed.String()
+}
+
+// Quiesce stops the publisher accepting the events, and waits for the
+// accepted events to be handled, which is useful for the zero-downtime
+// deploys. The publishing after quiescing returns ErrQuiescing. The
+// publishers not implementing Quiescer are considered drained. Wrap the
+// publisher by Gate to wait for the in-flight publishing as well.
+func Quiesce(ctx context.Context, pub Publisher) (DrainSummary, error) {
+	if q, ok := pub.(Quiescer); ok {
+		return q.Quiesce(ctx)
+	}
+	return DrainSummary{}, nil
+}
+
+// Gate is an event publisher to track the in-flight publishing to the
+// publisher, so that Quiesce waits for them before draining the publisher.
+type Gate struct {
+	publisher Publisher
+	mu        sync.Mutex
+	inflight  int
+	quiescing bool
+	idle      chan struct{}
+	clock     Clock
+}
+
+// NewGate creates a new gate publisher.
+func NewGate(pub Publisher) *Gate {
+	return &Gate{publisher: pub}
+}
+
+// Clock sets the clock to measure the elapsed time of quiescing instead of the
+// global clock. This method returns the publisher to allow method chaining.
+func (pub *Gate) Clock(c Clock) *Gate {
+	pub.clock = c
+	return pub
+}
+
+// Handle implements Subscriber for Gate.
+func (pub *Gate) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Gate.
+func (pub *Gate) Publish(ctx context.Context, ev Event) error {
+	pub.mu.Lock()
+	if pub.quiescing {
+		pub.mu.Unlock()
+		return ErrQuiescing
+	}
+	pub.inflight++
+	pub.mu.Unlock()
+	defer func() {
+		pub.mu.Lock()
+		defer pub.mu.Unlock()
+		if pub.inflight--; pub.inflight == 0 && pub.idle != nil {
+			close(pub.idle)
+			pub.idle = nil
+		}
+	}()
+	return pub.publisher.Publish(ctx, ev)
+}
+
+// Quiesce implements Quiescer for Gate. This method waits for the in-flight
+// publishing, and then quiesces the publisher.
+func (pub *Gate) Quiesce(ctx context.Context) (DrainSummary, error) {
+	clock := clockOr(pub.clock)
+	start := clock.Now()
+	pub.mu.Lock()
+	pub.quiescing = true
+	s := DrainSummary{InFlight: pub.inflight}
+	idle := pub.idle
+	if pub.inflight > 0 && idle == nil {
+		idle = make(chan struct{})
+		pub.idle = idle
+	}
+	pub.mu.Unlock()
+	if idle != nil {
+		select {
+		case <-ctx.Done():
+			pub.mu.Lock()
+			s.Remaining = pub.inflight
+			pub.mu.Unlock()
+			s.Elapsed = clock.Now().Sub(start)
+			return s, ctx.Err()
+		case <-idle:
+		}
+	}
+	t, err := Quiesce(ctx, pub.publisher)
+	s.InFlight += t.InFlight
+	s.Drained, s.Remaining, s.HandedOff = t.Drained, t.Remaining, t.HandedOff
+	s.Elapsed = clock.Now().Sub(start)
+	return s, err
+}
@@ -0,0 +1,157 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestQuiesce(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &logged{}
+	handling, release := make(chan struct{}), make(chan struct{})
+	lanes := event.NewLanes(event.Func(func(ctx context.Context, ev event.Event) error {
+		if ev == eventCreated(1) {
+			close(handling)
+			<-release
+		}
+		return sub1.Handle(ctx, ev)
+	})).Lane("default", 1, 1)
+	pub := event.NewGate(lanes)
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	<-handling
+	if err := pub.Handle(ctx, eventCreated(2)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	errc := make(chan error)
+	go func() { errc <- pub.Publish(ctx, eventCreated(3)) }()
+	time.Sleep(10 * time.Millisecond)
+	done := make(chan event.DrainSummary)
+	go func() {
+		s, err := event.Quiesce(ctx, pub)
+		if err != nil {
+			t.Errorf("got error: %v", err)
+		}
+		done <- s
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err, expected := pub.Publish(ctx, eventCreated(4)), event.ErrQuiescing; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if s := <-done; s.InFlight != 1 || s.Drained > 1 || s.Remaining != 0 {
+		t.Errorf("unexpected summary: %v", s)
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if err, expected := lanes.Publish(ctx, eventCreated(5)), event.ErrQuiescing; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+type quiescerFunc func(context.Context) (event.DrainSummary, error)
+
+func (f quiescerFunc) Handle(context.Context, event.Event) error {
+	return nil
+}
+
+func (f quiescerFunc) Publish(context.Context, event.Event) error {
+	return nil
+}
+
+func (f quiescerFunc) Quiesce(ctx context.Context) (event.DrainSummary, error) {
+	return f(ctx)
+}
+
+func TestQuiesceClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	pub := event.NewGate(quiescerFunc(func(context.Context) (event.DrainSummary, error) {
+		clock.Advance(time.Minute)
+		return event.DrainSummary{Drained: 1}, nil
+	})).Clock(clock)
+	if s, err := event.Quiesce(ctx, pub); err != nil || s.Drained != 1 || s.Elapsed != time.Minute {
+		t.Errorf("unexpected summary: %v, %v", s, err)
+	}
+	release := make(chan struct{})
+	lanes := event.NewLanes(event.Func(func(context.Context, event.Event) error {
+		<-release
+		clock.Advance(time.Minute)
+		return nil
+	})).Clock(clock).Lane("default", 1, 1)
+	if err := lanes.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	done := make(chan event.DrainSummary)
+	go func() {
+		s, err := event.Quiesce(ctx, lanes)
+		if err != nil {
+			t.Errorf("got error: %v", err)
+		}
+		done <- s
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if s := <-done; s.Elapsed != time.Minute {
+		t.Errorf("unexpected summary: %v", s)
+	}
+}
+
+func TestQuiesceTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	block := make(chan struct{})
+	defer close(block)
+	pub := event.NewReplicator("us", event.Discard).
+		Replica("eu", event.Func(func(context.Context, event.Event) error {
+			<-block
+			return nil
+		}), 10)
+	for i := 0; i < 3; i++ {
+		if err := pub.Publish(context.Background(), eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	s, err := event.Quiesce(ctx, pub)
+	if expected := context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if s.Remaining < 2 || s.Drained+s.Remaining > 3 {
+		t.Errorf("unexpected summary: %v", s)
+	}
+	if err, expected := pub.Publish(context.Background(), eventCreated(3)), event.ErrQuiescing; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	handling := make(chan struct{})
+	gate := event.NewGate(event.Func(func(context.Context, event.Event) error {
+		close(handling)
+		<-block
+		return nil
+	}))
+	go func() { _ = gate.Publish(context.Background(), eventCreated(1)) }()
+	<-handling
+	s, err = event.Quiesce(ctx, gate)
+	if expected := context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if s.InFlight != 1 || s.Remaining != 1 {
+		t.Errorf("unexpected summary: %v", s)
+	}
+	s = event.DrainSummary{InFlight: 1, Drained: 2, Remaining: 3, Elapsed: time.Second}
+	if got, expected := s.String(), "in-flight: 1, drained: 2, remaining: 3, handed off: 0, elapsed: 1s"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if s, err := event.Quiesce(ctx, event.Discard); err != nil || s != (event.DrainSummary{}) {
+		t.Errorf("unexpected summary: %v, %v", s, err)
+	}
+}
@@ -0,0 +1,84 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// Readier is the interface for a subscriber which needs to get ready before
+// handling the events, like a subscriber backed by a message broker which
+// needs to connect and subscribe. Ready blocks until the subscriber gets ready
+// or the context is done.
+type Readier interface {
+	Ready(context.Context) error
+}
+
+// WaitReady waits for the subscriber to get ready, which is useful to prevent
+// losing the events published during the startup. The subscribers composed by
+// Mapping, Mux, Ordered, Async, Limited and Builder are waited for as well. The
+// subscribers not implementing Readier are considered ready.
+func WaitReady(ctx context.Context, sub Subscriber) error {
+	if r, ok := sub.(Readier); ok {
+		return r.Ready(ctx)
+	}
+	return nil
+}
+
+func waitReady(ctx context.Context, subs []Subscriber) error {
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		err  error
+	)
+	for _, sub := range subs {
+		r, ok := sub.(Readier)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		spawn(ctx, func() {
+			defer wg.Done()
+			if e := r.Ready(ctx); e != nil {
+				once.Do(func() { err = e })
+			}
+		})
+	}
+	wg.Wait()
+	return err
+}
+
+// Ready implements Readier for Mapping, by waiting for all the registered
+// subscribers.
+func (pub Mapping) Ready(ctx context.Context) error {
+	subs := make([]Subscriber, 0, len(pub))
+	for _, sub := range pub {
+		subs = append(subs, sub)
+	}
+	return waitReady(ctx, subs)
+}
+
+// Ready implements Readier for Mux, by waiting for all the registered
+// subscribers.
+func (pub *Mux) Ready(ctx context.Context) error {
+	return waitReady(ctx, pub.readiers)
+}
+
+// Ready implements Readier for Ordered.
+func (sub Ordered) Ready(ctx context.Context) error {
+	return waitReady(ctx, sub)
+}
+
+// Ready implements Readier for Async.
+func (sub Async) Ready(ctx context.Context) error {
+	return waitReady(ctx, sub)
+}
+
+// Ready implements Readier for Limited.
+func (sub *Limited) Ready(ctx context.Context) error {
+	return WaitReady(ctx, sub.subscriber)
+}
+
+// Ready implements Readier for Builder.
+func (b *Builder) Ready(ctx context.Context) error {
+	return WaitReady(ctx, b.subscriber)
+}
@@ -0,0 +1,68 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+type readySubscriber struct {
+	logged
+	ready chan struct{}
+	err   error
+}
+
+func (sub *readySubscriber) Ready(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-sub.ready:
+		return sub.err
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	ctx := context.Background()
+	sub1 := &readySubscriber{ready: make(chan struct{})}
+	sub2 := &readySubscriber{ready: make(chan struct{})}
+	pub := event.NewMux(event.MuxMiddleware(func(sub event.Subscriber) event.Subscriber {
+		return event.Func(sub.Handle)
+	})).
+		On(eventTypeCreated, sub1).
+		On(eventTypeUpdated, event.Async{&logged{}, event.NewLimited(sub2, 1)}).
+		On(eventTypeDeleted, &logged{})
+	var ready int32
+	errc := make(chan error)
+	go func() {
+		err := event.WaitReady(ctx, pub)
+		atomic.StoreInt32(&ready, 1)
+		errc <- err
+	}()
+	close(sub1.ready)
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt32(&ready) != 0 {
+		t.Fatalf("expected not ready")
+	}
+	close(sub2.ready)
+	if err := <-errc; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := event.WaitReady(ctx, &logged{}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	sub3 := &readySubscriber{ready: make(chan struct{}), err: errors.New("connect error")}
+	close(sub3.ready)
+	if err, expected := event.WaitReady(ctx, event.Build(sub3).Retry(1)), "connect error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	sub4 := &readySubscriber{ready: make(chan struct{})}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err, expected := event.WaitReady(cctx, event.NewMapping().On(eventTypeCreated, event.Ordered{sub4})), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
@@ -0,0 +1,80 @@
+package event
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// DeadLetterSource is the interface for a source of dead-lettered events.
+type DeadLetterSource interface {
+	// Next returns the next record. This method returns io.EOF when there are
+	// no more records.
+	Next(context.Context) (*DeadLetterRecord, error)
+}
+
+// RedriveOption is an option for Redrive.
+type RedriveOption func(*redriveOptions)
+
+type redriveOptions struct {
+	clock Clock
+}
+
+// RedriveClock sets the clock of the rate instead of the global clock.
+func RedriveClock(c Clock) RedriveOption {
+	return func(opts *redriveOptions) { opts.clock = c }
+}
+
+// Redrive republishes the dead-lettered events of the source to the publisher,
+// which is useful to recover the events failed during an outage. The records
+// not matching the filter are skipped, and the filter can be nil to redrive all
+// the records. The rate is the max number of events to publish per second, and
+// zero means no limit. The report function is called with the result of each
+// republished event, and the errors of the publisher do not stop redriving.
+// Note that the publisher should not dead-letter the events back to the source
+// unless the source is a snapshot, or redriving may not terminate.
+func Redrive(
+	ctx context.Context,
+	src DeadLetterSource,
+	pub Publisher,
+	filter func(*DeadLetterRecord) bool,
+	rate float64,
+	report func(*DeadLetterRecord, error),
+	opts ...RedriveOption,
+) error {
+	var o redriveOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := clockOr(o.clock).NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
+	var n int
+	for {
+		r, err := src.Next(ctx)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if filter != nil && !filter(r) {
+			continue
+		}
+		if tick != nil && n > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-tick:
+			}
+		}
+		n++
+		err = pub.Publish(ctx, r.Event)
+		if report != nil {
+			report(r, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type deadLetterSource []*event.DeadLetterRecord
+
+func (src *deadLetterSource) Next(context.Context) (*event.DeadLetterRecord, error) {
+	if len(*src) == 0 {
+		return nil, io.EOF
+	}
+	r := (*src)[0]
+	*src = (*src)[1:]
+	return r, nil
+}
+
+func TestRedrive(t *testing.T) {
+	ctx := context.Background()
+	src := &deadLetterSource{
+		{Event: eventCreated(1), Subscriber: "sub1"},
+		{Event: eventCreated(2), Subscriber: "sub2"},
+		{Event: eventCreated(3), Subscriber: "sub1"},
+		{Event: eventCreated(4), Subscriber: "sub1"},
+	}
+	sub1 := &logged{}
+	pub := event.NewMapping().On(eventTypeCreated, event.Func(func(ctx context.Context, ev event.Event) error {
+		if ev == eventCreated(3) {
+			return errors.New("handle error")
+		}
+		return sub1.Handle(ctx, ev)
+	}))
+	var reports []string
+	err := event.Redrive(ctx, src, pub, func(r *event.DeadLetterRecord) bool {
+		return r.Subscriber == "sub1"
+	}, 1000, func(r *event.DeadLetterRecord, err error) {
+		reports = append(reports, fmt.Sprint(r.Event, err))
+	})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1), eventCreated(4)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := []string{"1 <nil>", "3 handle error", "4 <nil>"}; !reflect.DeepEqual(reports, expected) {
+		t.Errorf("reports: expected %v, got %v", expected, reports)
+	}
+}
+
+type deadLetterSourceError struct{}
+
+func (deadLetterSourceError) Next(context.Context) (*event.DeadLetterRecord, error) {
+	return nil, errors.New("source error")
+}
+
+func TestRedriveError(t *testing.T) {
+	ctx := context.Background()
+	err := event.Redrive(ctx, deadLetterSourceError{}, event.Discard, nil, 0, nil)
+	if expected := "source error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	src := &deadLetterSource{{Event: eventCreated(1)}, {Event: eventCreated(2)}}
+	err = event.Redrive(ctx, src, event.Func(func(context.Context, event.Event) error {
+		cancel()
+		return nil
+	}), nil, 1, nil)
+	if expected := context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestRedriveClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	src := &deadLetterSource{{Event: eventCreated(1)}, {Event: eventCreated(2)}, {Event: eventCreated(3)}}
+	published := make(chan event.Event)
+	errc := make(chan error)
+	go func() {
+		errc <- event.Redrive(ctx, src, event.Func(func(_ context.Context, ev event.Event) error {
+			published <- ev
+			return nil
+		}), nil, 1, nil, event.RedriveClock(clock))
+	}()
+	for i := 1; i <= 3; i++ {
+		if i > 1 {
+			clock.Advance(time.Second)
+		}
+		if got, expected := <-published, eventCreated(i); got != expected {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package event
+
+import (
+	"strconv"
+	"sync"
+)
+
+var types = struct {
+	sync.Mutex
+	names map[Type]string
+}{names: make(map[Type]string)}
+
+// MustRegisterType registers the name of the event type. This function panics
+// if the type is already registered with another name, which is useful to
+// detect the collisions of the event types across packages at init time.
+//
+//	func init() {
+//		event.MustRegisterType(EventTypeUserCreated, "UserCreated")
+//	}
+func MustRegisterType(typ Type, name string) {
+	types.Lock()
+	defer types.Unlock()
+	if n, ok := types.names[typ]; ok && n != name {
+		panic("event: event type " + strconv.Itoa(int(typ)) + " is registered as both " +
+			strconv.Quote(n) + " and " + strconv.Quote(name))
+	}
+	types.names[typ] = name
+}
+
+// TypeName returns the name of the event type registered by MustRegisterType.
+func TypeName(typ Type) (string, bool) {
+	types.Lock()
+	defer types.Unlock()
+	name, ok := types.names[typ]
+	return name, ok
+}
@@ -0,0 +1,26 @@
+package event_test
+
+import (
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestMustRegisterType(t *testing.T) {
+	typ := event.Namespace(100).Type(1)
+	event.MustRegisterType(typ, "Created")
+	event.MustRegisterType(typ, "Created")
+	if name, ok := event.TypeName(typ); !ok || name != "Created" {
+		t.Errorf("expected registered name, got %q", name)
+	}
+	if _, ok := event.TypeName(typ + 1); ok {
+		t.Errorf("expected not registered")
+	}
+	defer func() {
+		expected := `event: event type 6553601 is registered as both "Created" and "Updated"`
+		if r := recover(); r != expected {
+			t.Errorf("expected panic %v, got %v", expected, r)
+		}
+	}()
+	event.MustRegisterType(typ, "Updated")
+}
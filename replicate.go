@@ -0,0 +1,346 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Replication is the replication metadata of an event. The ID is unique across
+// the regions as it is prefixed by the origin region, so that the replicas
+// can deduplicate the events delivered more than once.
+type Replication struct {
+	ID     string
+	Origin string
+}
+
+type replicationKey struct{}
+
+// replicated is the replication of the event, carried by the context.
+type replicated struct {
+	replication *Replication
+	event       Event
+}
+
+// ReplicationFromContext returns the replication of the event being handled,
+// or nil if the context does not carry a replication.
+func ReplicationFromContext(ctx context.Context) *Replication {
+	if r, ok := ctx.Value(replicationKey{}).(*replicated); ok {
+		return r.replication
+	}
+	return nil
+}
+
+func withReplication(ctx context.Context, r *Replication, ev Event) context.Context {
+	return context.WithValue(ctx, replicationKey{}, &replicated{r, ev})
+}
+
+// Replicator is an event publisher to publish the events to the local
+// publisher, and replicate them asynchronously to the publishers of the remote
+// regions, for active-active deployments. The replicated events are published
+// with the context carrying the Replication, and the events published with a
+// replication from another region are published only to the local publisher,
+// so that the events are not replicated back. The new events published by the
+// subscribers of the replicated events are replicated as the events of this
+// region.
+type Replicator struct {
+	region    string
+	local     Publisher
+	newID     func() string
+	attempts  int
+	backoff   time.Duration
+	onError   func(error)
+	mu        sync.RWMutex
+	replicas  []*replica
+	quiescing bool
+	closed    bool
+	cancel    context.CancelFunc
+	ctx       context.Context
+	wg        sync.WaitGroup
+	done      chan struct{}
+	group     *Group
+	clock     Clock
+}
+
+type replica struct {
+	region string
+	pub    Publisher
+	events chan replicatedEvent
+	mu     sync.Mutex
+	stats  ReplicaStats
+}
+
+type replicatedEvent struct {
+	event       Event
+	replication *Replication
+	published   time.Time
+}
+
+// ReplicaStats is the statistics of the replication to a region. The lag is
+// the duration from publishing the last replicated event to the local
+// publisher until it is published to the region.
+type ReplicaStats struct {
+	Pending    int
+	Replicated uint64
+	Failed     uint64
+	Lag        time.Duration
+}
+
+// ErrReplicatorClosed is the error returned on publishing to the closed
+// replicator.
+var ErrReplicatorClosed = errors.New("replicator closed")
+
+// NewReplicator creates a new replicating publisher of the region.
+func NewReplicator(region string, local Publisher) *Replicator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Replicator{
+		region: region, local: local, newID: newTraceID,
+		attempts: 1, ctx: ctx, cancel: cancel, done: make(chan struct{}),
+	}
+}
+
+// Replica adds a remote region with the capacity of the replication queue.
+// The publisher is typically a Bridge to the transport of the region. This
+// method returns the publisher to allow method chaining.
+func (pub *Replicator) Replica(region string, remote Publisher, capacity int) *Replicator {
+	r := &replica{region: region, pub: remote, events: make(chan replicatedEvent, capacity)}
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	pub.replicas = append(pub.replicas, r)
+	pub.wg.Add(1)
+	spawnIn(pub.group, func() { pub.work(r) })
+	return pub
+}
+
+// IDs sets the function to generate the IDs of the events, which are prefixed
+// by the region. The IDs are random 128-bit hex strings by default. This
+// method returns the publisher to allow method chaining.
+func (pub *Replicator) IDs(newID func() string) *Replicator {
+	pub.newID = newID
+	return pub
+}
+
+// Retry sets the max number of attempts and the interval between the attempts
+// to replicate an event. The events are not retried by default. This method
+// returns the publisher to allow method chaining.
+func (pub *Replicator) Retry(attempts int, backoff time.Duration) *Replicator {
+	pub.attempts, pub.backoff = attempts, backoff
+	return pub
+}
+
+// Group sets the group to own the goroutines of the replication, so that
+// waiting for the group waits for the queued events to be replicated. Set the
+// group before adding the replicas, and close the replicator before waiting
+// for the group. The replicator is closed when the context of the group is
+// done. This method returns the publisher to allow method chaining.
+func (pub *Replicator) Group(g *Group) *Replicator {
+	pub.group = g
+	g.Go(func() error {
+		select {
+		case <-g.Context().Done():
+			return pub.Close(context.Background())
+		case <-pub.done:
+			return nil
+		}
+	})
+	return pub
+}
+
+// Clock sets the clock of the backoff, the lag and the drain summary instead of
+// the global clock. This method returns the publisher to allow method
+// chaining.
+func (pub *Replicator) Clock(c Clock) *Replicator {
+	pub.clock = c
+	return pub
+}
+
+// ErrorHandler sets the function to report the errors on replicating the
+// events. The errors are ignored by default. This method returns the publisher
+// to allow method chaining.
+func (pub *Replicator) ErrorHandler(f func(error)) *Replicator {
+	pub.onError = f
+	return pub
+}
+
+// Handle implements Subscriber for Replicator.
+func (pub *Replicator) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Replicator. The event is replicated only
+// when the local publisher succeeds. The events exceeding the capacity of the
+// replication queue, or published while closing the replicator, are reported
+// to the dropped event observer as DropUnreplicated.
+func (pub *Replicator) Publish(ctx context.Context, ev Event) error {
+	if err := pub.accepting(); err != nil {
+		return err
+	}
+	if r, ok := ctx.Value(replicationKey{}).(*replicated); ok &&
+		r.replication.Origin != pub.region && sameEvent(r.event, ev) {
+		return pub.local.Publish(ctx, ev)
+	}
+	r := &Replication{ID: pub.region + "-" + pub.newID(), Origin: pub.region}
+	ctx = withReplication(ctx, r, ev)
+	if err := pub.local.Publish(ctx, ev); err != nil {
+		return err
+	}
+	published := clockOr(pub.clock).Now()
+	pub.mu.RLock()
+	defer pub.mu.RUnlock()
+	for _, rep := range pub.replicas {
+		if !pub.closed {
+			select {
+			case rep.events <- replicatedEvent{ev, r, published}:
+				continue
+			default:
+			}
+		}
+		rep.mu.Lock()
+		rep.stats.Failed++
+		rep.mu.Unlock()
+		dropped(ctx, ev, DropUnreplicated)
+	}
+	return nil
+}
+
+func (pub *Replicator) accepting() error {
+	pub.mu.RLock()
+	defer pub.mu.RUnlock()
+	if pub.quiescing {
+		return ErrQuiescing
+	}
+	if pub.closed {
+		return ErrReplicatorClosed
+	}
+	return nil
+}
+
+func (pub *Replicator) work(r *replica) {
+	defer pub.wg.Done()
+	for ev := range r.events {
+		err := pub.replicate(r, ev)
+		r.mu.Lock()
+		if err != nil {
+			r.stats.Failed++
+		} else {
+			r.stats.Replicated++
+			r.stats.Lag = clockOr(pub.clock).Now().Sub(ev.published)
+		}
+		r.mu.Unlock()
+		if err != nil {
+			dropped(withReplication(context.Background(), ev.replication, ev.event), ev.event, DropUnreplicated)
+			if pub.onError != nil {
+				pub.onError(&ReplicationError{r.region, ev.event, err})
+			}
+		}
+	}
+}
+
+func (pub *Replicator) replicate(r *replica, ev replicatedEvent) (err error) {
+	ctx := withReplication(pub.ctx, ev.replication, ev.event)
+	for i := 0; i < pub.attempts || i == 0; i++ {
+		if i > 0 {
+			timer := clockOr(pub.clock).NewTimer(pub.backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C():
+			}
+		}
+		if err = r.pub.Publish(ctx, ev.event); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Stats returns the statistics of the replication to the region.
+func (pub *Replicator) Stats(region string) ReplicaStats {
+	pub.mu.RLock()
+	defer pub.mu.RUnlock()
+	for _, r := range pub.replicas {
+		if r.region == region {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			stats := r.stats
+			stats.Pending = len(r.events)
+			return stats
+		}
+	}
+	return ReplicaStats{}
+}
+
+// Close stops accepting the events, and waits for the queued events to be
+// replicated until the context is done. The retrying of the replication is
+// canceled when the context is done.
+func (pub *Replicator) Close(ctx context.Context) error {
+	pub.mu.Lock()
+	if !pub.closed {
+		pub.closed = true
+		for _, r := range pub.replicas {
+			close(r.events)
+		}
+		spawnIn(pub.group, func() {
+			pub.wg.Wait()
+			close(pub.done)
+		})
+	}
+	pub.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		pub.cancel()
+		return ctx.Err()
+	case <-pub.done:
+		pub.cancel()
+		return nil
+	}
+}
+
+// Quiesce implements Quiescer for Replicator. This method closes the
+// replicator, and the publishing returns ErrQuiescing instead of
+// ErrReplicatorClosed.
+func (pub *Replicator) Quiesce(ctx context.Context) (DrainSummary, error) {
+	clock := clockOr(pub.clock)
+	start := clock.Now()
+	pub.mu.Lock()
+	pub.quiescing = true
+	queued := pub.queued()
+	pub.mu.Unlock()
+	err := pub.Close(ctx)
+	pub.mu.RLock()
+	remaining := pub.queued()
+	pub.mu.RUnlock()
+	return DrainSummary{
+		Drained: queued - remaining, Remaining: remaining, Elapsed: clock.Now().Sub(start),
+	}, err
+}
+
+func (pub *Replicator) queued() int {
+	var n int
+	for _, l := range pub.replicas {
+		n += len(l.events)
+	}
+	return n
+}
+
+// ReplicationError is the error reported by Replicator on failing to
+// replicate an event to the region.
+type ReplicationError struct {
+	Region string
+	Event  Event
+	Err    error
+}
+
+// Error implements error for ReplicationError.
+func (err *ReplicationError) Error() string {
+	return "replicate event type " + strconv.Itoa(int(err.Event.Type())) +
+		" to " + err.Region + ": " + err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *ReplicationError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,287 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestReplicator(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var replications []*event.Replication
+	local1, local2 := &logged{}, &logged{}
+	var pub1, pub2 *event.Replicator
+	pub1 = event.NewReplicator("us", event.Func(local1.Handle)).
+		Replica("eu", event.Func(func(ctx context.Context, ev event.Event) error {
+			mu.Lock()
+			replications = append(replications, event.ReplicationFromContext(ctx))
+			mu.Unlock()
+			return pub2.Publish(ctx, ev)
+		}), 10)
+	pub2 = event.NewReplicator("eu", event.Func(local2.Handle)).
+		Replica("us", event.Func(func(ctx context.Context, ev event.Event) error {
+			return pub1.Publish(ctx, ev)
+		}), 10)
+	if r := event.ReplicationFromContext(ctx); r != nil {
+		t.Errorf("expected no replication, got %+v", r)
+	}
+	evs := []event.Event{eventCreated(1), eventUpdated(2), eventDeleted(3)}
+	for _, ev := range evs {
+		if err := pub1.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub1.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := pub2.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !reflect.DeepEqual(local1.Events(), evs) {
+		t.Errorf("local1 handled events: expected %v, got %v", evs, local1.Events())
+	}
+	if !reflect.DeepEqual(local2.Events(), evs) {
+		t.Errorf("local2 handled events: expected %v, got %v", evs, local2.Events())
+	}
+	if expected := 3; len(replications) != expected {
+		t.Fatalf("expected %d replications, got %d", expected, len(replications))
+	}
+	for _, r := range replications {
+		if r.Origin != "us" || !strings.HasPrefix(r.ID, "us-") {
+			t.Errorf("unexpected replication: %+v", r)
+		}
+	}
+	if got := pub1.Stats("eu"); got.Replicated != 3 || got.Failed != 0 || got.Pending != 0 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+	if got := pub2.Stats("us"); got.Replicated != 0 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+	if err, expected := pub1.Publish(ctx, eventCreated(4)), event.ErrReplicatorClosed; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestReplicatorFollowUp(t *testing.T) {
+	ctx := context.Background()
+	local1, local2 := &logged{}, &logged{}
+	var pub1, pub2 *event.Replicator
+	pub1 = event.NewReplicator("us", event.Func(local1.Handle)).
+		Replica("eu", event.Func(func(ctx context.Context, ev event.Event) error {
+			return pub2.Publish(ctx, ev)
+		}), 10)
+	pub2 = event.NewReplicator("eu", event.NewMapping().
+		On(eventTypeCreated, local2).
+		On(eventTypeCreated, event.Func(func(ctx context.Context, ev event.Event) error {
+			return pub2.Publish(ctx, eventUpdated(ev.(eventCreated)*10))
+		})).
+		On(eventTypeUpdated, local2)).
+		Replica("us", event.Func(func(ctx context.Context, ev event.Event) error {
+			if r := event.ReplicationFromContext(ctx); r == nil || r.Origin != "eu" {
+				t.Errorf("unexpected replication: %+v", r)
+			}
+			return pub1.Publish(ctx, ev)
+		}), 10)
+	if err := pub1.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for i := 0; pub2.Stats("us").Replicated == 0; i++ {
+		if i == 100 {
+			t.Fatalf("timed out waiting for the replication")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, pub := range []*event.Replicator{pub1, pub2} {
+		if err := pub.Close(ctx); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []event.Event{eventCreated(1), eventUpdated(10)}; !reflect.DeepEqual(local1.Events(), expected) {
+		t.Errorf("local1 handled events: expected %v, got %v", expected, local1.Events())
+	}
+	if expected := []event.Event{eventCreated(1), eventUpdated(10)}; !reflect.DeepEqual(local2.Events(), expected) {
+		t.Errorf("local2 handled events: expected %v, got %v", expected, local2.Events())
+	}
+	if got := pub1.Stats("eu"); got.Replicated != 1 || got.Failed != 0 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+	if got := pub2.Stats("us"); got.Replicated != 1 || got.Failed != 0 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+}
+
+func TestReplicatorGroup(t *testing.T) {
+	g, ctx := event.NewGroup(context.Background())
+	var mu sync.Mutex
+	var remote []event.Event
+	pub := event.NewReplicator("us", event.Discard).
+		Group(g).
+		Replica("eu", event.Func(func(_ context.Context, ev event.Event) error {
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			remote = append(remote, ev)
+			return nil
+		}), 10)
+	evs := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)}
+	for _, ev := range evs {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !reflect.DeepEqual(remote, evs) {
+		t.Errorf("remote handled events: expected %v, got %v", evs, remote)
+	}
+	g, ctx = event.NewGroup(context.Background())
+	pub = event.NewReplicator("us", event.Discard).Group(g)
+	g.Go(func() error { return errors.New("group error") })
+	if err, expected := g.Wait(), "group error"; err == nil || err.Error() != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if err, expected := pub.Publish(ctx, eventCreated(4)), event.ErrReplicatorClosed; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestReplicatorClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	pub := event.NewReplicator("us", event.Discard).
+		Clock(clock).
+		Replica("eu", event.Func(func(context.Context, event.Event) error {
+			clock.Advance(time.Minute)
+			return nil
+		}), 10)
+	if err := pub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got := pub.Stats("eu"); got.Replicated != 1 || got.Lag != time.Minute {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+}
+
+func TestReplicatorRetry(t *testing.T) {
+	ctx := context.Background()
+	var drops []event.Event
+	event.SetDroppedEventObserver(func(_ context.Context, ev event.Event, reason event.DropReason) {
+		if reason == event.DropUnreplicated {
+			drops = append(drops, ev)
+		}
+	})
+	defer event.SetDroppedEventObserver(nil)
+	var errs []error
+	var attempts int
+	var ids []string
+	remote := &logged{}
+	pub := event.NewReplicator("us", event.Discard).
+		IDs(func() string { return "x" }).
+		Replica("eu", event.Func(func(ctx context.Context, ev event.Event) error {
+			ids = append(ids, event.ReplicationFromContext(ctx).ID)
+			if attempts++; ev == eventCreated(2) || attempts == 1 {
+				return errors.New("replicate error")
+			}
+			return remote.Handle(ctx, ev)
+		}), 10).
+		Retry(3, time.Millisecond).
+		ErrorHandler(func(err error) { errs = append(errs, err) })
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(remote.Events(), expected) {
+		t.Errorf("remote handled events: expected %v, got %v", expected, remote.Events())
+	}
+	if expected := []event.Event{eventCreated(2)}; !reflect.DeepEqual(drops, expected) {
+		t.Errorf("dropped events: expected %v, got %v", expected, drops)
+	}
+	if expected := "replicate event type 0 to eu: replicate error"; len(errs) != 1 || errs[0].Error() != expected {
+		t.Errorf("expected %q, got %v", expected, errs)
+	}
+	if err := errors.Unwrap(errs[0]); err == nil || err.Error() != "replicate error" {
+		t.Errorf("expected the underlying error, got %v", err)
+	}
+	if expected := []string{"us-x", "us-x", "us-x", "us-x", "us-x"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+	if got := pub.Stats("eu"); got.Replicated != 1 || got.Failed != 1 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+}
+
+func TestReplicatorDrop(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var drops []event.Event
+	event.SetDroppedEventObserver(func(_ context.Context, ev event.Event, reason event.DropReason) {
+		if reason == event.DropUnreplicated {
+			mu.Lock()
+			defer mu.Unlock()
+			drops = append(drops, ev)
+		}
+	})
+	defer event.SetDroppedEventObserver(nil)
+	block, handling := make(chan struct{}), make(chan struct{}, 10)
+	pub := event.NewReplicator("us", event.Func(func(_ context.Context, ev event.Event) error {
+		if ev == eventDeleted(4) {
+			return errors.New("local error")
+		}
+		return nil
+	})).
+		Clock(eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))).
+		Replica("eu", event.Func(func(context.Context, event.Event) error {
+			handling <- struct{}{}
+			<-block
+			return errors.New("replicate error")
+		}), 1).
+		Retry(3, time.Minute)
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if ev == eventCreated(1) {
+			<-handling
+		}
+	}
+	if err, expected := pub.Publish(ctx, eventDeleted(4)), "local error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err, expected := pub.Close(cctx), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	close(block)
+	if err := pub.Close(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(3), eventCreated(1), eventCreated(2)}; !reflect.DeepEqual(drops, expected) {
+		t.Errorf("dropped events: expected %v, got %v", expected, drops)
+	}
+	if got := pub.Stats("eu"); got.Replicated != 0 || got.Failed != 3 {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+	if got := pub.Stats("ap"); got != (event.ReplicaStats{}) {
+		t.Errorf("unexpected stats: %+v", got)
+	}
+}
@@ -0,0 +1,119 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// Ring is a consistent hash ring to select the partition of the key, so that
+// the events of the same key are placed on the same partition, and only a
+// fraction of the keys move on changing the number of the partitions. The
+// keys are formatted by fmt.Fprint and hashed by the hash function, so the
+// partitioning of the transports agrees with the ring by using the same hash
+// function and the same number of the partitions.
+type Ring struct {
+	points []ringPoint
+	hash   func([]byte) uint32
+}
+
+type ringPoint struct {
+	hash      uint32
+	partition int
+}
+
+// RingOption is the option of Ring.
+type RingOption func(*ringOptions)
+
+type ringOptions struct {
+	replicas int
+	hash     func([]byte) uint32
+}
+
+// RingReplicas sets the number of the virtual nodes of each partition on the
+// ring. More virtual nodes distribute the keys more evenly. The default is
+// 100.
+func RingReplicas(n int) RingOption {
+	return func(opts *ringOptions) { opts.replicas = n }
+}
+
+// RingHash sets the hash function of the keys and the virtual nodes. The
+// default is 32-bit FNV-1a mixed by the finalizer of MurmurHash3, which
+// spreads the hashes of the short keys over the ring.
+func RingHash(hash func([]byte) uint32) RingOption {
+	return func(opts *ringOptions) { opts.hash = hash }
+}
+
+// NewRing creates a new consistent hash ring of the partitions. The virtual
+// nodes of the partition i are placed at the hashes of "i-0", "i-1", and so on.
+func NewRing(partitions int, opts ...RingOption) *Ring {
+	o := ringOptions{replicas: 100, hash: ringHash}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	r := &Ring{hash: o.hash}
+	for i := 0; i < partitions; i++ {
+		for j := 0; j < o.replicas; j++ {
+			h := o.hash([]byte(strconv.Itoa(i) + "-" + strconv.Itoa(j)))
+			r.points = append(r.points, ringPoint{h, i})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+		return r.points[i].partition < r.points[j].partition
+	})
+	return r
+}
+
+// Partition returns the partition index of the key, which is the partition of
+// the first virtual node clockwise from the hash of the key. This method
+// returns -1 if the ring has no partitions.
+func (r *Ring) Partition(key interface{}) int {
+	if len(r.points) == 0 {
+		return -1
+	}
+	h := r.hash([]byte(fmt.Sprint(key)))
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].partition
+}
+
+func ringHash(b []byte) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write(b)
+	x := h.Sum32()
+	x ^= x >> 16
+	x *= 0x85ebca6b
+	x ^= x >> 13
+	x *= 0xc2b2ae35
+	x ^= x >> 16
+	return x
+}
+
+// Sharded creates an event subscriber to handle each event by one of the
+// subscribers selected by the partition of the key on the ring of the same
+// number of partitions as the subscribers, so that the events of the same key
+// are handled by the same subscriber. The key function returns false to
+// select the subscriber randomly. The events are ignored when no subscribers
+// are specified, as well as an empty Async.
+func Sharded(key func(Event) (interface{}, bool), subs ...Subscriber) Func {
+	r := NewRing(len(subs))
+	return func(ctx context.Context, ev Event) error {
+		if len(subs) == 0 {
+			return nil
+		}
+		if k, ok := key(ev); ok {
+			return subs[r.Partition(k)].Handle(ctx, ev)
+		}
+		return subs[rand.Intn(len(subs))].Handle(ctx, ev)
+	}
+}
@@ -0,0 +1,105 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestRing(t *testing.T) {
+	r := event.NewRing(4)
+	counts := make([]int, 4)
+	placement := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		key := "key" + strconv.Itoa(i)
+		p := r.Partition(key)
+		if p != r.Partition(key) {
+			t.Fatalf("expected the same partition for %q", key)
+		}
+		counts[p]++
+		placement[key] = p
+	}
+	for i, count := range counts {
+		if count < 1500 || count > 3500 {
+			t.Errorf("partition %d: unbalanced count %d", i, count)
+		}
+	}
+	r = event.NewRing(5)
+	var moved int
+	for key, p := range placement {
+		if q := r.Partition(key); q != p {
+			if q != 4 {
+				t.Fatalf("expected %q to move to the new partition, got %d", key, q)
+			}
+			moved++
+		}
+	}
+	if moved < 1000 || moved > 3000 {
+		t.Errorf("expected about a fifth of the keys to move, got %d", moved)
+	}
+	if got, expected := event.NewRing(0).Partition("key"), -1; got != expected {
+		t.Errorf("expected %d, got %d", expected, got)
+	}
+	r = event.NewRing(3, event.RingReplicas(1), event.RingHash(func(b []byte) uint32 {
+		n, _ := strconv.Atoi(string(b[:1]))
+		return uint32(n * 10)
+	}))
+	for key, expected := range map[string]int{"0": 0, "1": 1, "2": 2, "3": 0, "9": 0} {
+		if got := r.Partition(key); got != expected {
+			t.Errorf("partition of %q: expected %d, got %d", key, expected, got)
+		}
+	}
+	r = event.NewRing(3, event.RingHash(func([]byte) uint32 { return 0 }))
+	if got, expected := r.Partition("key"), 0; got != expected {
+		t.Errorf("expected %d, got %d", expected, got)
+	}
+}
+
+func TestSharded(t *testing.T) {
+	ctx := context.Background()
+	subs := []*logged{{}, {}, {}}
+	sub := event.Sharded(func(ev event.Event) (interface{}, bool) {
+		return int(ev.(eventCreated)) % 10, true
+	}, subs[0], subs[1], subs[2])
+	for i := 0; i < 100; i++ {
+		if err := sub.Handle(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	r := event.NewRing(3)
+	for i, s := range subs {
+		for _, ev := range s.Events() {
+			if p := r.Partition(int(ev.(eventCreated)) % 10); p != i {
+				t.Errorf("expected %v handled by %d, got %d", ev, p, i)
+			}
+		}
+	}
+	var total int
+	for _, s := range subs {
+		total += len(s.Events())
+	}
+	if expected := 100; total != expected {
+		t.Errorf("expected %d events handled, got %d", expected, total)
+	}
+	sub1 := &logged{}
+	sub = event.Sharded(func(event.Event) (interface{}, bool) { return nil, false }, sub1)
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []event.Event{eventCreated(1)}; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+}
+
+func TestShardedEmpty(t *testing.T) {
+	ctx := context.Background()
+	for _, ok := range []bool{true, false} {
+		sub := event.Sharded(func(event.Event) (interface{}, bool) { return 1, ok })
+		if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+}
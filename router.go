@@ -0,0 +1,56 @@
+package event
+
+import "context"
+
+// Router is an event publisher for routing events by their contents, rather
+// than only by their types. The routes are evaluated in the registered order,
+// and an event is published to the publisher of the first matching route.
+type Router struct {
+	routes   []route
+	fallback Publisher
+}
+
+type route struct {
+	match     func(Event) bool
+	publisher Publisher
+}
+
+// NewRouter creates a new event routing publisher.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Route registers the publisher to publish the events matching the predicate.
+// This method returns the publisher to allow method chaining. Note that this
+// method is not goroutine safe so register all the routes before starting
+// event publishing.
+func (pub *Router) Route(match func(Event) bool, to Publisher) *Router {
+	pub.routes = append(pub.routes, route{match, to})
+	return pub
+}
+
+// Default sets the publisher to publish the events matching none of the
+// routes. The events are ignored when no default publisher is set.
+func (pub *Router) Default(to Publisher) *Router {
+	pub.fallback = to
+	return pub
+}
+
+// Handle implements Subscriber for Router.
+func (pub *Router) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Router.
+func (pub *Router) Publish(ctx context.Context, ev Event) error {
+	for _, r := range pub.routes {
+		if r.match(ev) {
+			return r.publisher.Publish(ctx, ev)
+		}
+	}
+	if pub.fallback != nil {
+		return pub.fallback.Publish(ctx, ev)
+	}
+	dropped(ctx, ev, DropUnrouted)
+	return nil
+}
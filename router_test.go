@@ -0,0 +1,54 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2, sub3 := &logged{}, &logged{}, &logged{}
+	pub := event.NewRouter().
+		Route(func(ev event.Event) bool {
+			v, ok := ev.(eventCreated)
+			return ok && v > 10
+		}, event.NewMapping().On(eventTypeCreated, sub1)).
+		Route(func(ev event.Event) bool {
+			return ev.Type() == eventTypeCreated || ev.Type() == eventTypeUpdated
+		}, event.NewMapping().On(eventTypeCreated, sub2).On(eventTypeUpdated, sub2))
+	evs := []event.Event{
+		eventCreated(1), eventCreated(20), eventUpdated(30), eventOther(40),
+	}
+	for _, ev := range evs {
+		if err := pub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := evs[1:2]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := []event.Event{evs[0], evs[2]}; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+	pub.Default(event.NewMux().Default(sub3))
+	for _, ev := range evs {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := evs[3:]; !reflect.DeepEqual(sub3.Events(), expected) {
+		t.Errorf("sub3 handled events: expected %v, got %v", expected, sub3.Events())
+	}
+}
+
+func TestRouterError(t *testing.T) {
+	ctx := context.Background()
+	pub := event.NewRouter().
+		Route(func(event.Event) bool { return true }, event.NewMapping().On(eventTypeCreated, &suberr{}))
+	if err, expected := pub.Publish(ctx, eventCreated(1)), "handle error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
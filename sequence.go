@@ -0,0 +1,78 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+type sequenceKey struct{}
+
+type sequence struct {
+	seq      uint64
+	restored bool
+	event    Event
+}
+
+// WithSequence returns a copy of the context carrying the sequence number of
+// the event, which is useful for the transports and the replays to restore
+// the sequence numbers of the recorded events.
+func WithSequence(ctx context.Context, seq uint64, ev Event) context.Context {
+	return context.WithValue(ctx, sequenceKey{}, sequence{seq, true, ev})
+}
+
+// SequenceFromContext returns the sequence number of the event being handled,
+// or false if the context does not carry a sequence number of the event. The
+// events published by the handlers with the context do not share the number.
+func SequenceFromContext(ctx context.Context, ev Event) (uint64, bool) {
+	s, ok := ctx.Value(sequenceKey{}).(sequence)
+	if !ok || !sameEvent(s.event, ev) {
+		return 0, false
+	}
+	return s.seq, true
+}
+
+// Sequencer is an event publisher to assign the monotonically increasing
+// sequence numbers to the events, which are stored in the context to handle
+// the events. The sequence numbers restored by WithSequence are kept, and the
+// following events are numbered after them, so that the replays are
+// reproducible and the consumers can detect the gaps. The events published
+// concurrently may be handled out of the order of the sequence numbers.
+type Sequencer struct {
+	publisher Publisher
+	mu        sync.Mutex
+	next      uint64
+}
+
+// NewSequencer creates a new sequencing publisher, starting the sequence
+// numbers from the number, which is the next number of the last published
+// event saved on the previous run.
+func NewSequencer(pub Publisher, start uint64) *Sequencer {
+	return &Sequencer{publisher: pub, next: start}
+}
+
+// Handle implements Subscriber for Sequencer.
+func (pub *Sequencer) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Sequencer.
+func (pub *Sequencer) Publish(ctx context.Context, ev Event) error {
+	s, _ := ctx.Value(sequenceKey{}).(sequence)
+	pub.mu.Lock()
+	if !s.restored || !sameEvent(s.event, ev) {
+		s.seq = pub.next
+	}
+	if s.seq >= pub.next {
+		pub.next = s.seq + 1
+	}
+	pub.mu.Unlock()
+	return pub.publisher.Publish(context.WithValue(ctx, sequenceKey{}, sequence{s.seq, false, ev}), ev)
+}
+
+// Next returns the sequence number of the next event, which should be saved
+// on shutdown to continue the sequence.
+func (pub *Sequencer) Next() uint64 {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	return pub.next
+}
@@ -0,0 +1,89 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestSequencer(t *testing.T) {
+	ctx := context.Background()
+	var seqs []uint64
+	var pub *event.Sequencer
+	pub = event.NewSequencer(event.Func(func(ctx context.Context, ev event.Event) error {
+		seq, ok := event.SequenceFromContext(ctx, ev)
+		if !ok {
+			t.Fatalf("expected a sequence number")
+		}
+		seqs = append(seqs, seq)
+		if ev == eventCreated(2) {
+			return pub.Publish(ctx, eventUpdated(2))
+		}
+		return nil
+	}), 10)
+	if _, ok := event.SequenceFromContext(ctx, eventCreated(1)); ok {
+		t.Errorf("expected no sequence number")
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)} {
+		if err := pub.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := pub.Publish(event.WithSequence(ctx, 20, eventCreated(4)), eventCreated(4)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := pub.Publish(event.WithSequence(ctx, 5, eventCreated(5)), eventCreated(5)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := pub.Handle(ctx, eventCreated(6)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []uint64{10, 11, 12, 13, 20, 5, 21}; !reflect.DeepEqual(seqs, expected) {
+		t.Errorf("expected %v, got %v", expected, seqs)
+	}
+	if got, expected := pub.Next(), uint64(22); got != expected {
+		t.Errorf("expected %d, got %d", expected, got)
+	}
+}
+
+func TestSequencerNested(t *testing.T) {
+	ctx := context.Background()
+	var seqs []uint64
+	var unnumbered []event.Event
+	var mapping event.Mapping
+	pub := event.NewSequencer(event.Func(func(ctx context.Context, ev event.Event) error {
+		return mapping.Publish(ctx, ev)
+	}), 10)
+	record := event.Func(func(ctx context.Context, ev event.Event) error {
+		if seq, ok := event.SequenceFromContext(ctx, ev); ok {
+			seqs = append(seqs, seq)
+		} else {
+			unnumbered = append(unnumbered, ev)
+		}
+		return nil
+	})
+	mapping = event.NewMapping().
+		On(eventTypeCreated, record).
+		On(eventTypeCreated, event.Func(func(ctx context.Context, ev event.Event) error {
+			if err := mapping.Publish(ctx, eventUpdated(ev.(eventCreated))); err != nil {
+				return err
+			}
+			return pub.Publish(ctx, eventDeleted(ev.(eventCreated)))
+		})).
+		On(eventTypeUpdated, record).
+		On(eventTypeDeleted, record)
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := pub.Publish(event.WithSequence(ctx, 20, eventCreated(2)), eventCreated(2)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []uint64{10, 11, 20, 21}; !reflect.DeepEqual(seqs, expected) {
+		t.Errorf("expected %v, got %v", expected, seqs)
+	}
+	if expected := []event.Event{eventUpdated(1), eventUpdated(2)}; !reflect.DeepEqual(unnumbered, expected) {
+		t.Errorf("expected %v, got %v", expected, unnumbered)
+	}
+}
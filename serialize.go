@@ -0,0 +1,34 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// SerializeByType creates an event subscriber to handle the events of the same
+// type one at a time, even when the events are published concurrently, like
+// with Async or DispatchAsync. This is useful for the subscribers maintaining
+// the states per type which are not goroutine safe. The events of different
+// types are handled concurrently.
+func SerializeByType(sub Subscriber) Func {
+	var (
+		mu   sync.Mutex
+		sems = make(map[Type]chan struct{})
+	)
+	return func(ctx context.Context, ev Event) error {
+		mu.Lock()
+		sem, ok := sems[ev.Type()]
+		if !ok {
+			sem = make(chan struct{}, 1)
+			sems[ev.Type()] = sem
+		}
+		mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			return sub.Handle(ctx, ev)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package event_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestSerializeByType(t *testing.T) {
+	ctx := context.Background()
+	var running [2]int32
+	var overlapped, concurrent int32
+	sub := event.SerializeByType(event.Func(func(_ context.Context, ev event.Event) error {
+		if atomic.AddInt32(&running[ev.Type()], 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		if atomic.LoadInt32(&running[1-ev.Type()]) > 0 {
+			atomic.StoreInt32(&concurrent, 1)
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running[ev.Type()], -1)
+		return nil
+	}))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_ = sub.Handle(ctx, eventCreated(i))
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_ = sub.Handle(ctx, eventUpdated(i))
+		}(i)
+	}
+	wg.Wait()
+	if overlapped != 0 {
+		t.Errorf("expected the events of the same type not to be handled concurrently")
+	}
+	if concurrent == 0 {
+		t.Errorf("expected the events of different types to be handled concurrently")
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	block := make(chan struct{})
+	sub = event.SerializeByType(event.Func(func(context.Context, event.Event) error {
+		<-block
+		return nil
+	}))
+	go func() { _ = sub.Handle(ctx, eventCreated(1)) }()
+	time.Sleep(5 * time.Millisecond)
+	if err, expected := sub.Handle(cctx, eventCreated(2)), context.Canceled; err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	close(block)
+}
@@ -0,0 +1,30 @@
+package event
+
+import (
+	"context"
+	"fmt"
+)
+
+// Shadow creates an event subscriber to handle events by both the primary and
+// the candidate subscribers, which is useful to validate a rewritten subscriber
+// in production. The subscriber returns the error of the primary subscriber,
+// and the report function is called when only one of them fails. The panics of
+// the candidate subscriber are recovered and reported as errors.
+func Shadow(primary, candidate Subscriber, report func(ev Event, errPrimary, errCandidate error)) Func {
+	return func(ctx context.Context, ev Event) error {
+		err := primary.Handle(ctx, ev)
+		if e := handleRecover(ctx, candidate, ev); (err == nil) != (e == nil) {
+			report(ev, err, e)
+		}
+		return err
+	}
+}
+
+func handleRecover(ctx context.Context, sub Subscriber, ev Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return sub.Handle(ctx, ev)
+}
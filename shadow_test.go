@@ -0,0 +1,62 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestShadow(t *testing.T) {
+	ctx := context.Background()
+	var reports []string
+	sub1, sub2 := &logged{}, &logged{}
+	sub := event.Shadow(
+		event.Ordered{sub1, event.Func(func(_ context.Context, ev event.Event) error {
+			if ev == eventCreated(2) || ev == eventCreated(4) {
+				return errors.New("primary error")
+			}
+			return nil
+		})},
+		event.Ordered{sub2, event.Func(func(_ context.Context, ev event.Event) error {
+			switch ev {
+			case eventCreated(3), eventCreated(4):
+				return errors.New("candidate error")
+			case eventCreated(5):
+				panic("candidate panic")
+			}
+			return nil
+		})},
+		func(ev event.Event, errPrimary, errCandidate error) {
+			reports = append(reports, fmt.Sprint(ev, errPrimary, errCandidate))
+		},
+	)
+	evs := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3), eventCreated(4), eventCreated(5)}
+	for _, ev := range evs {
+		err := sub.Handle(ctx, ev)
+		if ev == eventCreated(2) || ev == eventCreated(4) {
+			if expected := "primary error"; err == nil || err.Error() != expected {
+				t.Fatalf("expected %v, got %v", expected, err)
+			}
+		} else if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(sub1.Events(), evs) {
+		t.Errorf("sub1 handled events: expected %v, got %v", evs, sub1.Events())
+	}
+	if !reflect.DeepEqual(sub2.Events(), evs) {
+		t.Errorf("sub2 handled events: expected %v, got %v", evs, sub2.Events())
+	}
+	expected := []string{
+		"2 primary error <nil>",
+		"3 <nil> candidate error",
+		"5 <nil> panic: candidate panic",
+	}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("reports: expected %v, got %v", expected, reports)
+	}
+}
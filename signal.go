@@ -0,0 +1,49 @@
+package event
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// Signals publishes the events on receiving the OS signals, so that the reload
+// and the shutdown flows can use the same subscribers as the domain events. The
+// events are published with a background context, and the errors are ignored.
+// Call the returned function to stop relaying the signals, which is safe to
+// call multiple times. Note that mapping os.Interrupt or syscall.SIGTERM
+// disables the default behavior to terminate the process until the relaying is
+// stopped, so the subscribers are responsible for the shutdown. The signals
+// mapped to nil events are ignored, and an empty mapping relays no signals.
+func Signals(pub Publisher, mapping map[os.Signal]Event) (stop func()) {
+	sigs := make([]os.Signal, 0, len(mapping))
+	for sig, ev := range mapping {
+		if ev != nil {
+			sigs = append(sigs, sig)
+		}
+	}
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	ch, done := make(chan os.Signal, len(sigs)), make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-ch:
+				if ev := mapping[sig]; ev != nil {
+					_ = pub.Publish(context.Background(), ev)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
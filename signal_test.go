@@ -0,0 +1,65 @@
+//go:build !windows
+// +build !windows
+
+package event_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestSignals(t *testing.T) {
+	handled := make(chan event.Event, 1)
+	stop := event.Signals(event.Func(func(_ context.Context, ev event.Event) error {
+		handled <- ev
+		return nil
+	}), map[os.Signal]event.Event{syscall.SIGUSR1: eventCreated(1)})
+	defer stop()
+	defer stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	select {
+	case ev := <-handled:
+		if expected := eventCreated(1); ev != expected {
+			t.Errorf("expected %v, got %v", expected, ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for the event")
+	}
+}
+
+func TestSignalsEmpty(t *testing.T) {
+	handled := make(chan event.Event, 2)
+	pub := event.Func(func(_ context.Context, ev event.Event) error {
+		handled <- ev
+		return nil
+	})
+	stop1 := event.Signals(pub, nil)
+	defer stop1()
+	stop2 := event.Signals(pub, map[os.Signal]event.Event{syscall.SIGUSR1: nil})
+	defer stop2()
+	stop3 := event.Signals(pub, map[os.Signal]event.Event{syscall.SIGUSR2: eventCreated(2)})
+	defer stop3()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	select {
+	case ev := <-handled:
+		if expected := eventCreated(2); ev != expected {
+			t.Errorf("expected %v, got %v", expected, ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for the event")
+	}
+	select {
+	case ev := <-handled:
+		t.Errorf("expected no more events, got %v", ev)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,84 @@
+package event
+
+import (
+	"context"
+	"strconv"
+)
+
+// SizePolicy is a function to handle an event whose serialized data exceeds
+// the size limit of MaxSize. The policy returns the event to handle instead,
+// or an error to reject the event.
+type SizePolicy func(ctx context.Context, ev Event, data []byte, limit int) (Event, error)
+
+// RejectOversized is a size policy to reject the oversized events with
+// SizeLimitError.
+func RejectOversized(_ context.Context, ev Event, data []byte, limit int) (Event, error) {
+	return nil, &SizeLimitError{ev, len(data), limit}
+}
+
+// TruncateOversized creates a size policy to truncate the oversized events by
+// the function, like trimming a long description of the event.
+func TruncateOversized(truncate func(ev Event, limit int) Event) SizePolicy {
+	return func(_ context.Context, ev Event, _ []byte, limit int) (Event, error) {
+		return truncate(ev, limit), nil
+	}
+}
+
+// ClaimCheck creates a size policy to store the serialized data of the
+// oversized events to an external storage, and handle a reference event
+// instead, which the consumers use to load the original event.
+func ClaimCheck(
+	store func(context.Context, []byte) (string, error),
+	reference func(ev Event, id string) Event,
+) SizePolicy {
+	return func(ctx context.Context, ev Event, data []byte, _ int) (Event, error) {
+		id, err := store(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		return reference(ev, id), nil
+	}
+}
+
+// MaxSize creates a middleware to guard the subscriber from the events whose
+// serialized data exceeds the limit in bytes, which is useful before the
+// transports with hard limits of the message size. The oversized events are
+// handled by the policy, and SizeLimitError is returned when the event by the
+// policy still exceeds the limit.
+func MaxSize(limit int, encode func(Event) ([]byte, error), policy SizePolicy) func(Subscriber) Subscriber {
+	return func(sub Subscriber) Subscriber {
+		return Func(func(ctx context.Context, ev Event) error {
+			data, err := encode(ev)
+			if err != nil {
+				return err
+			}
+			if len(data) <= limit {
+				return sub.Handle(ctx, ev)
+			}
+			if ev, err = policy(ctx, ev, data, limit); err != nil {
+				return err
+			}
+			if data, err = encode(ev); err != nil {
+				return err
+			}
+			if len(data) > limit {
+				return &SizeLimitError{ev, len(data), limit}
+			}
+			return sub.Handle(ctx, ev)
+		})
+	}
+}
+
+// SizeLimitError is the error on the event exceeding the size limit of
+// MaxSize.
+type SizeLimitError struct {
+	Event Event
+	Size  int
+	Limit int
+}
+
+// Error implements error for SizeLimitError.
+func (err *SizeLimitError) Error() string {
+	return "event size limit exceeded: " + strconv.Itoa(err.Size) +
+		" bytes > " + strconv.Itoa(err.Limit) + " bytes"
+}
@@ -0,0 +1,141 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+type eventMessage string
+
+func (eventMessage) Type() event.Type {
+	return eventTypeOther
+}
+
+func encodeMessage(ev event.Event) ([]byte, error) {
+	if ev, ok := ev.(eventMessage); ok {
+		return []byte(ev), nil
+	}
+	return nil, errors.New("encode error")
+}
+
+func TestMaxSize(t *testing.T) {
+	ctx := context.Background()
+	stored := make(map[string][]byte)
+	testCases := []struct {
+		name     string
+		policy   event.SizePolicy
+		expected []event.Event
+		err      string
+	}{
+		{
+			name:     "reject",
+			policy:   event.RejectOversized,
+			expected: []event.Event{eventMessage("short")},
+			err:      "event size limit exceeded: 17 bytes > 10 bytes",
+		},
+		{
+			name: "truncate",
+			policy: event.TruncateOversized(func(ev event.Event, limit int) event.Event {
+				return ev.(eventMessage)[:limit]
+			}),
+			expected: []event.Event{eventMessage("short"), eventMessage("very long ")},
+		},
+		{
+			name: "truncate not enough",
+			policy: event.TruncateOversized(func(ev event.Event, limit int) event.Event {
+				return ev.(eventMessage)[:limit+1]
+			}),
+			expected: []event.Event{eventMessage("short")},
+			err:      "event size limit exceeded: 11 bytes > 10 bytes",
+		},
+		{
+			name: "claim check",
+			policy: event.ClaimCheck(func(_ context.Context, data []byte) (string, error) {
+				id := strconv.Itoa(len(stored))
+				stored[id] = data
+				return id, nil
+			}, func(_ event.Event, id string) event.Event {
+				return eventMessage("claim:" + id)
+			}),
+			expected: []event.Event{eventMessage("short"), eventMessage("claim:0")},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub1 := &logged{}
+			sub := event.MaxSize(10, encodeMessage, tc.policy)(sub1)
+			if err := sub.Handle(ctx, eventMessage("short")); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+			err := sub.Handle(ctx, eventMessage("very long message"))
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("got error: %v", err)
+				}
+			} else {
+				var serr *event.SizeLimitError
+				if !errors.As(err, &serr) || err.Error() != tc.err {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+			}
+			if !reflect.DeepEqual(sub1.Events(), tc.expected) {
+				t.Errorf("sub1 handled events: expected %v, got %v", tc.expected, sub1.Events())
+			}
+		})
+	}
+	if got, expected := string(stored["0"]), "very long message"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestMaxSizeError(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name   string
+		policy event.SizePolicy
+		ev     event.Event
+		err    string
+	}{
+		{
+			name:   "encode",
+			policy: event.RejectOversized,
+			ev:     eventCreated(1),
+			err:    "encode error",
+		},
+		{
+			name: "encode truncated",
+			policy: event.TruncateOversized(func(event.Event, int) event.Event {
+				return eventCreated(1)
+			}),
+			ev:  eventMessage("very long message"),
+			err: "encode error",
+		},
+		{
+			name: "claim check",
+			policy: event.ClaimCheck(func(context.Context, []byte) (string, error) {
+				return "", errors.New("store error")
+			}, func(_ event.Event, id string) event.Event {
+				return eventMessage("claim:" + id)
+			}),
+			ev:  eventMessage("very long message"),
+			err: "store error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub1 := &logged{}
+			err := event.MaxSize(10, encodeMessage, tc.policy)(sub1).Handle(ctx, tc.ev)
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			if len(sub1.Events()) != 0 {
+				t.Errorf("sub1 handled events: expected no events, got %v", sub1.Events())
+			}
+		})
+	}
+}
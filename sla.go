@@ -0,0 +1,62 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// Deadliner is the interface for an event which declares the deadline of its
+// processing. The zero time means the event has no deadline.
+type Deadliner interface {
+	Event
+	Deadline() time.Time
+}
+
+// SLA is an event publisher to enforce the deadlines of the events declared by
+// Deadliner. The context is canceled on the deadline, and the violations are
+// reported with the latency over the deadline, which is useful to monitor the
+// end-to-end latency guarantees.
+type SLA struct {
+	publisher Publisher
+	report    func(context.Context, Event, time.Duration)
+	clock     Clock
+}
+
+// NewSLA creates a new publisher to enforce the deadlines of the events.
+func NewSLA(pub Publisher, report func(context.Context, Event, time.Duration)) *SLA {
+	return &SLA{publisher: pub, report: report}
+}
+
+// Clock sets the clock to measure the latency over the deadlines instead of the
+// global clock. Note that the context is canceled on the deadline by the
+// system clock. This method returns the publisher to allow method chaining.
+func (pub *SLA) Clock(c Clock) *SLA {
+	pub.clock = c
+	return pub
+}
+
+// Handle implements Subscriber for SLA.
+func (pub *SLA) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for SLA. An event already past the deadline is
+// not published and context.DeadlineExceeded is returned.
+func (pub *SLA) Publish(ctx context.Context, ev Event) error {
+	d, ok := ev.(Deadliner)
+	if !ok || d.Deadline().IsZero() {
+		return pub.publisher.Publish(ctx, ev)
+	}
+	deadline, clock := d.Deadline(), clockOr(pub.clock)
+	if late := clock.Now().Sub(deadline); late >= 0 {
+		pub.report(ctx, ev, late)
+		return context.DeadlineExceeded
+	}
+	dctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	err := pub.publisher.Publish(dctx, ev)
+	if late := clock.Now().Sub(deadline); late >= 0 {
+		pub.report(ctx, ev, late)
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type eventDeadline struct {
+	eventCreated
+	deadline time.Time
+}
+
+func (ev eventDeadline) Deadline() time.Time {
+	return ev.deadline
+}
+
+func TestSLA(t *testing.T) {
+	ctx := context.Background()
+	var violated []event.Event
+	sub1 := &logged{}
+	pub := event.NewSLA(
+		event.NewMapping().On(eventTypeCreated, event.Ordered{
+			sub1,
+			event.Func(func(ctx context.Context, ev event.Event) error {
+				if ev == eventCreated(1) {
+					return nil
+				}
+				<-ctx.Done()
+				return ctx.Err()
+			}),
+		}),
+		func(_ context.Context, ev event.Event, late time.Duration) {
+			if late < 0 {
+				t.Errorf("expected non-negative latency, got %v", late)
+			}
+			violated = append(violated, ev)
+		},
+	)
+	now := time.Now()
+	evs := []event.Event{
+		eventCreated(1),
+		eventDeadline{eventCreated(2), time.Time{}},
+		eventDeadline{eventCreated(3), now.Add(10 * time.Millisecond)},
+		eventDeadline{eventCreated(4), now.Add(-time.Second)},
+	}
+	if err := pub.Publish(ctx, evs[0]); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err, expected := pub.Publish(cctx, evs[1]), context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	for _, ev := range evs[2:] {
+		if err, expected := pub.Publish(ctx, ev), context.DeadlineExceeded; err != expected {
+			t.Fatalf("expected %v, got %v", expected, err)
+		}
+	}
+	if expected := evs[:3]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := evs[2:]; !reflect.DeepEqual(violated, expected) {
+		t.Errorf("violated events: expected %v, got %v", expected, violated)
+	}
+}
+
+func TestSLAClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	clock := eventtest.NewClock(now)
+	var lates []time.Duration
+	pub := event.NewSLA(event.Func(func(context.Context, event.Event) error {
+		clock.Advance(2 * time.Hour)
+		return nil
+	}), func(_ context.Context, _ event.Event, late time.Duration) {
+		lates = append(lates, late)
+	}).Clock(clock)
+	if err := pub.Handle(ctx, eventDeadline{eventCreated(1), now.Add(time.Hour)}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := pub.Publish(ctx, eventDeadline{eventCreated(2), now}), context.DeadlineExceeded; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if expected := []time.Duration{time.Hour, 2 * time.Hour}; !reflect.DeepEqual(lates, expected) {
+		t.Errorf("expected %v, got %v", expected, lates)
+	}
+}
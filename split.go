@@ -0,0 +1,76 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync/atomic"
+)
+
+// Splitter is an event subscriber to split events between two subscribers by
+// percentage, which is useful to migrate the handling from an old subscriber
+// to a new one gradually.
+type Splitter struct {
+	a, b    Subscriber
+	percent int
+	key     func(Event) (interface{}, bool)
+	stats   [2]SplitStats
+}
+
+// SplitStats is the statistics of a subscriber of Splitter, which is useful to
+// compare the error rates of the subscribers.
+type SplitStats struct {
+	Handled int64
+	Failed  int64
+}
+
+// Split creates a new subscriber to handle the specified percentage of events
+// by the subscriber b, and the rest by the subscriber a.
+func Split(a, b Subscriber, percentToB int) *Splitter {
+	return &Splitter{a: a, b: b, percent: percentToB}
+}
+
+// Sticky makes the subscriber select the subscriber by the hash of the key of
+// the event, so that the events with the same key are handled by the same
+// subscriber. The key function returns false to select the subscriber
+// randomly. This method returns the subscriber to allow method chaining.
+func (sub *Splitter) Sticky(key func(Event) (interface{}, bool)) *Splitter {
+	sub.key = key
+	return sub
+}
+
+// Handle implements Subscriber for Splitter.
+func (sub *Splitter) Handle(ctx context.Context, ev Event) error {
+	i, s := 0, sub.a
+	if bucket(sub.key, ev) < sub.percent {
+		i, s = 1, sub.b
+	}
+	atomic.AddInt64(&sub.stats[i].Handled, 1)
+	err := s.Handle(ctx, ev)
+	if err != nil {
+		atomic.AddInt64(&sub.stats[i].Failed, 1)
+	}
+	return err
+}
+
+// bucket returns the bucket of the event from 0 to 99, by the hash of the key
+// or randomly.
+func bucket(key func(Event) (interface{}, bool), ev Event) int {
+	if key != nil {
+		if k, ok := key(ev); ok {
+			h := fnv.New32a()
+			fmt.Fprint(h, k)
+			return int(h.Sum32() % 100)
+		}
+	}
+	return rand.Intn(100)
+}
+
+// Stats returns the statistics of the subscribers a and b.
+func (sub *Splitter) Stats() (a, b SplitStats) {
+	load := func(s *SplitStats) SplitStats {
+		return SplitStats{atomic.LoadInt64(&s.Handled), atomic.LoadInt64(&s.Failed)}
+	}
+	return load(&sub.stats[0]), load(&sub.stats[1])
+}
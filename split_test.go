@@ -0,0 +1,68 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestSplit(t *testing.T) {
+	ctx := context.Background()
+	for _, percent := range []int{0, 100} {
+		sub1, sub2 := &logged{}, &logged{}
+		sub := event.Split(sub1, sub2, percent)
+		evs := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)}
+		for _, ev := range evs {
+			if err := sub.Handle(ctx, ev); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+		}
+		if percent == 100 {
+			sub1, sub2 = sub2, sub1
+		}
+		if !reflect.DeepEqual(sub1.Events(), evs) {
+			t.Errorf("sub1 handled events: expected %v, got %v", evs, sub1.Events())
+		}
+		if len(sub2.Events()) != 0 {
+			t.Errorf("sub2 handled events: expected no events, got %v", sub2.Events())
+		}
+	}
+}
+
+func TestSplitSticky(t *testing.T) {
+	ctx := context.Background()
+	var handled [2]map[event.Event]bool
+	sub := event.Split(
+		event.Func(func(_ context.Context, ev event.Event) error {
+			handled[0][ev] = true
+			return nil
+		}),
+		event.Ordered{event.Func(func(_ context.Context, ev event.Event) error {
+			handled[1][ev] = true
+			return nil
+		}), suberr{}},
+		50,
+	).Sticky(func(ev event.Event) (interface{}, bool) {
+		return int(ev.(eventCreated)) % 100, true
+	})
+	handled[0], handled[1] = make(map[event.Event]bool), make(map[event.Event]bool)
+	for i := 0; i < 1000; i++ {
+		_ = sub.Handle(ctx, eventCreated(i))
+	}
+	for i := 0; i < 100; i++ {
+		for j := i; j < 1000; j += 100 {
+			if handled[0][eventCreated(i)] != handled[0][eventCreated(j)] {
+				t.Fatalf("expected events %d and %d handled by the same subscriber", i, j)
+			}
+		}
+	}
+	a, b := sub.Stats()
+	if a.Handled+b.Handled != 1000 || a.Handled == 0 || b.Handled == 0 {
+		t.Errorf("unexpected stats: %v, %v", a, b)
+	}
+	if a.Failed != 0 || b.Failed != b.Handled {
+		t.Errorf("unexpected stats: %v, %v", a, b)
+	}
+}
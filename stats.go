@@ -0,0 +1,190 @@
+package event
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TypeStats is the statistics of the events of a type. The latency percentiles
+// are computed from the latest events. The statistics of the subscribers are
+// keyed by the names, and available with MuxProfile.
+type TypeStats struct {
+	Count       int64
+	Errors      int64
+	P50         time.Duration
+	P90         time.Duration
+	P99         time.Duration
+	Subscribers map[string]SubscriberStats
+}
+
+// SubscriberStats is the statistics of a subscriber for the events of a type.
+// The wall time is the total of handling the events.
+type SubscriberStats struct {
+	Count int64
+	Wall  time.Duration
+}
+
+const statsSamples = 1024
+
+type muxStats struct {
+	mu    sync.Mutex
+	types map[Type]*typeStats
+}
+
+type typeStats struct {
+	count       int64
+	errors      int64
+	latencies   []time.Duration
+	subscribers map[string]*SubscriberStats
+}
+
+func (s *muxStats) typeStats(typ Type) *typeStats {
+	t, ok := s.types[typ]
+	if !ok {
+		t = &typeStats{}
+		s.types[typ] = t
+	}
+	return t
+}
+
+func (s *muxStats) record(typ Type, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.typeStats(typ)
+	if len(t.latencies) < statsSamples {
+		t.latencies = append(t.latencies, latency)
+	} else {
+		t.latencies[t.count%statsSamples] = latency
+	}
+	t.count++
+	if err != nil {
+		t.errors++
+	}
+}
+
+func (s *muxStats) profile(sub Subscriber, c Clock) Subscriber {
+	name := subscriberName(sub)
+	return Func(func(ctx context.Context, ev Event) error {
+		clock := clockOr(c)
+		start := clock.Now()
+		err := sub.Handle(ctx, ev)
+		wall := clock.Now().Sub(start)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		t := s.typeStats(ev.Type())
+		if t.subscribers == nil {
+			t.subscribers = make(map[string]*SubscriberStats)
+		}
+		st, ok := t.subscribers[name]
+		if !ok {
+			st = &SubscriberStats{}
+			t.subscribers[name] = st
+		}
+		st.Count++
+		st.Wall += wall
+		return err
+	})
+}
+
+// Stats returns the statistics of the published events per type since the
+// start or the last reset. This method returns nil unless the mux is
+// created with MuxStats.
+func (pub *Mux) Stats() map[Type]TypeStats {
+	if pub.stats == nil {
+		return nil
+	}
+	pub.stats.mu.Lock()
+	defer pub.stats.mu.Unlock()
+	stats := make(map[Type]TypeStats, len(pub.stats.types))
+	for typ, t := range pub.stats.types {
+		latencies := append([]time.Duration(nil), t.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		percentile := func(p int) time.Duration {
+			if len(latencies) == 0 {
+				return 0
+			}
+			return latencies[(len(latencies)*p-1)/100]
+		}
+		var subscribers map[string]SubscriberStats
+		if t.subscribers != nil {
+			subscribers = make(map[string]SubscriberStats, len(t.subscribers))
+			for name, st := range t.subscribers {
+				subscribers[name] = *st
+			}
+		}
+		stats[typ] = TypeStats{
+			t.count, t.errors, percentile(50), percentile(90), percentile(99), subscribers,
+		}
+	}
+	return stats
+}
+
+// ResetStats resets the statistics of the mux.
+func (pub *Mux) ResetStats() {
+	if pub.stats == nil {
+		return
+	}
+	pub.stats.mu.Lock()
+	defer pub.stats.mu.Unlock()
+	pub.stats.types = make(map[Type]*typeStats)
+}
+
+// StatsSnapshot is the statistics of the mux at a time, which is useful to
+// capture the statistics before and after an incident or a load test, and
+// compare them by Diff.
+type StatsSnapshot struct {
+	Time  time.Time
+	Types map[Type]TypeStats
+}
+
+// Snapshot returns the statistics of the mux at the current time. The
+// types are empty unless the mux is created with MuxStats.
+func (pub *Mux) Snapshot() StatsSnapshot {
+	return StatsSnapshot{clockOr(pub.clock).Now(), pub.Stats()}
+}
+
+// StatsDelta is the difference of the statistics between two snapshots. The
+// counts and the times of the types are the increases, and the percentiles
+// are the changes of the percentiles, which are negative when the latencies
+// decreased.
+type StatsDelta struct {
+	Elapsed time.Duration
+	Types   map[Type]TypeStats
+}
+
+// Diff returns the difference of the statistics from the snapshot a to the
+// snapshot b. The statistics of a type are considered reset when the count
+// decreased, and the statistics in b are the difference. The types without
+// the events between the snapshots are omitted.
+func Diff(a, b StatsSnapshot) StatsDelta {
+	d := StatsDelta{Elapsed: b.Time.Sub(a.Time), Types: make(map[Type]TypeStats)}
+	for typ, t := range b.Types {
+		s, ok := a.Types[typ]
+		if !ok || t.Count < s.Count {
+			s = TypeStats{}
+		}
+		if t.Count == s.Count {
+			continue
+		}
+		var subscribers map[string]SubscriberStats
+		if t.Subscribers != nil {
+			subscribers = make(map[string]SubscriberStats, len(t.Subscribers))
+			for name, st := range t.Subscribers {
+				su := s.Subscribers[name]
+				if st.Count < su.Count {
+					su = SubscriberStats{}
+				}
+				if st.Count > su.Count {
+					subscribers[name] = SubscriberStats{st.Count - su.Count, st.Wall - su.Wall}
+				}
+			}
+		}
+		d.Types[typ] = TypeStats{
+			t.Count - s.Count, t.Errors - s.Errors,
+			t.P50 - s.P50, t.P90 - s.P90, t.P99 - s.P99, subscribers,
+		}
+	}
+	return d
+}
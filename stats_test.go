@@ -0,0 +1,205 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+func TestMuxStats(t *testing.T) {
+	ctx := context.Background()
+	pub := event.NewMux(event.MuxStats()).
+		On(eventTypeCreated, event.Func(func(_ context.Context, ev event.Event) error {
+			time.Sleep(time.Duration(ev.(eventCreated)) * time.Millisecond)
+			return nil
+		})).
+		On(eventTypeUpdated, suberr{})
+	if stats := event.NewMux().Stats(); stats != nil {
+		t.Errorf("expected nil stats, got %v", stats)
+	}
+	event.NewMux().ResetStats()
+	for i := 1; i <= 10; i++ {
+		_ = pub.Publish(ctx, eventCreated(i))
+	}
+	for i := 0; i < 3; i++ {
+		_ = pub.Publish(ctx, eventUpdated(i))
+	}
+	stats := pub.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats of 2 types, got %v", stats)
+	}
+	if s := stats[eventTypeCreated]; s.Count != 10 || s.Errors != 0 ||
+		s.P50 < 5*time.Millisecond || s.P90 < 9*time.Millisecond || s.P99 < 10*time.Millisecond ||
+		s.P50 > s.P90 || s.P90 > s.P99 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+	if s := stats[eventTypeUpdated]; s.Count != 3 || s.Errors != 3 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+	pub.ResetStats()
+	if stats := pub.Stats(); len(stats) != 0 {
+		t.Errorf("expected empty stats, got %v", stats)
+	}
+}
+
+func TestMuxProfile(t *testing.T) {
+	ctx := context.Background()
+	spin := event.Func(func(context.Context, event.Event) error {
+		for start := time.Now(); time.Since(start) < 10*time.Millisecond; {
+		}
+		return nil
+	})
+	pub := event.NewMux(event.MuxProfile()).
+		On(eventTypeCreated, event.Build(spin).Named("spin")).
+		On(eventTypeCreated, event.Func(func(context.Context, event.Event) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})).
+		On(eventTypeUpdated, &logged{}).
+		On(eventTypeDeleted, event.Func((&logged{}).Handle))
+	for i := 0; i < 2; i++ {
+		_ = pub.Publish(ctx, eventCreated(i))
+	}
+	_ = pub.Publish(ctx, eventUpdated(1))
+	_ = pub.Publish(ctx, eventDeleted(1))
+	stats := pub.Stats()
+	if s := stats[eventTypeCreated]; s.Count != 2 || len(s.Subscribers) != 2 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if s := stats[eventTypeCreated].Subscribers["spin"]; s.Count != 2 || s.Wall < 20*time.Millisecond {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+	if s := stats[eventTypeCreated].Subscribers["event-go_test.TestMuxProfile.func2"]; s.Count != 2 || s.Wall < 20*time.Millisecond {
+		t.Errorf("unexpected stats: %+v", stats[eventTypeCreated])
+	}
+	if s := stats[eventTypeUpdated].Subscribers["*event_test.logged"]; s.Count != 1 {
+		t.Errorf("unexpected stats: %+v", stats[eventTypeUpdated])
+	}
+	if s := stats[eventTypeDeleted].Subscribers["event-go_test.(*logged).Handle"]; s.Count != 1 {
+		t.Errorf("unexpected stats: %+v", stats[eventTypeDeleted])
+	}
+}
+
+func TestMuxSnapshot(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := eventtest.NewClock(now)
+	pub := event.NewMux(event.MuxStats(), event.MuxClock(clock)).
+		On(eventTypeCreated, event.Discard).
+		On(eventTypeUpdated, suberr{}).
+		On(eventTypeDeleted, event.Discard)
+	_ = pub.Publish(ctx, eventCreated(1))
+	_ = pub.Publish(ctx, eventUpdated(1))
+	_ = pub.Publish(ctx, eventDeleted(1))
+	a := pub.Snapshot()
+	clock.Advance(time.Minute)
+	for i := 0; i < 3; i++ {
+		_ = pub.Publish(ctx, eventCreated(i))
+	}
+	_ = pub.Publish(ctx, eventUpdated(2))
+	b := pub.Snapshot()
+	if !a.Time.Equal(now) || !b.Time.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected snapshot times: %v, %v", a.Time, b.Time)
+	}
+	d := event.Diff(a, b)
+	if expected := time.Minute; d.Elapsed != expected {
+		t.Errorf("expected %v, got %v", expected, d.Elapsed)
+	}
+	if len(d.Types) != 2 {
+		t.Fatalf("expected delta of 2 types, got %v", d.Types)
+	}
+	if s := d.Types[eventTypeCreated]; s.Count != 3 || s.Errors != 0 {
+		t.Errorf("unexpected delta: %+v", s)
+	}
+	if s := d.Types[eventTypeUpdated]; s.Count != 1 || s.Errors != 1 {
+		t.Errorf("unexpected delta: %+v", s)
+	}
+	pub.ResetStats()
+	_ = pub.Publish(ctx, eventCreated(1))
+	d = event.Diff(b, pub.Snapshot())
+	if s := d.Types[eventTypeCreated]; len(d.Types) != 1 || s.Count != 1 {
+		t.Errorf("unexpected delta: %+v", d.Types)
+	}
+}
+
+func TestMuxClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	sub := event.Func(func(context.Context, event.Event) error {
+		clock.Advance(time.Second)
+		return nil
+	})
+	pub := event.NewMux(event.MuxStats(), event.MuxProfile(), event.MuxClock(clock)).
+		On(eventTypeCreated, sub)
+	for i := 0; i < 3; i++ {
+		if err := pub.Publish(ctx, eventCreated(i)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	s := pub.Stats()[eventTypeCreated]
+	if s.Count != 3 || s.P50 != time.Second || s.P99 != time.Second {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+	for _, st := range s.Subscribers {
+		if st.Count != 3 || st.Wall != 3*time.Second {
+			t.Errorf("unexpected subscriber stats: %+v", st)
+		}
+	}
+	if len(s.Subscribers) != 1 {
+		t.Errorf("unexpected subscriber stats: %+v", s.Subscribers)
+	}
+	for i := 0; i < 2000; i++ {
+		_ = pub.Publish(ctx, eventCreated(i))
+	}
+	if s := pub.Stats()[eventTypeCreated]; s.Count != 2003 || s.P50 != time.Second || s.P99 != time.Second {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestMuxStatsHandling(t *testing.T) {
+	ctx := context.Background()
+	var stats []event.TypeStats
+	var pub *event.Mux
+	pub = event.NewMux(event.MuxProfile()).
+		On(eventTypeCreated, event.Discard).
+		On(eventTypeCreated, event.Func(func(context.Context, event.Event) error {
+			stats = append(stats, pub.Stats()[eventTypeCreated])
+			return nil
+		}))
+	if err := pub.Publish(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Count != 0 || stats[0].P50 != 0 || len(stats[0].Subscribers) != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	a := event.StatsSnapshot{Time: now, Types: map[event.Type]event.TypeStats{
+		eventTypeCreated: {Count: 10, Subscribers: map[string]event.SubscriberStats{
+			"sub1": {Count: 10, Wall: time.Second},
+			"sub2": {Count: 10, Wall: time.Second},
+			"sub3": {Count: 10, Wall: time.Second},
+		}},
+	}}
+	b := event.StatsSnapshot{Time: now.Add(time.Minute), Types: map[event.Type]event.TypeStats{
+		eventTypeCreated: {Count: 15, Subscribers: map[string]event.SubscriberStats{
+			"sub1": {Count: 15, Wall: 2 * time.Second},
+			"sub2": {Count: 10, Wall: time.Second},
+			"sub3": {Count: 5, Wall: time.Second},
+		}},
+	}}
+	d := event.Diff(a, b)
+	expected := map[string]event.SubscriberStats{
+		"sub1": {Count: 5, Wall: time.Second},
+		"sub3": {Count: 5, Wall: time.Second},
+	}
+	if s := d.Types[eventTypeCreated]; s.Count != 5 || !reflect.DeepEqual(s.Subscribers, expected) {
+		t.Errorf("unexpected delta: %+v", d.Types)
+	}
+}
@@ -0,0 +1,58 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// Store is the interface for storing the states of the subscribers, like the
+// pending events of Correlator by NewCorrelationStore, the checkpoints of
+// Backfill by StoreCheckpoint, and the KeyValueStore of the eventcron and
+// workflow packages, so that the subscribers share the storage plumbing. The
+// values are opaque bytes; the subscribers serialize their states. The
+// methods are called concurrently so the implementations should be goroutine
+// safe. See the eventstore package for the stores backed by Redis and the SQL
+// databases.
+type Store interface {
+	// Load the value of the key, or false if not found.
+	Load(context.Context, string) ([]byte, bool, error)
+	// Save the value of the key.
+	Save(context.Context, string, []byte) error
+	// Delete the value of the key. Deleting a missing key is not an error.
+	Delete(context.Context, string) error
+}
+
+// MemoryStore is a store to keep the values in memory.
+type MemoryStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+// NewMemoryStore creates a new memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+// Load implements Store for MemoryStore.
+func (s *MemoryStore) Load(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return append([]byte(nil), value...), ok, nil
+}
+
+// Save implements Store for MemoryStore.
+func (s *MemoryStore) Save(_ context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete implements Store for MemoryStore.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
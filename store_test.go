@@ -0,0 +1,58 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	var s event.Store = event.NewMemoryStore()
+	if _, ok, err := s.Load(ctx, "foo"); err != nil || ok {
+		t.Fatalf("expected not found, got %v, %v", ok, err)
+	}
+	value := []byte("bar")
+	if err := s.Save(ctx, "foo", value); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	value[0] = 'c'
+	if got, ok, err := s.Load(ctx, "foo"); err != nil || !ok || string(got) != "bar" {
+		t.Fatalf("expected %q, got %q, %v, %v", "bar", got, ok, err)
+	}
+	if err := s.Save(ctx, "foo", nil); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if got, ok, err := s.Load(ctx, "foo"); err != nil || !ok || len(got) != 0 {
+		t.Fatalf("expected empty value, got %q, %v, %v", got, ok, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Delete(ctx, "foo"); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if _, ok, err := s.Load(ctx, "foo"); err != nil || ok {
+		t.Fatalf("expected not found, got %v, %v", ok, err)
+	}
+}
+
+// storeError is a store to fail on loading or saving the values.
+type storeError struct {
+	*event.MemoryStore
+	load, save error
+}
+
+func (s storeError) Load(ctx context.Context, key string) ([]byte, bool, error) {
+	if s.load != nil {
+		return nil, false, s.load
+	}
+	return s.MemoryStore.Load(ctx, key)
+}
+
+func (s storeError) Save(ctx context.Context, key string, value []byte) error {
+	if s.save != nil {
+		return s.save
+	}
+	return s.MemoryStore.Save(ctx, key, value)
+}
@@ -0,0 +1,53 @@
+package event
+
+import "context"
+
+// TeePolicy is the policy of Tee on failures of some of the publishers.
+type TeePolicy int
+
+const (
+	// TeeAll publishes an event to all the publishers and returns the last
+	// error as well as Ordered.
+	TeeAll TeePolicy = iota
+	// TeeFailFast stops publishing an event on the first error.
+	TeeFailFast
+	// TeeAny publishes an event to all the publishers and returns the last
+	// error only when all the publishers failed.
+	TeeAny
+)
+
+// Tee is an event publisher to publish events to multiple publishers in order,
+// like publishing to a local mapping and to an audit log.
+type Tee struct {
+	publishers []Publisher
+	policy     TeePolicy
+}
+
+// NewTee creates a new publisher to publish events to the publishers.
+func NewTee(policy TeePolicy, pubs ...Publisher) *Tee {
+	return &Tee{pubs, policy}
+}
+
+// Handle implements Subscriber for Tee.
+func (pub *Tee) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Tee.
+func (pub *Tee) Publish(ctx context.Context, ev Event) error {
+	var err error
+	var failed int
+	for _, p := range pub.publishers {
+		if e := p.Publish(ctx, ev); e != nil {
+			if pub.policy == TeeFailFast {
+				return e
+			}
+			err = e
+			failed++
+		}
+	}
+	if pub.policy == TeeAny && failed < len(pub.publishers) {
+		return nil
+	}
+	return err
+}
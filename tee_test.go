@@ -0,0 +1,86 @@
+package event_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestTee(t *testing.T) {
+	ctx := context.Background()
+	sub1, sub2 := &logged{}, &logged{}
+	pub := event.NewTee(
+		event.TeeAll,
+		event.NewMapping().On(eventTypeCreated, sub1),
+		event.NewMapping().On(eventTypeCreated, sub2).On(eventTypeUpdated, sub2),
+	)
+	evs := []event.Event{eventCreated(1), eventUpdated(2), eventOther(3)}
+	for _, ev := range evs {
+		if err := pub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := evs[:1]; !reflect.DeepEqual(sub1.Events(), expected) {
+		t.Errorf("sub1 handled events: expected %v, got %v", expected, sub1.Events())
+	}
+	if expected := evs[:2]; !reflect.DeepEqual(sub2.Events(), expected) {
+		t.Errorf("sub2 handled events: expected %v, got %v", expected, sub2.Events())
+	}
+}
+
+func TestTeeEmpty(t *testing.T) {
+	ctx := context.Background()
+	for _, policy := range []event.TeePolicy{event.TeeAll, event.TeeFailFast, event.TeeAny} {
+		if err := event.NewTee(policy).Publish(ctx, eventCreated(1)); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+}
+
+func TestTeeError(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		policy   event.TeePolicy
+		failAll  bool
+		handled  int
+		expected string
+	}{
+		{event.TeeAll, false, 2, "handle error"},
+		{event.TeeAll, true, 0, "handle error"},
+		{event.TeeFailFast, false, 1, "handle error"},
+		{event.TeeAny, false, 2, ""},
+		{event.TeeAny, true, 0, "handle error"},
+	}
+	for _, tc := range testCases {
+		sub1, sub2 := event.Subscriber(&logged{}), event.Subscriber(&logged{})
+		if tc.failAll {
+			sub1, sub2 = &suberr{}, &suberr{}
+		}
+		mid := event.Publisher(event.NewMapping().On(eventTypeCreated, &suberr{}))
+		pub := event.NewTee(
+			tc.policy,
+			event.NewMapping().On(eventTypeCreated, sub1),
+			mid,
+			event.NewMapping().On(eventTypeCreated, sub2),
+		)
+		err := pub.Publish(ctx, eventCreated(1))
+		if tc.expected == "" {
+			if err != nil {
+				t.Errorf("policy %d: got error: %v", tc.policy, err)
+			}
+		} else if err == nil || err.Error() != tc.expected {
+			t.Errorf("policy %d: expected %v, got %v", tc.policy, tc.expected, err)
+		}
+		var handled int
+		for _, sub := range []event.Subscriber{sub1, sub2} {
+			if sub, ok := sub.(*logged); ok {
+				handled += len(sub.Events())
+			}
+		}
+		if handled != tc.handled {
+			t.Errorf("policy %d: expected %d handled events, got %d", tc.policy, tc.handled, handled)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// TickOption is an option for Tick.
+type TickOption func(*tickOptions)
+
+type tickOptions struct {
+	clock Clock
+}
+
+// TickClock sets the clock of the ticks instead of the global clock.
+func TickClock(c Clock) TickOption {
+	return func(opts *tickOptions) { opts.clock = c }
+}
+
+// Tick publishes the events built by the factory periodically until the
+// context is canceled, so that the polling subscribers do not need their own
+// timers. The ticks are scheduled at the multiples of the interval since the
+// start not to drift, and the ticks missed by slow publishing are skipped. The
+// errors of publishing are ignored, and this function returns the error of the
+// context. This function panics on the non-positive interval, as well as
+// time.NewTicker.
+func Tick(ctx context.Context, pub Publisher, interval time.Duration, factory func(time.Time) Event, opts ...TickOption) error {
+	if interval <= 0 {
+		panic("event: non-positive interval for Tick")
+	}
+	var o tickOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	clock := clockOr(o.clock)
+	start := clock.Now()
+	timer := clock.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-timer.C():
+			_ = pub.Publish(ctx, factory(now))
+			elapsed := clock.Now().Sub(start)
+			timer.Reset(interval - elapsed%interval)
+		}
+	}
+}
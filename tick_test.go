@@ -0,0 +1,79 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type eventTicked time.Time
+
+func (eventTicked) Type() event.Type {
+	return eventTypeOther
+}
+
+func TestTick(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ticks []time.Time
+	pub := event.Func(func(_ context.Context, ev event.Event) error {
+		if ticks = append(ticks, time.Time(ev.(eventTicked))); len(ticks) == 5 {
+			cancel()
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	start := time.Now()
+	err := event.Tick(ctx, pub, 10*time.Millisecond, func(now time.Time) event.Event {
+		return eventTicked(now)
+	})
+	if expected := context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if len(ticks) != 5 {
+		t.Fatalf("expected 5 ticks, got %v", ticks)
+	}
+	if elapsed := ticks[4].Sub(start); elapsed < 50*time.Millisecond || elapsed > 70*time.Millisecond {
+		t.Errorf("expected ticks without drift, got the fifth tick after %v", elapsed)
+	}
+}
+
+func TestTickInvalidInterval(t *testing.T) {
+	defer func() {
+		if got, expected := recover(), "event: non-positive interval for Tick"; got != expected {
+			t.Errorf("expected panic %q, got %v", expected, got)
+		}
+	}()
+	_ = event.Tick(context.Background(), event.Func(func(context.Context, event.Event) error {
+		return nil
+	}), 0, func(now time.Time) event.Event { return eventTicked(now) })
+}
+
+func TestTickClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := eventtest.NewClock(start)
+	ticks := make(chan time.Time)
+	errc := make(chan error)
+	go func() {
+		errc <- event.Tick(ctx, event.Func(func(_ context.Context, ev event.Event) error {
+			ticks <- time.Time(ev.(eventTicked))
+			return nil
+		}), time.Second, func(now time.Time) event.Event {
+			return eventTicked(now)
+		}, event.TickClock(clock))
+	}()
+	for i := 1; i <= 3; i++ {
+		clock.WaitTimers(1)
+		clock.Advance(time.Second)
+		if got, expected := <-ticks, start.Add(time.Duration(i)*time.Second); !got.Equal(expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	}
+	cancel()
+	if err, expected := <-errc, context.Canceled; err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
@@ -0,0 +1,111 @@
+package event
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+)
+
+// Trace is the tracing metadata of an event. The causation ID is the ID of the
+// event being handled when the event is published, and the correlation ID is
+// the ID of the first event of the chain, so that multi-hop flows can be
+// reconstructed end to end. The hops is the number of the preceding events in
+// the chain.
+type Trace struct {
+	ID            string
+	CausationID   string
+	CorrelationID string
+	Hops          int
+}
+
+type traceKey struct{}
+
+// TraceFromContext returns the trace of the event being handled, or nil if
+// the context does not carry a trace.
+func TraceFromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// Tracer is an event publisher to assign the traces to the events. The trace
+// of a published event is linked to the trace of the event being handled, and
+// is stored in the context to handle the event. Buffer keeps the traces of the
+// buffered events to link the events on dispatching.
+type Tracer struct {
+	publisher Publisher
+	newID     func() string
+	record    func(context.Context, Event, *Trace)
+	maxHops   int
+}
+
+// NewTracer creates a new tracing publisher.
+func NewTracer(pub Publisher) *Tracer {
+	return &Tracer{publisher: pub, newID: newTraceID}
+}
+
+// IDs sets the function to generate the IDs of the events. The IDs are random
+// 128-bit hex strings by default. This method returns the publisher to allow
+// method chaining.
+func (pub *Tracer) IDs(newID func() string) *Tracer {
+	pub.newID = newID
+	return pub
+}
+
+// Record sets the function to record the traces of the published events, like
+// logging them. This method returns the publisher to allow method chaining.
+func (pub *Tracer) Record(record func(context.Context, Event, *Trace)) *Tracer {
+	pub.record = record
+	return pub
+}
+
+// MaxHops sets the max number of hops of the events, to detect the loops of
+// the subscribers publishing events back into the same publisher. The events
+// exceeding the hops are not published and LoopError is returned. The hops
+// are not limited by default. This method returns the publisher to allow
+// method chaining.
+func (pub *Tracer) MaxHops(n int) *Tracer {
+	pub.maxHops = n
+	return pub
+}
+
+// Handle implements Subscriber for Tracer.
+func (pub *Tracer) Handle(ctx context.Context, ev Event) error {
+	return pub.Publish(ctx, ev)
+}
+
+// Publish implements Publisher for Tracer.
+func (pub *Tracer) Publish(ctx context.Context, ev Event) error {
+	t := &Trace{ID: pub.newID()}
+	if parent := TraceFromContext(ctx); parent != nil {
+		t.CausationID, t.CorrelationID, t.Hops = parent.ID, parent.CorrelationID, parent.Hops+1
+		if pub.maxHops > 0 && t.Hops > pub.maxHops {
+			return &LoopError{ev, parent}
+		}
+	} else {
+		t.CorrelationID = t.ID
+	}
+	if pub.record != nil {
+		pub.record(ctx, ev, t)
+	}
+	return pub.publisher.Publish(context.WithValue(ctx, traceKey{}, t), ev)
+}
+
+// LoopError is the error returned by Tracer on an event exceeding the max hops.
+type LoopError struct {
+	Event Event
+	Cause *Trace
+}
+
+// Error implements error for LoopError.
+func (err *LoopError) Error() string {
+	return "event loop detected: event type " + strconv.Itoa(int(err.Event.Type())) +
+		" published after " + strconv.Itoa(err.Cause.Hops+1) +
+		" hops in correlation " + err.Cause.CorrelationID
+}
+
+func newTraceID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
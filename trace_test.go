@@ -0,0 +1,75 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/itchyny/event-go"
+)
+
+func TestTracer(t *testing.T) {
+	ctx := context.Background()
+	var traces []string
+	var id int
+	var buf *event.Buffer
+	pub := event.NewTracer(
+		event.NewMapping().
+			On(eventTypeCreated, event.Func(func(ctx context.Context, ev event.Event) error {
+				return buf.Publish(ctx, eventUpdated(ev.(eventCreated)))
+			})).
+			On(eventTypeUpdated, event.Func(func(ctx context.Context, ev event.Event) error {
+				if got, expected := event.TraceFromContext(ctx).CausationID, "1"; ev == eventUpdated(1) && got != expected {
+					t.Errorf("expected causation ID %s, got %s", expected, got)
+				}
+				return buf.Publish(ctx, eventDeleted(ev.(eventUpdated)))
+			})),
+	).
+		IDs(func() string {
+			id++
+			return strconv.Itoa(id)
+		}).
+		Record(func(_ context.Context, ev event.Event, t *event.Trace) {
+			traces = append(traces, t.ID+" "+t.CausationID+" "+t.CorrelationID)
+		})
+	buf = event.NewBuffer(pub)
+	if event.TraceFromContext(ctx) != nil {
+		t.Fatalf("expected no trace")
+	}
+	for _, ev := range []event.Event{eventCreated(1), eventCreated(2)} {
+		if err := buf.Publish(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err := buf.Dispatch(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := []string{"1  1", "2  2", "3 1 1", "4 2 2", "5 3 1", "6 4 2"}; !reflect.DeepEqual(traces, expected) {
+		t.Errorf("traces: expected %v, got %v", expected, traces)
+	}
+}
+
+func TestTracerMaxHops(t *testing.T) {
+	ctx := context.Background()
+	var handled int
+	var pub *event.Tracer
+	pub = event.NewTracer(event.Func(func(ctx context.Context, ev event.Event) error {
+		handled++
+		return pub.Publish(ctx, ev)
+	})).
+		IDs(func() string { return "x" }).
+		MaxHops(3)
+	err := pub.Handle(ctx, eventCreated(1))
+	if expected := "event loop detected: event type 0 published after 4 hops in correlation x"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	var lerr *event.LoopError
+	if !errors.As(err, &lerr) || lerr.Event != eventCreated(1) || lerr.Cause.Hops != 3 {
+		t.Errorf("expected LoopError, got %#v", err)
+	}
+	if expected := 4; handled != expected {
+		t.Errorf("expected %d handled events, got %d", expected, handled)
+	}
+}
@@ -0,0 +1,170 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Window is an event subscriber to handle the events in a time window at once,
+// which is useful for computing aggregates over the streams of events. By
+// default, the window is a tumbling window; the events are handled when the
+// window duration elapsed since the first event of the window.
+type Window struct {
+	handle   func(context.Context, []Event) error
+	size     time.Duration
+	slide    time.Duration
+	count    int
+	onError  func(error)
+	clock    Clock
+	mu       sync.Mutex
+	events   []windowEvent
+	timer    Timer
+	gen      int
+	tickets  int
+	handling *sync.Cond
+	turn     int
+}
+
+type windowEvent struct {
+	event Event
+	time  time.Time
+}
+
+// WindowOption is an option for NewWindow.
+type WindowOption func(*Window)
+
+// WindowSliding makes the window a sliding window, which handles the events
+// within the last window duration at every interval.
+func WindowSliding(interval time.Duration) WindowOption {
+	return func(sub *Window) { sub.slide = interval }
+}
+
+// WindowCount limits the number of events in a tumbling window. The events are
+// handled on the event when the window gets full, and the error is returned
+// from Handle.
+func WindowCount(count int) WindowOption {
+	return func(sub *Window) { sub.count = count }
+}
+
+// WindowErrorHandler sets the function to report the errors on handling the
+// windows on the timer. The errors are ignored by default.
+func WindowErrorHandler(f func(error)) WindowOption {
+	return func(sub *Window) { sub.onError = f }
+}
+
+// WindowClock sets the clock of the window instead of the global clock.
+func WindowClock(c Clock) WindowOption {
+	return func(sub *Window) { sub.clock = c }
+}
+
+// NewWindow creates a new window subscriber.
+func NewWindow(handle func(context.Context, []Event) error, window time.Duration, opts ...WindowOption) *Window {
+	sub := &Window{handle: handle, size: window, handling: sync.NewCond(&sync.Mutex{})}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	return sub
+}
+
+// Handle implements Subscriber for Window.
+func (sub *Window) Handle(ctx context.Context, ev Event) error {
+	sub.mu.Lock()
+	sub.events = append(sub.events, windowEvent{ev, clockOr(sub.clock).Now()})
+	if sub.timer == nil {
+		if sub.slide > 0 {
+			sub.schedule(sub.slide)
+		} else {
+			sub.schedule(sub.size)
+		}
+	}
+	if sub.slide == 0 && sub.count > 0 && len(sub.events) >= sub.count {
+		evs, ticket := sub.take(0)
+		sub.mu.Unlock()
+		return sub.run(ctx, evs, ticket)
+	}
+	sub.mu.Unlock()
+	return nil
+}
+
+// Flush handles the pending events immediately and stops the timer. Call this
+// method on shutdown not to lose the events.
+func (sub *Window) Flush(ctx context.Context) error {
+	return sub.flush(ctx, 0)
+}
+
+func (sub *Window) schedule(d time.Duration) {
+	sub.gen++
+	gen := sub.gen
+	sub.timer = clockOr(sub.clock).AfterFunc(d, func() {
+		if err := sub.flush(context.Background(), gen); err != nil && sub.onError != nil {
+			sub.onError(err)
+		}
+	})
+}
+
+func (sub *Window) flush(ctx context.Context, gen int) error {
+	sub.mu.Lock()
+	if gen != 0 && gen != sub.gen {
+		sub.mu.Unlock()
+		return nil
+	}
+	evs, ticket := sub.take(gen)
+	sub.mu.Unlock()
+	return sub.run(ctx, evs, ticket)
+}
+
+// take the events of the window, and the ticket to handle them in order. The
+// caller must hold the lock.
+func (sub *Window) take(gen int) ([]Event, int) {
+	var evs []Event
+	if gen == 0 || sub.slide == 0 {
+		for _, ev := range sub.events {
+			evs = append(evs, ev.event)
+		}
+		sub.events = nil
+		if sub.timer != nil {
+			sub.timer.Stop()
+			sub.timer = nil
+			sub.gen++ // invalidate the timer already fired
+		}
+	} else {
+		since, i := clockOr(sub.clock).Now().Add(-sub.size), 0
+		for i < len(sub.events) && sub.events[i].time.Before(since) {
+			i++
+		}
+		sub.events = sub.events[i:]
+		for _, ev := range sub.events {
+			evs = append(evs, ev.event)
+		}
+		if len(sub.events) == 0 {
+			sub.events, sub.timer = nil, nil
+		} else {
+			sub.schedule(sub.slide)
+		}
+	}
+	if len(evs) == 0 {
+		return nil, 0
+	}
+	sub.tickets++
+	return evs, sub.tickets
+}
+
+// run handles the events taken by take in the order of the tickets.
+func (sub *Window) run(ctx context.Context, evs []Event, ticket int) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	sub.handling.L.Lock()
+	for sub.turn+1 != ticket {
+		sub.handling.Wait()
+	}
+	sub.handling.L.Unlock()
+	defer func() {
+		sub.handling.L.Lock()
+		sub.turn++
+		sub.handling.Broadcast()
+		sub.handling.L.Unlock()
+	}()
+	return sub.handle(ctx, evs)
+}
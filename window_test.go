@@ -0,0 +1,253 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/eventtest"
+)
+
+type windows struct {
+	mu      sync.Mutex
+	windows [][]event.Event
+}
+
+func (w *windows) handle(_ context.Context, evs []event.Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.windows = append(w.windows, evs)
+	return nil
+}
+
+func (w *windows) get() [][]event.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.windows
+}
+
+func TestWindow(t *testing.T) {
+	ctx := context.Background()
+	w := &windows{}
+	sub := event.NewWindow(w.handle, 20*time.Millisecond)
+	evs := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)}
+	for _, ev := range evs[:2] {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if got := w.get(); len(got) != 0 {
+		t.Fatalf("expected no windows, got %v", got)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if err := sub.Handle(ctx, evs[2]); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := sub.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := sub.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := [][]event.Event{evs[:2], evs[2:]}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+}
+
+func TestWindowClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	w := &windows{}
+	sub := event.NewWindow(w.handle, time.Minute, event.WindowSliding(30*time.Second), event.WindowClock(clock))
+	evs := []event.Event{eventCreated(1), eventCreated(2)}
+	if err := sub.Handle(ctx, evs[0]); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(30 * time.Second)
+	if err := sub.Handle(ctx, evs[1]); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	if expected := [][]event.Event{evs[:1], evs, evs[1:]}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+}
+
+func TestWindowCount(t *testing.T) {
+	ctx := context.Background()
+	w := &windows{}
+	sub := event.NewWindow(w.handle, 20*time.Millisecond, event.WindowCount(2))
+	evs := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)}
+	for _, ev := range evs {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := [][]event.Event{evs[:2]}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+	time.Sleep(40 * time.Millisecond)
+	if expected := [][]event.Event{evs[:2], evs[2:]}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+}
+
+func TestWindowCountBlocking(t *testing.T) {
+	ctx := context.Background()
+	w := &windows{}
+	sub := event.NewWindow(func(ctx context.Context, evs []event.Event) error {
+		time.Sleep(30 * time.Millisecond)
+		return w.handle(ctx, evs)
+	}, 10*time.Millisecond, event.WindowCount(2))
+	evs := []event.Event{eventCreated(1), eventCreated(2), eventCreated(3)}
+	for _, ev := range evs {
+		if err := sub.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := [][]event.Event{evs[:2]}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+	time.Sleep(60 * time.Millisecond)
+	if expected := [][]event.Event{evs[:2], evs[2:]}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+}
+
+func TestWindowCountConcurrent(t *testing.T) {
+	ctx := context.Background()
+	w := &windows{}
+	sub := event.NewWindow(w.handle, time.Hour, event.WindowCount(3))
+	var wg sync.WaitGroup
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := sub.Handle(ctx, eventCreated(i)); err != nil {
+				t.Errorf("got error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got, expected := len(w.get()), 100; got != expected {
+		t.Errorf("expected %d windows, got %d", expected, got)
+	}
+	for _, evs := range w.get() {
+		if len(evs) != 3 {
+			t.Errorf("expected 3 events in a window, got %v", evs)
+		}
+	}
+}
+
+func TestWindowSliding(t *testing.T) {
+	ctx := context.Background()
+	w := &windows{}
+	sub := event.NewWindow(w.handle, 200*time.Millisecond, event.WindowSliding(80*time.Millisecond))
+	evs := []event.Event{eventCreated(1), eventCreated(2)}
+	if err := sub.Handle(ctx, evs[0]); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := sub.Handle(ctx, evs[1]); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if expected := [][]event.Event{evs[:1], evs[:2], evs[1:]}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+}
+
+func TestWindowError(t *testing.T) {
+	ctx := context.Background()
+	errc := make(chan error, 1)
+	sub := event.NewWindow(
+		func(context.Context, []event.Event) error {
+			return errors.New("handle error")
+		},
+		10*time.Millisecond,
+		event.WindowCount(2),
+		event.WindowErrorHandler(func(err error) { errc <- err }),
+	)
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := sub.Handle(ctx, eventCreated(2)), "handle error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := sub.Handle(ctx, eventCreated(3)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := <-errc, "handle error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := event.NewWindow(nil, time.Millisecond).Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+func TestWindowOrder(t *testing.T) {
+	ctx := context.Background()
+	w := &windows{}
+	block, handling := make(chan struct{}), make(chan struct{})
+	sub := event.NewWindow(func(ctx context.Context, evs []event.Event) error {
+		if evs[0] == eventCreated(1) {
+			close(handling)
+			<-block
+		}
+		return w.handle(ctx, evs)
+	}, time.Hour, event.WindowCount(1))
+	errc := make(chan error, 2)
+	go func() { errc <- sub.Handle(ctx, eventCreated(1)) }()
+	<-handling
+	go func() { errc <- sub.Handle(ctx, eventCreated(2)) }()
+	time.Sleep(10 * time.Millisecond)
+	close(block)
+	for i := 0; i < 2; i++ {
+		if err := <-errc; err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := [][]event.Event{{eventCreated(1)}, {eventCreated(2)}}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+}
+
+// firedClock keeps the functions of the timers to call them after stopping
+// the timers, like the timers fired concurrently with stopping.
+type firedClock struct {
+	*eventtest.Clock
+	fs []func()
+}
+
+func (c *firedClock) AfterFunc(d time.Duration, f func()) event.Timer {
+	c.fs = append(c.fs, f)
+	return c.Clock.AfterFunc(d, f)
+}
+
+func TestWindowFlushStopped(t *testing.T) {
+	ctx := context.Background()
+	clock := &firedClock{Clock: eventtest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))}
+	w := &windows{}
+	sub := event.NewWindow(w.handle, time.Minute, event.WindowClock(clock))
+	if err := sub.Handle(ctx, eventCreated(1)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := sub.Flush(ctx); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := sub.Handle(ctx, eventCreated(2)); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	clock.fs[0]()
+	if expected := [][]event.Event{{eventCreated(1)}}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+	clock.Advance(time.Minute)
+	if expected := [][]event.Event{{eventCreated(1)}, {eventCreated(2)}}; !reflect.DeepEqual(w.get(), expected) {
+		t.Errorf("handled windows: expected %v, got %v", expected, w.get())
+	}
+}
@@ -0,0 +1,180 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/itchyny/event-go"
+)
+
+// Store is the interface for storing the progress of the processes.
+type Store interface {
+	// Load the state of the key, or the zero state if not found.
+	Load(context.Context, interface{}) (State, error)
+	// Save the state of the key.
+	Save(context.Context, interface{}, State) error
+	// Delete the state of the key. Deleting a missing key is not an error.
+	Delete(context.Context, interface{}) error
+}
+
+// MemoryStore is a store to keep the states in memory.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[interface{}]State
+}
+
+// NewMemoryStore creates a new memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[interface{}]State)}
+}
+
+// Load implements Store for MemoryStore.
+func (s *MemoryStore) Load(_ context.Context, key interface{}) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[key], nil
+}
+
+// Save implements Store for MemoryStore.
+func (s *MemoryStore) Save(_ context.Context, key interface{}, state State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[key] = state
+	return nil
+}
+
+// Delete implements Store for MemoryStore.
+func (s *MemoryStore) Delete(_ context.Context, key interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+// FileStore is a store to keep the states in a JSON file. The keys are
+// formatted by fmt.Sprint, and the file is replaced atomically on each save.
+type FileStore struct {
+	path   string
+	mu     sync.Mutex
+	states map[string]State
+}
+
+// NewFileStore creates a new file store, loading the states from the file if
+// it exists.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, states: make(map[string]State)}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(bs, &s.states); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Load implements Store for FileStore.
+func (s *FileStore) Load(_ context.Context, key interface{}) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[fmt.Sprint(key)], nil
+}
+
+// Save implements Store for FileStore.
+func (s *FileStore) Save(_ context.Context, key interface{}, state State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := fmt.Sprint(key)
+	prev, ok := s.states[k]
+	s.states[k] = state
+	if err := s.write(); err != nil {
+		if ok {
+			s.states[k] = prev
+		} else {
+			delete(s.states, k)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete implements Store for FileStore.
+func (s *FileStore) Delete(_ context.Context, key interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := fmt.Sprint(key)
+	prev, ok := s.states[k]
+	if !ok {
+		return nil
+	}
+	delete(s.states, k)
+	if err := s.write(); err != nil {
+		s.states[k] = prev
+		return err
+	}
+	return nil
+}
+
+// write replaces the file by the temporary file synced to the disk, so that the
+// file is not lost or truncated on crashes.
+func (s *FileStore) write() error {
+	bs, _ := json.Marshal(s.states) // State is always serializable
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(bs)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path)
+	}
+	return err
+}
+
+// KeyValueStore is a store to keep the states in event.Store, like the stores
+// of the eventstore package. The keys are formatted by fmt.Sprint, and the
+// states are serialized in JSON.
+type KeyValueStore struct {
+	store event.Store
+}
+
+// NewKeyValueStore creates a new store backed by event.Store.
+func NewKeyValueStore(store event.Store) *KeyValueStore {
+	return &KeyValueStore{store: store}
+}
+
+// Load implements Store for KeyValueStore.
+func (s *KeyValueStore) Load(ctx context.Context, key interface{}) (State, error) {
+	var state State
+	bs, ok, err := s.store.Load(ctx, fmt.Sprint(key))
+	if err != nil || !ok {
+		return state, err
+	}
+	err = json.Unmarshal(bs, &state)
+	return state, err
+}
+
+// Save implements Store for KeyValueStore.
+func (s *KeyValueStore) Save(ctx context.Context, key interface{}, state State) error {
+	bs, _ := json.Marshal(state) // State is always serializable
+	return s.store.Save(ctx, fmt.Sprint(key), bs)
+}
+
+// Delete implements Store for KeyValueStore.
+func (s *KeyValueStore) Delete(ctx context.Context, key interface{}) error {
+	return s.store.Delete(ctx, fmt.Sprint(key))
+}
@@ -0,0 +1,204 @@
+// Package workflow provides the orchestration of multi-step processes, which
+// advance on the events and compensate the completed steps on failures.
+package workflow
+
+import (
+	"context"
+	"sync"
+
+	"github.com/itchyny/event-go"
+)
+
+// Workflow is an event subscriber to advance the steps of the processes. Each
+// process is identified by the key of the events, and the progress is saved to
+// the store so that the processes survive restarts. The progress of a process
+// is deleted from the store when the process completes all the steps or
+// compensates the steps, so the key can start a new process.
+type Workflow struct {
+	key         func(event.Event) (interface{}, bool)
+	steps       []step
+	failures    map[event.Type]bool
+	store       Store
+	publisher   event.Publisher
+	completed   event.Type
+	compensated event.Type
+	mu          sync.Mutex
+	locks       map[interface{}]*keyLock
+}
+
+// keyLock serializes the handling of the events of a key, so that the store
+// is accessed without blocking the events of the other keys.
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+type step struct {
+	name       string
+	typ        event.Type
+	compensate event.Subscriber
+}
+
+// State is the progress of a process.
+type State struct {
+	// The number of the completed steps.
+	Completed int `json:"completed"`
+	// Whether or not the process failed and is compensated.
+	Failed bool `json:"failed,omitempty"`
+}
+
+// New creates a new workflow. The key function returns the key of the process
+// of an event, or false to ignore the event.
+func New(key func(event.Event) (interface{}, bool)) *Workflow {
+	return &Workflow{
+		key: key, failures: make(map[event.Type]bool), store: NewMemoryStore(),
+		locks: make(map[interface{}]*keyLock),
+	}
+}
+
+// Step appends the step which completes on the event type. The compensate
+// subscriber handles the failure event to undo the step, or can be nil when
+// the step needs no compensation. This method returns the workflow to allow
+// method chaining. Note that the methods to configure the workflow are not
+// goroutine safe so configure the workflow before starting event publishing.
+func (w *Workflow) Step(name string, typ event.Type, compensate event.Subscriber) *Workflow {
+	w.steps = append(w.steps, step{name, typ, compensate})
+	return w
+}
+
+// FailOn registers the event type which fails the process. The completed steps
+// are compensated in the reverse order. This method returns the workflow to
+// allow method chaining.
+func (w *Workflow) FailOn(typ event.Type) *Workflow {
+	w.failures[typ] = true
+	return w
+}
+
+// Store sets the store of the progress of the processes. The progress is stored
+// in memory by default. This method returns the workflow to allow method
+// chaining.
+func (w *Workflow) Store(store Store) *Workflow {
+	w.store = store
+	return w
+}
+
+// Emit sets the publisher to publish a StepCompleted event of the completed
+// type on each completed step, and a StepCompensated event of the compensated
+// type on each compensated step. This method returns the workflow to allow
+// method chaining.
+func (w *Workflow) Emit(pub event.Publisher, completed, compensated event.Type) *Workflow {
+	w.publisher, w.completed, w.compensated = pub, completed, compensated
+	return w
+}
+
+// Handle implements Subscriber for Workflow. The events not expected in the
+// current step are ignored. The failed state is saved before compensating the
+// steps, so the steps are not compensated again on the compensation errors.
+func (w *Workflow) Handle(ctx context.Context, ev event.Event) error {
+	key, ok := w.key(ev)
+	if !ok {
+		return nil
+	}
+	unlock := w.lock(key)
+	state, err := w.store.Load(ctx, key)
+	if err != nil {
+		unlock()
+		return err
+	}
+	if state.Failed || state.Completed >= len(w.steps) {
+		unlock()
+		return nil
+	}
+	if w.steps[state.Completed].typ == ev.Type() {
+		state.Completed++
+		if state.Completed == len(w.steps) {
+			err = w.store.Delete(ctx, key)
+		} else {
+			err = w.store.Save(ctx, key, state)
+		}
+		unlock()
+		if err != nil {
+			return err
+		}
+		return w.publish(ctx, &StepCompleted{
+			w.completed, key, w.steps[state.Completed-1].name, state.Completed == len(w.steps), ev,
+		})
+	}
+	if !w.failures[ev.Type()] {
+		unlock()
+		return nil
+	}
+	err = w.store.Save(ctx, key, State{state.Completed, true})
+	unlock()
+	if err != nil {
+		return err
+	}
+	for i := state.Completed - 1; i >= 0; i-- {
+		if s := w.steps[i]; s.compensate != nil {
+			if err := s.compensate.Handle(ctx, ev); err != nil {
+				return err
+			}
+		}
+		if err := w.publish(ctx, &StepCompensated{w.compensated, key, w.steps[i].name, ev}); err != nil {
+			return err
+		}
+	}
+	unlock = w.lock(key)
+	defer unlock()
+	return w.store.Delete(ctx, key)
+}
+
+// lock locks the key, and returns the function to unlock the key.
+func (w *Workflow) lock(key interface{}) func() {
+	w.mu.Lock()
+	l, ok := w.locks[key]
+	if !ok {
+		l = &keyLock{}
+		w.locks[key] = l
+	}
+	l.refs++
+	w.mu.Unlock()
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(w.locks, key)
+		}
+	}
+}
+
+func (w *Workflow) publish(ctx context.Context, ev event.Event) error {
+	if w.publisher == nil {
+		return nil
+	}
+	return w.publisher.Publish(ctx, ev)
+}
+
+// StepCompleted is the event published by Workflow on each completed step.
+type StepCompleted struct {
+	typ   event.Type
+	Key   interface{}
+	Step  string
+	Done  bool
+	Event event.Event
+}
+
+// Type implements event.Event for StepCompleted.
+func (ev *StepCompleted) Type() event.Type {
+	return ev.typ
+}
+
+// StepCompensated is the event published by Workflow on each compensated step.
+type StepCompensated struct {
+	typ   event.Type
+	Key   interface{}
+	Step  string
+	Event event.Event
+}
+
+// Type implements event.Event for StepCompensated.
+func (ev *StepCompensated) Type() event.Type {
+	return ev.typ
+}
@@ -0,0 +1,321 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/itchyny/event-go"
+	"github.com/itchyny/event-go/workflow"
+)
+
+const (
+	eventTypePaid event.Type = iota
+	eventTypeReserved
+	eventTypeShipped
+	eventTypeFailed
+	eventTypeStepCompleted
+	eventTypeStepCompensated
+)
+
+type order struct {
+	typ event.Type
+	ID  int
+}
+
+func (ev order) Type() event.Type {
+	return ev.typ
+}
+
+func newWorkflow(compensated *[]string) *workflow.Workflow {
+	compensate := func(name string) event.Subscriber {
+		return event.Func(func(_ context.Context, ev event.Event) error {
+			*compensated = append(*compensated, name)
+			return nil
+		})
+	}
+	return workflow.New(func(ev event.Event) (interface{}, bool) {
+		return ev.(order).ID, true
+	}).
+		Step("pay", eventTypePaid, compensate("refund")).
+		Step("reserve", eventTypeReserved, compensate("release")).
+		Step("ship", eventTypeShipped, nil).
+		FailOn(eventTypeFailed)
+}
+
+func TestWorkflow(t *testing.T) {
+	ctx := context.Background()
+	var compensated []string
+	var published []string
+	w := newWorkflow(&compensated).Emit(event.Func(func(_ context.Context, ev event.Event) error {
+		switch ev := ev.(type) {
+		case *workflow.StepCompleted:
+			if ev.Type() != eventTypeStepCompleted {
+				t.Errorf("unexpected event type: %d", ev.Type())
+			}
+			published = append(published, "completed "+ev.Step)
+			if ev.Done {
+				published = append(published, "done")
+			}
+		case *workflow.StepCompensated:
+			if ev.Type() != eventTypeStepCompensated {
+				t.Errorf("unexpected event type: %d", ev.Type())
+			}
+			published = append(published, "compensated "+ev.Step)
+		}
+		return nil
+	}), eventTypeStepCompleted, eventTypeStepCompensated)
+	evs := []event.Event{
+		order{eventTypePaid, 1}, order{eventTypePaid, 2}, order{eventTypeShipped, 1},
+		order{eventTypeReserved, 1}, order{eventTypeReserved, 2}, order{eventTypeShipped, 1},
+		order{eventTypeFailed, 1}, order{eventTypeFailed, 2}, order{eventTypeShipped, 2},
+	}
+	for _, ev := range evs {
+		if err := w.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	expected := []string{
+		"completed pay", "completed pay", "completed reserve", "completed reserve",
+		"completed ship", "done", "compensated reserve", "compensated pay",
+	}
+	if !reflect.DeepEqual(published, expected) {
+		t.Errorf("published events: expected %v, got %v", expected, published)
+	}
+	if expected := []string{"release", "refund"}; !reflect.DeepEqual(compensated, expected) {
+		t.Errorf("compensated steps: expected %v, got %v", expected, compensated)
+	}
+}
+
+func TestWorkflowFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "workflow.json")
+	var compensated []string
+	for _, ev := range []event.Event{
+		order{eventTypePaid, 1}, order{eventTypeReserved, 1}, order{eventTypeFailed, 1},
+	} {
+		store, err := workflow.NewFileStore(path)
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		if err := newWorkflow(&compensated).Store(store).Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []string{"release", "refund"}; !reflect.DeepEqual(compensated, expected) {
+		t.Errorf("compensated steps: expected %v, got %v", expected, compensated)
+	}
+	store, err := workflow.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	state, err := store.Load(ctx, 1)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := (workflow.State{}); state != expected {
+		t.Errorf("expected %v, got %v", expected, state)
+	}
+}
+
+func TestWorkflowKeyValueStore(t *testing.T) {
+	ctx := context.Background()
+	kv := event.NewMemoryStore()
+	var compensated []string
+	for _, ev := range []event.Event{
+		order{eventTypePaid, 1}, order{eventTypeReserved, 1}, order{eventTypeFailed, 1},
+	} {
+		store := workflow.NewKeyValueStore(kv)
+		if err := newWorkflow(&compensated).Store(store).Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if expected := []string{"release", "refund"}; !reflect.DeepEqual(compensated, expected) {
+		t.Errorf("compensated steps: expected %v, got %v", expected, compensated)
+	}
+	store := workflow.NewKeyValueStore(kv)
+	state, err := store.Load(ctx, 1)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if expected := (workflow.State{}); state != expected {
+		t.Errorf("expected %v, got %v", expected, state)
+	}
+	if err := kv.Save(ctx, "1", []byte("invalid")); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := store.Load(ctx, 1); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestWorkflowError(t *testing.T) {
+	ctx := context.Background()
+	w := workflow.New(func(ev event.Event) (interface{}, bool) {
+		return ev.(order).ID, true
+	}).
+		Step("pay", eventTypePaid, event.Func(func(context.Context, event.Event) error {
+			return errors.New("compensate error")
+		})).
+		Step("ship", eventTypeShipped, nil).
+		FailOn(eventTypeFailed)
+	if err := w.Handle(ctx, order{eventTypePaid, 1}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err, expected := w.Handle(ctx, order{eventTypeFailed, 1}), "compensate error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if err := w.Handle(ctx, order{eventTypeFailed, 1}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+}
+
+type failingStore struct {
+	state workflow.State
+}
+
+func (s failingStore) Load(context.Context, interface{}) (workflow.State, error) {
+	return s.state, nil
+}
+
+func (failingStore) Save(context.Context, interface{}, workflow.State) error {
+	return errors.New("save error")
+}
+
+func (failingStore) Delete(context.Context, interface{}) error {
+	return errors.New("delete error")
+}
+
+func TestWorkflowStoreError(t *testing.T) {
+	ctx := context.Background()
+	var compensated []string
+	w := newWorkflow(&compensated).Store(failingStore{})
+	if err, expected := w.Handle(ctx, order{eventTypePaid, 1}), "save error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	w = newWorkflow(&compensated).Store(failingStore{workflow.State{Completed: 1}})
+	if err, expected := w.Handle(ctx, order{eventTypeFailed, 1}), "save error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if len(compensated) != 0 {
+		t.Errorf("expected no compensations, got %v", compensated)
+	}
+	w = newWorkflow(&compensated).Store(failingStore{workflow.State{Completed: 2}})
+	if err, expected := w.Handle(ctx, order{eventTypeShipped, 1}), "delete error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	kv := event.NewMemoryStore()
+	if err := kv.Save(ctx, "1", []byte("invalid")); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	w = newWorkflow(&compensated).Store(workflow.NewKeyValueStore(kv))
+	if err := w.Handle(ctx, order{eventTypePaid, 1}); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+type blockingStore struct {
+	workflow.Store
+	started, release chan struct{}
+}
+
+func (s blockingStore) Load(ctx context.Context, key interface{}) (workflow.State, error) {
+	if key == 1 {
+		close(s.started)
+		<-s.release
+	}
+	return s.Store.Load(ctx, key)
+}
+
+func TestWorkflowStoreConcurrency(t *testing.T) {
+	ctx := context.Background()
+	var compensated []string
+	store := blockingStore{workflow.NewMemoryStore(), make(chan struct{}), make(chan struct{})}
+	w := newWorkflow(&compensated).Store(store)
+	errc := make(chan error)
+	go func() { errc <- w.Handle(ctx, order{eventTypePaid, 1}) }()
+	<-store.started
+	if err := w.Handle(ctx, order{eventTypePaid, 2}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	close(store.release)
+	if err := <-errc; err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, key := range []int{1, 2} {
+		if got, err := store.Store.Load(ctx, key); err != nil || got.Completed != 1 {
+			t.Errorf("expected the first step completed, got %v, %v", got, err)
+		}
+	}
+}
+
+func TestWorkflowPublishError(t *testing.T) {
+	ctx := context.Background()
+	w := workflow.New(func(ev event.Event) (interface{}, bool) {
+		return ev.(order).ID, ev.(order).ID > 0
+	}).
+		Step("pay", eventTypePaid, nil).
+		Step("ship", eventTypeShipped, nil).
+		FailOn(eventTypeFailed).
+		Emit(event.Func(func(_ context.Context, ev event.Event) error {
+			if _, ok := ev.(*workflow.StepCompensated); ok {
+				return errors.New("publish error")
+			}
+			return nil
+		}), eventTypeStepCompleted, eventTypeStepCompensated)
+	for _, ev := range []event.Event{order{eventTypePaid, 1}, order{eventTypeFailed, 0}} {
+		if err := w.Handle(ctx, ev); err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+	}
+	if err, expected := w.Handle(ctx, order{eventTypeFailed, 1}), "publish error"; err == nil || err.Error() != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestFileStoreError(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if _, err := workflow.NewFileStore(dir); err == nil {
+		t.Fatalf("expected an error")
+	}
+	path := filepath.Join(dir, "workflow.json")
+	if err := os.WriteFile(path, []byte("invalid"), 0o644); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if _, err := workflow.NewFileStore(path); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	store, err := workflow.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := store.Save(ctx, 1, workflow.State{Completed: 1}); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for _, key := range []int{1, 2} {
+		if err := store.Save(ctx, key, workflow.State{Completed: 2}); err == nil {
+			t.Fatalf("expected an error")
+		}
+	}
+	if err := store.Delete(ctx, 1); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := store.Delete(ctx, 2); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	for key, expected := range map[int]workflow.State{1: {Completed: 1}, 2: {}} {
+		if got, err := store.Load(ctx, key); err != nil || got != expected {
+			t.Errorf("expected %v, got %v, %v", expected, got, err)
+		}
+	}
+}